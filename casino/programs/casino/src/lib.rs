@@ -41,13 +41,15 @@ pub mod casino {
         Ok(())
     }
 
-    pub fn withdraw(ctx: Context<Withdraw>, amount: u64, nonce: u64, signature: [u8; 64], recovery_id: u8) -> Result<()> {
+    pub fn withdraw(ctx: Context<Withdraw>, amount: u64, nonce: u64, expires_at: i64, signature: [u8; 64], recovery_id: u8) -> Result<()> {
         require!(nonce == ctx.accounts.user_balance.last_withdrawal_nonce + 1, MyError::InvalidNonce);
+        require!(Clock::get()?.unix_timestamp <= expires_at, MyError::AuthorizationExpired);
 
         let mut message = Vec::new();
         message.extend_from_slice(&ctx.accounts.user.key().to_bytes());
         message.extend_from_slice(&amount.to_le_bytes());
         message.extend_from_slice(&nonce.to_le_bytes());
+        message.extend_from_slice(&expires_at.to_le_bytes());
         let message_hash = keccak::hash(&message).to_bytes();
 
         let recovered_pubkey_bytes = secp256k1_recover(&message_hash, recovery_id, &signature)
@@ -227,4 +229,6 @@ pub enum MyError {
     InvalidNonce,
     #[msg("Deposit amount would cause an overflow.")]
     DepositOverflow,
+    #[msg("The withdrawal authorization has expired.")]
+    AuthorizationExpired,
 }
\ No newline at end of file
@@ -63,21 +63,48 @@ describe("casino", () => {
         console.log("✅ Player deposited 1 SOL.");
     });
 
-    it("Player makes a valid withdrawal", async () => {
-        const withdrawAmount = new anchor.BN(0.5 * LAMPORTS_PER_SOL);
-        const nonce = new anchor.BN(1);
-
+    const signWithdrawal = (amount: anchor.BN, nonce: anchor.BN, expiresAt: anchor.BN) => {
         const message = Buffer.concat([
             player.publicKey.toBuffer(),
-            withdrawAmount.toBuffer('le', 8),
+            amount.toBuffer('le', 8),
             nonce.toBuffer('le', 8),
+            expiresAt.toTwos(64).toBuffer('le', 8),
         ]);
         const messageHash = Buffer.from(keccak_256.digest(message));
+        return secp256k1.ecdsaSign(messageHash, serverSecpPrivateKey);
+    };
 
-        const { signature, recid: recoveryId } = secp256k1.ecdsaSign(messageHash, serverSecpPrivateKey);
+    it("Rejects an expired withdrawal authorization", async () => {
+        const withdrawAmount = new anchor.BN(0.5 * LAMPORTS_PER_SOL);
+        const nonce = new anchor.BN(1);
+        const expiresAt = new anchor.BN(Math.floor(Date.now() / 1000) - 60);
+        const { signature, recid: recoveryId } = signWithdrawal(withdrawAmount, nonce, expiresAt);
+
+        try {
+            await program.methods
+                .withdraw(withdrawAmount, nonce, expiresAt, Array.from(signature), recoveryId)
+                .accounts({
+                    casinoVault: casinoVaultPDA,
+                    userBalance: playerBalancePDA,
+                    user: player.publicKey,
+                })
+                .signers([player])
+                .rpc();
+            assert.fail("Expired authorization was accepted");
+        } catch (err) {
+            assert.include(err.toString(), "AuthorizationExpired");
+        }
+        console.log("✅ Expired withdrawal authorization rejected.");
+    });
+
+    it("Player makes a valid withdrawal", async () => {
+        const withdrawAmount = new anchor.BN(0.5 * LAMPORTS_PER_SOL);
+        const nonce = new anchor.BN(1);
+        const expiresAt = new anchor.BN(Math.floor(Date.now() / 1000) + 600);
+        const { signature, recid: recoveryId } = signWithdrawal(withdrawAmount, nonce, expiresAt);
 
         await program.methods
-            .withdraw(withdrawAmount, nonce, Array.from(signature), recoveryId)
+            .withdraw(withdrawAmount, nonce, expiresAt, Array.from(signature), recoveryId)
             .accounts({
                 casinoVault: casinoVaultPDA,
                 userBalance: playerBalancePDA,
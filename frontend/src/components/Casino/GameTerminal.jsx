@@ -18,11 +18,11 @@ const API_URL = import.meta.env.PUBLIC_API_URL;
 const PROGRAM_ID = new PublicKey("7WLsmcUxHVJ1hF6X1rVkLfRmaFWGi8Xdwqjys3mvqYxB");
 const VAULT_ADDRESS = new PublicKey("Fak2ZEUZEsETSjNZJT5AkNYCipgfZxaSVjknnsVQ9QFX");
 
-const SYMBOLS = ['😶', '🍒', '🍋', '🍇', '🍫', '🔔', '7️⃣', '💎', '🃏'];
+const SYMBOLS = ['😶', '🍒', '🍋', '🍇', '🍫', '🔔', '7️⃣', '💎', '🃏', '⭐'];
 
 export default function GameTerminal() {
     const { connection } = useConnection();
-    const { publicKey, signTransaction, sendTransaction } = useWallet();
+    const { publicKey, signTransaction, signMessage } = useWallet();
 
     const [logs, setLogs] = useState(["> System initialized...", "> Waiting for wallet connection..."]);
     const [balance, setBalance] = useState("0.00");
@@ -30,24 +30,47 @@ export default function GameTerminal() {
     const [isSpinning, setIsSpinning] = useState(false);
     const [betAmount, setBetAmount] = useState(0.5);
     const [clientSeed, setClientSeed] = useState(Math.random().toString(36).substring(7));
+    const [authToken, setAuthToken] = useState(null);
 
     const addLog = (msg) => setLogs(prev => [`> ${msg}`, ...prev].slice(0, 10));
 
     const refreshBalance = async () => {
         if (!publicKey) return;
         try {
-            const res = await axios.get(`${API_URL}/wallet/balance/${publicKey.toString()}`);
+            const res = await axios.get(`${API_URL}/wallet/balance`);
             setBalance(res.data.data.balance_sol);
         } catch (e) {
             console.error(e);
         }
     };
 
+    const signIn = async () => {
+        addLog("Requesting sign-in challenge...");
+        const challenge = await axios.post(`${API_URL}/auth/challenge`, {
+            wallet_address: publicKey.toString()
+        });
+        const { nonce, message } = challenge.data.data;
+
+        const signature = await signMessage(new TextEncoder().encode(message));
+
+        const res = await axios.post(`${API_URL}/auth/login`, {
+            wallet_address: publicKey.toString(),
+            nonce,
+            signature: Buffer.from(signature).toString('hex')
+        });
+        const { token } = res.data.data;
+
+        axios.defaults.headers.common['Authorization'] = `Bearer ${token}`;
+        setAuthToken(token);
+        addLog("Wallet verified.");
+    };
+
     const initSession = async () => {
         if (!publicKey) return;
         try {
+            await signIn();
             addLog("Initializing secure session...");
-            await axios.post(`${API_URL}/game/session`, { wallet_address: publicKey.toString() });
+            await axios.post(`${API_URL}/game/session`);
             addLog("Session established.");
             refreshBalance();
         } catch (e) {
@@ -113,9 +136,8 @@ export default function GameTerminal() {
 
             try {
                 await axios.post(`${API_URL}/wallet/sync`, {
-                    wallet_address: publicKey.toString(),
                     tx_signature: signature
-                });
+                }, { headers: { 'Idempotency-Key': `sync-${signature}` } });
                 addLog("✅ Server Synced!");
                 await refreshBalance();
             } catch (syncError) {
@@ -136,10 +158,9 @@ export default function GameTerminal() {
 
         try {
             const res = await axios.post(`${API_URL}/game/spin`, {
-                wallet_address: publicKey.toString(),
                 bet_amount: betAmount,
                 client_seed: clientSeed
-            });
+            }, { headers: { 'Idempotency-Key': crypto.randomUUID() } });
 
             const data = res.data.data;
 
@@ -171,11 +192,10 @@ export default function GameTerminal() {
             addLog("Requesting server authorization...");
 
             const res = await axios.post(`${API_URL}/wallet/withdraw`, {
-                wallet_address: publicKey.toString(),
                 amount: parseFloat(balance)
-            });
+            }, { headers: { 'Idempotency-Key': crypto.randomUUID() } });
 
-            const { signature, recovery_id, nonce, amount_lamports } = res.data.data;
+            const { signature, recovery_id, nonce, amount_lamports, expires_at } = res.data.data;
 
             setBalance("0.00");
             addLog("Auth received. Constructing transaction...");
@@ -200,6 +220,7 @@ export default function GameTerminal() {
                 .withdraw(
                     new BN(amount_lamports),
                     new BN(nonce),
+                    new BN(expires_at),
                     sigArray,
                     recovery_id
                 )
@@ -231,7 +252,7 @@ export default function GameTerminal() {
             addLog("✅ Withdrawal Confirmed on Blockchain.");
 
             await axios.post(`${API_URL}/wallet/complete-withdraw`, {
-                wallet_address: publicKey.toString()
+                tx_signature: txSignature
             });
 
         } catch (e) {
@@ -240,21 +261,17 @@ export default function GameTerminal() {
             addLog(`⚠️ Transaction failed/cancelled. Attempting refund...`);
 
             try {
-                await axios.post(`${API_URL}/wallet/refund`, {
-                    wallet_address: publicKey.toString()
-                });
+                await axios.post(`${API_URL}/wallet/refund`);
                 addLog("✅ Refund Successful! Balance restored.");
             } catch (refundError) {
                 console.error("Refund denied:", refundError);
                 addLog("ℹ️ Refund denied. Checking if transaction actually succeeded...");
 
                 try {
-                    await axios.post(`${API_URL}/wallet/complete-withdraw`, {
-                        wallet_address: publicKey.toString()
-                    });
+                    await axios.post(`${API_URL}/wallet/complete-withdraw`);
                     addLog("✅ It did succeed! State updated.");
                 } catch (finalError) {
-                    addLog("❌ Critical Sync Error. Contact Support.");
+                    addLog("⏳ Authorization still valid. Funds return automatically once it expires.");
                 }
             }
 
@@ -266,6 +283,9 @@ export default function GameTerminal() {
         if (publicKey) {
             addLog(`Connected: ${publicKey.toBase58().substring(0,6)}...`);
             initSession();
+        } else {
+            delete axios.defaults.headers.common['Authorization'];
+            setAuthToken(null);
         }
     }, [publicKey]);
 
@@ -301,12 +321,12 @@ export default function GameTerminal() {
                         min="0.1"
                     />
                 </div>
-                <button onClick={spin} disabled={isSpinning || !publicKey}>
+                <button onClick={spin} disabled={isSpinning || !authToken}>
                     [ SPIN_REELS ]
                 </button>
                 <div className="secondary-actions">
-                    <button onClick={deposit} disabled={!publicKey}>[ DEPOSIT ]</button>
-                    <button onClick={withdraw} disabled={!publicKey}>[ WITHDRAW ]</button>
+                    <button onClick={deposit} disabled={!authToken}>[ DEPOSIT ]</button>
+                    <button onClick={withdraw} disabled={!authToken}>[ WITHDRAW ]</button>
                 </div>
             </div>
 
@@ -316,7 +336,7 @@ export default function GameTerminal() {
                 ))}
             </div>
 
-            <HistoryPanel publicKey={publicKey} />
+            <HistoryPanel authToken={authToken} />
 
             <style>{`
                 .terminal-interface {
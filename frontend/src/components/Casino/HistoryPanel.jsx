@@ -5,17 +5,19 @@ import { sha256 } from 'js-sha256';
 
 const API_URL = import.meta.env.PUBLIC_API_URL;
 
-export default function HistoryPanel({ publicKey }) {
+export default function HistoryPanel({ authToken }) {
     const [spins, setSpins] = useState([]);
     const [selectedSpin, setSelectedSpin] = useState(null);
     const [verificationStatus, setVerificationStatus] = useState(null);
 
     useEffect(() => {
-        if (!publicKey) return;
-        axios.get(`${API_URL}/game/history?wallet_address=${publicKey.toString()}`)
+        if (!authToken) return;
+        axios.get(`${API_URL}/game/history`, {
+            headers: { Authorization: `Bearer ${authToken}` }
+        })
             .then(res => setSpins(res.data.data || []))
             .catch(err => console.error("History fetch error:", err));
-    }, [publicKey]);
+    }, [authToken]);
 
     const verifySpin = async (spinId) => {
         setVerificationStatus("Fetching proof...");
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/db"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository/postgres"
+)
+
+// reconcile proves the ledger: every journal must sum to zero and every
+// wallet's ledger totals must equal its stored playable and pending balances.
+// Exits 1 if anything is off.
+//
+//	DATABASE_URL=postgres://... go run ./cmd/reconcile
+func main() {
+	_ = godotenv.Load()
+
+	dbURL := flag.String("db", os.Getenv("DATABASE_URL"), "Postgres connection string")
+	flag.Parse()
+
+	if *dbURL == "" {
+		log.Fatal("DATABASE_URL or -db is required")
+	}
+
+	pool, err := db.ConnectDB(*dbURL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	repo := postgres.NewPostgresRepo(pool)
+
+	report, err := repo.ReconcileLedger(context.Background())
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+
+	if !report.Balanced() {
+		fmt.Printf("\n❌ Ledger does not reconcile: %d wallet mismatches, %d unbalanced journals\n",
+			len(report.Mismatches), len(report.UnbalancedJournals))
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ Ledger reconciles across %d wallets\n", report.Wallets)
+}
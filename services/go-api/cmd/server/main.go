@@ -3,18 +3,25 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"log"
 	"os"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/api"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/db"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository/postgres"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/risk"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/worker"
-	"golang.org/x/crypto/sha3"
+	"github.com/shopspring/decimal"
 )
 
 func main() {
@@ -31,10 +38,16 @@ func main() {
 	}
 	defer pool.Close()
 
-	serverPrivKey := os.Getenv("SERVER_SECP_PRIVATE_KEY_HEX")
-	if serverPrivKey == "" {
-		log.Fatal("SERVER_SECP_PRIVATE_KEY_HEX environment variable is required")
+	signerBackend := os.Getenv("SIGNER_BACKEND")
+	if signerBackend == "" {
+		signerBackend = "local"
 	}
+	loadedSigner, err := loadSigner(context.Background(), signerBackend)
+	if err != nil {
+		log.Fatalf("Unable to load withdrawal signer: %v", err)
+	}
+	log.Printf("🔑 Server Signing Identity (Hash): %s", hex.EncodeToString(loadedSigner.Authority()))
+	withdrawalSigner := signer.NewRotating(loadedSigner)
 
 	rpcURL := os.Getenv("SOLANA_RPC_URL")
 	if rpcURL == "" {
@@ -42,25 +55,31 @@ func main() {
 	}
 	rpcClient := rpc.New(rpcURL)
 
+	// Commit and withdrawal transactions are only treated as landed once they reach this level.
+	commitment := rpc.CommitmentType(os.Getenv("SOLANA_COMMITMENT"))
+	if commitment == "" {
+		commitment = rpc.CommitmentConfirmed
+	}
+	tracker := txconfirm.NewTracker(rpcClient, commitment)
+
 	serverWalletPath := os.Getenv("SERVER_WALLET_PATH")
 	programID := os.Getenv("PROGRAM_ID")
 	vaultAddr := os.Getenv("VAULT_ADDRESS")
 
-	//DEBUG
-	pkBytes, _ := hex.DecodeString(serverPrivKey)
-	ecdsaKey, _ := crypto.ToECDSA(pkBytes)
-	pubKeyBytes := crypto.FromECDSAPub(&ecdsaKey.PublicKey)
-
-	hasher := sha3.NewLegacyKeccak256()
-	hasher.Write(pubKeyBytes[1:])
-	identityHash := hex.EncodeToString(hasher.Sum(nil))
-
-	log.Printf("🔑 Server Signing Identity (Hash): %s", identityHash)
-	//
+	games, err := game.NewDefaultRegistry()
+	if err != nil {
+		log.Fatalf("Failed to load built-in games: %v", err)
+	}
+	if gamesDir := os.Getenv("GAME_DEFINITIONS_DIR"); gamesDir != "" {
+		if err := games.LoadDir(gamesDir); err != nil {
+			log.Fatalf("Failed to load game definitions from %s: %v", gamesDir, err)
+		}
+	}
+	log.Printf("🎰 Loaded games: %v", games.IDs())
 
 	repo := postgres.NewPostgresRepo(pool)
 
-	committer, err := worker.NewBatchCommitter(repo, rpcURL, serverWalletPath, programID, vaultAddr)
+	committer, err := worker.NewBatchCommitter(repo, rpcClient, tracker, serverWalletPath, programID, vaultAddr)
 	if err != nil {
 		log.Printf("⚠️  Warning: Failed to start Batch Committer: %v", err)
 		log.Println("Server will run, but spins will NOT be anchored to Solana.")
@@ -68,11 +87,154 @@ func main() {
 		go committer.Start(context.Background())
 	}
 
+	indexer, err := worker.NewDepositIndexer(repo, rpcClient, programID, vaultAddr)
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to start Deposit Indexer: %v", err)
+		log.Println("Server will run, but deposits are only credited through /wallet/sync.")
+	} else {
+		go indexer.Start(context.Background())
+	}
+
+	withdrawals, err := worker.NewWithdrawalReconciler(repo, rpcClient, tracker, programID)
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to start Withdrawal Reconciler: %v", err)
+		log.Println("Server will run, but withdrawals only complete through /wallet/complete-withdraw.")
+	} else {
+		go withdrawals.Start(context.Background())
+	}
+
+	bankroll, err := risk.NewManager(repo, rpcClient, vaultAddr, riskConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize bankroll risk manager: %v", err)
+	}
+	go bankroll.Start(context.Background())
+
+	reconciliationSvc, err := service.NewReconciliationService(repo, rpcClient, bankroll, programID)
+	if err != nil {
+		log.Fatalf("Failed to initialize reconciliation: %v", err)
+	}
+	go worker.NewBalanceReconciler(reconciliationSvc).Start(context.Background())
+
+	authoritySvc, err := newAuthorityService(repo, withdrawalSigner, rpcClient, tracker, serverWalletPath, programID, vaultAddr, signerBackend)
+	if err != nil {
+		log.Printf("⚠️  Warning: Signing authority admin is unavailable: %v", err)
+	} else if status, err := authoritySvc.Status(context.Background()); err != nil {
+		log.Printf("⚠️  Warning: Could not read the on-chain signing authority: %v", err)
+	} else if status.Authority != status.OnChainAuthority {
+		log.Printf("⚠️  Warning: Signing key %s does not match the vault's signing authority %s", status.Authority, status.OnChainAuthority)
+		if status.RotationPending {
+			log.Println("A rotation was interrupted; POST /api/v1/admin/signing-authority/rotate finishes it.")
+		}
+	}
+
 	router := gin.Default()
 
-	api.RegisterRoutes(router, pool, serverPrivKey, rpcClient, vaultAddr)
+	// Domain shown in the Sign-In-With-Solana message the wallet signs.
+	authDomain := os.Getenv("AUTH_DOMAIN")
+	if authDomain == "" {
+		authDomain = "localhost"
+	}
+
+	adminToken := os.Getenv("ADMIN_API_TOKEN")
+	if adminToken == "" {
+		log.Println("ADMIN_API_TOKEN not set, admin routes are disabled.")
+	}
+
+	api.RegisterRoutes(router, pool, games, withdrawalSigner, rpcClient, tracker, vaultAddr, programID, authDomain, bankroll, authoritySvc, reconciliationSvc, adminToken)
 
 	if err := router.Run(":8080"); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// loadSigner picks the withdrawal signer from SIGNER_BACKEND:
+//
+//	local    - SERVER_SECP_PRIVATE_KEY_HEX (default)
+//	keystore - SIGNER_KEYSTORE_PATH plus SIGNER_KEYSTORE_PASSPHRASE(_FILE)
+//	remote   - SIGNER_URL, a cmd/signer process (unix:///path.sock or http://127.0.0.1:port)
+func loadSigner(ctx context.Context, backend string) (signer.Signer, error) {
+	var s signer.Signer
+	switch backend {
+	case "local":
+		keyHex := os.Getenv("SERVER_SECP_PRIVATE_KEY_HEX")
+		if keyHex == "" {
+			return nil, fmt.Errorf("SERVER_SECP_PRIVATE_KEY_HEX environment variable is required")
+		}
+		local, err := signer.NewLocalSigner(keyHex)
+		if err != nil {
+			return nil, err
+		}
+		s = local
+	case "keystore":
+		path := os.Getenv("SIGNER_KEYSTORE_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("SIGNER_KEYSTORE_PATH environment variable is required")
+		}
+		passphrase, err := signer.ReadPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		local, err := signer.LoadKeystore(path, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		s = local
+	case "remote":
+		url := os.Getenv("SIGNER_URL")
+		if url == "" {
+			return nil, fmt.Errorf("SIGNER_URL environment variable is required")
+		}
+		remote, err := signer.NewRemoteSigner(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		s = remote
+	default:
+		return nil, fmt.Errorf("unknown SIGNER_BACKEND %q", backend)
+	}
+
+	return signer.WithAudit(s, backend), nil
+}
+
+// riskConfig reads the bankroll limits, falling back to the defaults:
+//
+//	RISK_MAX_EXPOSURE - share of free vault liquidity one spin may win (0.01)
+//	RISK_ALERT_RATIO  - liabilities to liquidity ratio that raises an alert (0.8)
+func riskConfig() risk.Config {
+	cfg := risk.DefaultConfig()
+	if v := os.Getenv("RISK_MAX_EXPOSURE"); v != "" {
+		exposure, err := decimal.NewFromString(v)
+		if err != nil || !exposure.IsPositive() || exposure.GreaterThan(decimal.NewFromInt(1)) {
+			log.Fatalf("RISK_MAX_EXPOSURE must be in (0, 1], got %q", v)
+		}
+		cfg.MaxExposure = exposure
+	}
+	if v := os.Getenv("RISK_ALERT_RATIO"); v != "" {
+		ratio, err := decimal.NewFromString(v)
+		if err != nil || !ratio.IsPositive() {
+			log.Fatalf("RISK_ALERT_RATIO must be positive, got %q", v)
+		}
+		cfg.AlertRatio = ratio
+	}
+	return cfg
+}
+
+// newAuthorityService sets up signing-authority rotation. Rotation writes the
+// new key to SIGNER_KEYSTORE_PATH, so it is only enabled for the keystore
+// backend; the other backends can still report their status.
+func newAuthorityService(repo repository.Repository, withdrawalSigner *signer.Rotating, rpcClient solana_parser.ChainClient, tracker *txconfirm.Tracker, serverWalletPath string, programID string, vaultAddr string, backend string) (*service.AuthorityService, error) {
+	operator, err := solana_parser.LoadKeypair(serverWalletPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var keystorePath, passphrase string
+	if backend == "keystore" {
+		keystorePath = os.Getenv("SIGNER_KEYSTORE_PATH")
+		if passphrase, err = signer.ReadPassphrase(); err != nil {
+			return nil, err
+		}
+	}
+
+	return service.NewAuthorityService(repo, withdrawalSigner, rpcClient, tracker, operator, programID, vaultAddr, keystorePath, passphrase)
+}
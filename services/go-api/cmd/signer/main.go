@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
+)
+
+// signer keeps the withdrawal signing key out of the API process. It decrypts
+// a keystore once at startup and serves signatures over a Unix socket or a
+// local TCP address; the API points SIGNER_URL at it. Every request is logged.
+//
+//	echo $SERVER_SECP_PRIVATE_KEY_HEX | go run ./cmd/signer -encrypt -keystore signer.json
+//	SIGNER_KEYSTORE_PASSPHRASE_FILE=/run/secrets/signer go run ./cmd/signer -keystore signer.json -listen unix:///run/casino-signer.sock
+func main() {
+	_ = godotenv.Load()
+
+	keystorePath := flag.String("keystore", os.Getenv("SIGNER_KEYSTORE_PATH"), "encrypted keystore file")
+	listen := flag.String("listen", "unix:///tmp/casino-signer.sock", "unix:///path.sock or host:port (loopback only)")
+	encrypt := flag.Bool("encrypt", false, "read a hex private key from stdin and write it to -keystore encrypted")
+	flag.Parse()
+
+	if *keystorePath == "" {
+		log.Fatal("SIGNER_KEYSTORE_PATH or -keystore is required")
+	}
+	passphrase, err := signer.ReadPassphrase()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *encrypt {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Reading private key from stdin: %v", err)
+		}
+		data, err := signer.EncryptKeystore(strings.TrimSpace(line), passphrase)
+		if err != nil {
+			log.Fatalf("Encrypting key failed: %v", err)
+		}
+		if err := os.WriteFile(*keystorePath, data, 0o600); err != nil {
+			log.Fatalf("Writing keystore failed: %v", err)
+		}
+		fmt.Printf("✅ Keystore written to %s\n", *keystorePath)
+		return
+	}
+
+	local, err := signer.LoadKeystore(*keystorePath, passphrase)
+	if err != nil {
+		log.Fatalf("Loading keystore failed: %v", err)
+	}
+	log.Printf("🔑 Signing Identity (Hash): %s", hex.EncodeToString(local.Authority()))
+
+	ln, err := listenLocal(*listen)
+	if err != nil {
+		log.Fatalf("Listening on %s failed: %v", *listen, err)
+	}
+	log.Printf("✍️  Signer listening on %s", *listen)
+
+	handler := signer.NewHandler(signer.WithAudit(local, "signer"))
+	if err := http.Serve(ln, handler); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// listenLocal refuses anything but a Unix socket or a loopback address: the
+// protocol has no authentication of its own.
+func listenLocal(addr string) (net.Listener, error) {
+	if socket, ok := strings.CutPrefix(addr, "unix://"); ok {
+		_ = os.Remove(socket)
+		ln, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(socket, 0o600); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("refusing to serve on non-loopback address %s", host)
+	}
+	return net.Listen("tcp", addr)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/shopspring/decimal"
+)
+
+// simulate reports RTP and volatility for a slot definition, both by Monte
+// Carlo play through game.CalculateSpin and by exhaustive enumeration of
+// every reel-stop combination. A non-zero exit code means the exact RTP fell
+// outside [-min-rtp, -max-rtp], so it can gate config changes in CI.
+func main() {
+	gameID := flag.String("game", game.DefaultGameID, "game id to simulate")
+	version := flag.Int("version", 0, "game version (0 = latest)")
+	defsDir := flag.String("defs", "", "extra directory of game definitions to load")
+	spins := flag.Int64("spins", 10_000_000, "number of Monte Carlo spins (0 to skip)")
+	workers := flag.Int("workers", runtime.NumCPU(), "parallel Monte Carlo workers")
+	exact := flag.Bool("exact", true, "compute exact RTP by enumerating every reel-stop combination")
+	exactLimit := flag.Int64("exact-limit", 100_000_000, "refuse exact enumeration above this many combinations")
+	minRTP := flag.Float64("min-rtp", 0, "fail if the exact RTP is below this value (e.g. 0.92)")
+	maxRTP := flag.Float64("max-rtp", 1, "fail if the exact RTP is above this value (e.g. 0.98)")
+	flag.Parse()
+
+	registry, err := game.NewDefaultRegistry()
+	if err != nil {
+		log.Fatalf("Failed to load built-in games: %v", err)
+	}
+	if *defsDir != "" {
+		if err := registry.LoadDir(*defsDir); err != nil {
+			log.Fatalf("Failed to load game definitions: %v", err)
+		}
+	}
+
+	var def *game.Definition
+	if *version == 0 {
+		def, err = registry.Latest(*gameID)
+	} else {
+		def, err = registry.Get(*gameID, *version)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Game %s v%d: %d reels x %d rows, %d paylines\n", def.ID, def.Version, def.Cols(), def.Rows, len(def.Paylines))
+
+	if *spins > 0 {
+		start := time.Now()
+		stats, err := monteCarlo(def, *spins, *workers)
+		if err != nil {
+			log.Fatalf("Simulation failed: %v", err)
+		}
+		fmt.Printf("\n--- Monte Carlo (%d spins, %s) ---\n", stats.Spins, time.Since(start).Round(time.Millisecond))
+		printStats(def, stats)
+	}
+
+	if !*exact {
+		return
+	}
+
+	combos, err := def.Combinations()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if combos > *exactLimit {
+		log.Fatalf("Exact enumeration needs %d combinations (limit %d)", combos, *exactLimit)
+	}
+
+	start := time.Now()
+	stats := def.ExactStats()
+	fmt.Printf("\n--- Exact (%d combinations, %s) ---\n", stats.Spins, time.Since(start).Round(time.Millisecond))
+	printStats(def, stats)
+
+	rtp := stats.RTP()
+	if rtp < *minRTP || rtp > *maxRTP {
+		fmt.Printf("\n❌ RTP %.4f%% outside allowed range [%.4f%%, %.4f%%]\n", rtp*100, *minRTP*100, *maxRTP*100)
+		os.Exit(1)
+	}
+}
+
+// monteCarlo plays spins with a fresh random server seed per worker and a
+// running nonce, exactly as GameService does for a session.
+func monteCarlo(def *game.Definition, spins int64, workers int) (*game.Stats, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	bet := decimal.NewFromInt(1)
+
+	var wg sync.WaitGroup
+	results := make([]*game.Stats, workers)
+	errs := make([]error, workers)
+
+	for w := 0; w < workers; w++ {
+		share := spins / int64(workers)
+		if int64(w) < spins%int64(workers) {
+			share++
+		}
+
+		wg.Add(1)
+		go func(w int, share int64) {
+			defer wg.Done()
+			local := game.NewStats()
+			results[w] = local
+
+			serverSeed, err := crypto.GenerateSeed()
+			if err != nil {
+				errs[w] = err
+				return
+			}
+			clientSeed, err := crypto.GenerateSeed()
+			if err != nil {
+				errs[w] = err
+				return
+			}
+
+			for nonce := int64(1); nonce <= share; nonce++ {
+				result, err := game.CalculateSpin(def, serverSeed, clientSeed, nonce, bet)
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				local.Add(result.Wins)
+			}
+		}(w, share)
+	}
+	wg.Wait()
+
+	total := game.NewStats()
+	for w := range results {
+		if errs[w] != nil {
+			return nil, errs[w]
+		}
+		total.Merge(results[w])
+	}
+	return total, nil
+}
+
+func printStats(def *game.Definition, stats *game.Stats) {
+	fmt.Printf("RTP:            %.4f%%\n", stats.RTP()*100)
+	fmt.Printf("Hit frequency:  %.4f%% (1 in %.2f)\n", stats.HitFrequency()*100, 1/stats.HitFrequency())
+	fmt.Printf("Variance:       %.4f\n", stats.Variance())
+	fmt.Printf("Std deviation:  %.4f\n", stats.StdDev())
+	fmt.Printf("Max win:        %.2fx\n", stats.MaxWin)
+
+	fmt.Println("\nBy payline:")
+	lines := make([]int, 0, len(stats.ByPayline))
+	for line := range stats.ByPayline {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	for _, line := range lines {
+		label := fmt.Sprintf("line %d", line)
+		if line < 0 {
+			label = "scatter"
+		}
+		fmt.Printf("  %-10s %8.4f%%\n", label, stats.ByPayline[line]/float64(stats.Spins)*100)
+	}
+
+	fmt.Println("\nBy symbol:")
+	symbols := make([]game.Symbol, 0, len(stats.BySymbol))
+	for sym := range stats.BySymbol {
+		symbols = append(symbols, sym)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	for _, sym := range symbols {
+		fmt.Printf("  %-10s %8.4f%%\n", def.SymbolName(sym), stats.BySymbol[sym]/float64(stats.Spins)*100)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/verify"
+)
+
+// verify checks a spin offline. It reads the JSON returned by
+// GET /api/v1/game/proof/:spin_id (either the whole response or its "data"
+// field) and replays the spin, its leaf hash and its Merkle proof. With -rpc it
+// also compares the root against the BatchCommit account on Solana, and with
+// -seed-hash it checks the seed against the hash shown before the spin.
+//
+//	curl -s $API/game/proof/$SPIN_ID | go run ./cmd/verify -rpc https://api.devnet.solana.com
+func main() {
+	inPath := flag.String("in", "-", "proof JSON file ('-' for stdin)")
+	rpcURL := flag.String("rpc", "", "Solana RPC URL for the on-chain root check (optional)")
+	programID := flag.String("program", os.Getenv("PROGRAM_ID"), "casino program id")
+	defsDir := flag.String("defs", "", "extra directory of game definitions to load")
+	seedHash := flag.String("seed-hash", "", "server seed hash you were shown before the spin (optional)")
+	flag.Parse()
+
+	var in io.Reader = os.Stdin
+	if *inPath != "-" {
+		f, err := os.Open(*inPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	raw, err := io.ReadAll(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var envelope struct {
+		Data *verify.Request `json:"data"`
+	}
+	var req verify.Request
+	if err := json.Unmarshal(raw, &envelope); err == nil && envelope.Data != nil {
+		req = *envelope.Data
+	} else if err := json.Unmarshal(raw, &req); err != nil {
+		log.Fatalf("Invalid proof JSON: %v", err)
+	}
+
+	if *seedHash != "" {
+		req.CommittedSeedHash = *seedHash
+	}
+
+	games, err := game.NewDefaultRegistry()
+	if err != nil {
+		log.Fatalf("Failed to load built-in games: %v", err)
+	}
+	if *defsDir != "" {
+		if err := games.LoadDir(*defsDir); err != nil {
+			log.Fatalf("Failed to load game definitions: %v", err)
+		}
+	}
+
+	verifier, err := newVerifier(games, *rpcURL, *programID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := verifier.Verify(context.Background(), &req)
+	if err != nil {
+		log.Fatalf("Verification failed: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
+
+// newVerifier connects to rpcURL when one is given. Without it the verifier
+// runs offline and needs no program id.
+func newVerifier(games *game.Registry, rpcURL string, programID string) (*verify.Verifier, error) {
+	var rpcClient solana_parser.ChainClient
+	if rpcURL != "" {
+		rpcClient = rpc.New(rpcURL)
+	}
+	return verify.NewVerifier(games, rpcClient, programID)
+}
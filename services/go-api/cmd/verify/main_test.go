@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/verify"
+	"github.com/shopspring/decimal"
+)
+
+func TestVerifyOffline(t *testing.T) {
+	games, err := game.NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+	def, _ := games.Latest(game.DefaultGameID)
+
+	serverSeed, _ := crypto.GenerateSeed()
+	bet := decimal.RequireFromString("0.1")
+	wallet := "AMyC4nrskq9PERnZfFZv3KRhEm23VUpRV4VrggAjYiiU"
+	result, err := game.CalculateSpin(def, serverSeed, "client-seed", 1, bet)
+	if err != nil {
+		t.Fatalf("Spin failed: %v", err)
+	}
+	var reels []int
+	for _, row := range result.Matrix {
+		for _, sym := range row {
+			reels = append(reels, int(sym))
+		}
+	}
+	leaf := verify.LeafHash(wallet, 1, serverSeed, "client-seed", bet, result.Matrix, result.TotalPayout)
+	leaves := []string{leaf, crypto.HashStringSHA256("other")}
+	root, _ := crypto.ComputeMerkleRootRFC6962(leaves)
+	proof, _ := crypto.GenerateMerkleProofRFC6962(leaves, 0)
+	proofJSON, _ := json.Marshal(proof)
+
+	batchID := int64(4)
+	req := &verify.Request{
+		Spin: domain.Spin{
+			WalletAddress:  wallet,
+			SpinNonce:      1,
+			GameID:         def.ID,
+			GameVersion:    def.Version,
+			ServerSeed:     serverSeed,
+			ClientSeed:     "client-seed",
+			ServerSeedHash: crypto.HashStringSHA256(serverSeed),
+			BetAmount:      bet,
+			PayoutAmount:   result.TotalPayout,
+			Outcome:        domain.SpinOutcome{Reels: reels, Rows: def.Rows},
+			LeafHash:       leaf,
+		},
+		BatchID:    &batchID,
+		TreeFormat: int(crypto.MerkleFormatRFC6962),
+		MerkleRoot: &root,
+		Proof:      proofJSON,
+	}
+
+	// Without -rpc neither a program id nor a node is needed, whether or not
+	// -program is set.
+	for _, programID := range []string{"", "11111111111111111111111111111111"} {
+		verifier, err := newVerifier(games, "", programID)
+		if err != nil {
+			t.Fatalf("newVerifier(%q) failed: %v", programID, err)
+		}
+		report, err := verifier.Verify(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if !report.Valid || report.OnChainChecked {
+			t.Errorf("Expected a valid offline report, got %+v", report)
+		}
+	}
+}
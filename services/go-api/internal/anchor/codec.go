@@ -0,0 +1,577 @@
+package anchor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// primitiveSizes are the Borsh widths of the primitive IDL types; -1 marks
+// length-prefixed types.
+var primitiveSizes = map[string]int{
+	"bool":   1,
+	"u8":     1,
+	"i8":     1,
+	"u16":    2,
+	"i16":    2,
+	"u32":    4,
+	"i32":    4,
+	"u64":    8,
+	"i64":    8,
+	"f32":    4,
+	"f64":    8,
+	"pubkey": 32,
+	"string": -1,
+	"bytes":  -1,
+}
+
+// Type is an IDL type: a primitive like "u64", a reference to a defined type,
+// or an array, vec or option of another type.
+type Type struct {
+	Primitive string
+	Defined   string
+	Kind      string // "array", "vec" or "option" when Elem is set
+	Elem      *Type
+	Len       int // array length
+}
+
+func (t *Type) UnmarshalJSON(data []byte) error {
+	var primitive string
+	if err := json.Unmarshal(data, &primitive); err == nil {
+		t.Primitive = primitive
+		return nil
+	}
+
+	var raw struct {
+		Array   []json.RawMessage `json:"array"`
+		Vec     *Type             `json:"vec"`
+		Option  *Type             `json:"option"`
+		Defined json.RawMessage   `json:"defined"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid type %s: %w", data, err)
+	}
+
+	switch {
+	case raw.Array != nil:
+		if len(raw.Array) != 2 {
+			return fmt.Errorf("invalid array type %s", data)
+		}
+		var elem Type
+		if err := json.Unmarshal(raw.Array[0], &elem); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw.Array[1], &t.Len); err != nil {
+			return fmt.Errorf("invalid array length in %s", data)
+		}
+		t.Kind, t.Elem = "array", &elem
+	case raw.Vec != nil:
+		t.Kind, t.Elem = "vec", raw.Vec
+	case raw.Option != nil:
+		t.Kind, t.Elem = "option", raw.Option
+	case raw.Defined != nil:
+		// Anchor 0.30 writes {"defined": {"name": X}}, older IDLs {"defined": X}.
+		var named struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw.Defined, &named); err == nil && named.Name != "" {
+			t.Defined = named.Name
+		} else if err := json.Unmarshal(raw.Defined, &t.Defined); err != nil {
+			return fmt.Errorf("invalid defined type %s", data)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", data)
+	}
+	return nil
+}
+
+func (t Type) String() string {
+	switch {
+	case t.Defined != "":
+		return t.Defined
+	case t.Kind == "array":
+		return fmt.Sprintf("[%s; %d]", t.Elem, t.Len)
+	case t.Elem != nil:
+		return fmt.Sprintf("%s<%s>", t.Kind, t.Elem)
+	default:
+		return t.Primitive
+	}
+}
+
+// AccountSize is the encoded length of the named account, discriminator
+// included. It is false for unknown accounts and accounts of variable length.
+func (idl *IDL) AccountSize(name string) (int, bool) {
+	def, ok := idl.types[name]
+	if !ok {
+		return 0, false
+	}
+	size := 8
+	for _, f := range def.Type.Fields {
+		n := idl.size(f.Type)
+		if n < 0 {
+			return 0, false
+		}
+		size += n
+	}
+	return size, true
+}
+
+// size is the fixed encoded length of t, or -1.
+func (idl *IDL) size(t Type) int {
+	switch {
+	case t.Defined != "":
+		def := idl.types[t.Defined]
+		if def.Type.Kind == "enum" {
+			return 1
+		}
+		total := 0
+		for _, f := range def.Type.Fields {
+			n := idl.size(f.Type)
+			if n < 0 {
+				return -1
+			}
+			total += n
+		}
+		return total
+	case t.Kind == "array":
+		n := idl.size(*t.Elem)
+		if n < 0 {
+			return -1
+		}
+		return n * t.Len
+	case t.Elem != nil:
+		return -1
+	default:
+		return primitiveSizes[t.Primitive]
+	}
+}
+
+// decoder reads Borsh values off the front of data.
+type decoder struct {
+	idl  *IDL
+	data []byte
+}
+
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || len(d.data) < n {
+		return nil, fmt.Errorf("data too short: need %d bytes, have %d", n, len(d.data))
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *decoder) length() (int, error) {
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint32(b)), nil
+}
+
+// decodeFields decodes fields in order from data. Trailing bytes, like the
+// padding of accounts allocated with spare space, are ignored.
+func (idl *IDL) decodeFields(data []byte, fields []Field) (map[string]interface{}, error) {
+	d := &decoder{idl: idl, data: data}
+	return d.fields(fields)
+}
+
+func (d *decoder) fields(fields []Field) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		v, err := d.value(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		out[f.Name] = v
+	}
+	return out, nil
+}
+
+// value decodes one value of t. [u8; N] and bytes decode to []byte, pubkey to
+// solana.PublicKey, unit enums to the variant name and options to nil or the
+// inner value.
+func (d *decoder) value(t Type) (interface{}, error) {
+	switch {
+	case t.Defined != "":
+		def := d.idl.types[t.Defined]
+		if def.Type.Kind == "struct" {
+			return d.fields(def.Type.Fields)
+		}
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		if int(b[0]) >= len(def.Type.Variants) {
+			return nil, fmt.Errorf("%s has no variant %d", def.Name, b[0])
+		}
+		return def.Type.Variants[b[0]].Name, nil
+
+	case t.Kind == "array" && t.Elem.Primitive == "u8":
+		b, err := d.take(t.Len)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+
+	case t.Kind == "array" || t.Kind == "vec":
+		n := t.Len
+		if t.Kind == "vec" {
+			var err error
+			if n, err = d.length(); err != nil {
+				return nil, err
+			}
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := d.value(*t.Elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			items = append(items, v)
+		}
+		return items, nil
+
+	case t.Kind == "option":
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		switch b[0] {
+		case 0:
+			return nil, nil
+		case 1:
+			return d.value(*t.Elem)
+		default:
+			return nil, fmt.Errorf("invalid option tag %d", b[0])
+		}
+	}
+
+	if t.Primitive == "string" || t.Primitive == "bytes" {
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		if t.Primitive == "string" {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	}
+
+	b, err := d.take(primitiveSizes[t.Primitive])
+	if err != nil {
+		return nil, err
+	}
+	switch t.Primitive {
+	case "bool":
+		return b[0] != 0, nil
+	case "u8":
+		return b[0], nil
+	case "i8":
+		return int8(b[0]), nil
+	case "u16":
+		return binary.LittleEndian.Uint16(b), nil
+	case "i16":
+		return int16(binary.LittleEndian.Uint16(b)), nil
+	case "u32":
+		return binary.LittleEndian.Uint32(b), nil
+	case "i32":
+		return int32(binary.LittleEndian.Uint32(b)), nil
+	case "u64":
+		return binary.LittleEndian.Uint64(b), nil
+	case "i64":
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	case "f32":
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "f64":
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "pubkey":
+		return solana.PublicKeyFromBytes(b), nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", t.Primitive)
+}
+
+// encodeValue appends the Borsh encoding of v as type t. Integers may be given
+// as any Go integer type that fits; byte arrays as []byte, [N]byte or a
+// solana.PublicKey.
+func (idl *IDL) encodeValue(buf *bytes.Buffer, t Type, v interface{}) error {
+	switch {
+	case t.Defined != "":
+		def := idl.types[t.Defined]
+		if def.Type.Kind == "enum" {
+			name, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("%s: want variant name, got %T", def.Name, v)
+			}
+			for i, variant := range def.Type.Variants {
+				if variant.Name == name {
+					buf.WriteByte(byte(i))
+					return nil
+				}
+			}
+			return fmt.Errorf("%s has no variant %s", def.Name, name)
+		}
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want map[string]interface{}, got %T", def.Name, v)
+		}
+		for _, f := range def.Type.Fields {
+			fv, ok := fields[f.Name]
+			if !ok {
+				return fmt.Errorf("%s: missing field %s", def.Name, f.Name)
+			}
+			if err := idl.encodeValue(buf, f.Type, fv); err != nil {
+				return fmt.Errorf("%s.%s: %w", def.Name, f.Name, err)
+			}
+		}
+		return nil
+
+	case t.Kind == "option":
+		if v == nil {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.WriteByte(1)
+		return idl.encodeValue(buf, *t.Elem, v)
+
+	case t.Kind == "array" || t.Kind == "vec":
+		if t.Elem.Primitive == "u8" {
+			if b, ok := byteSlice(v); ok {
+				if t.Kind == "array" && len(b) != t.Len {
+					return fmt.Errorf("want %d bytes, got %d", t.Len, len(b))
+				}
+				if t.Kind == "vec" {
+					writeLength(buf, len(b))
+				}
+				buf.Write(b)
+				return nil
+			}
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("want %s, got %T", t, v)
+		}
+		if t.Kind == "array" && rv.Len() != t.Len {
+			return fmt.Errorf("want %d elements, got %d", t.Len, rv.Len())
+		}
+		if t.Kind == "vec" {
+			writeLength(buf, rv.Len())
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := idl.encodeValue(buf, *t.Elem, rv.Index(i).Interface()); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	switch t.Primitive {
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("want bool, got %T", v)
+		}
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		return nil
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("want string, got %T", v)
+		}
+		writeLength(buf, len(s))
+		buf.WriteString(s)
+		return nil
+	case "bytes":
+		b, ok := byteSlice(v)
+		if !ok {
+			return fmt.Errorf("want []byte, got %T", v)
+		}
+		writeLength(buf, len(b))
+		buf.Write(b)
+		return nil
+	case "pubkey":
+		b, ok := byteSlice(v)
+		if !ok || len(b) != 32 {
+			return fmt.Errorf("want a 32-byte public key, got %T", v)
+		}
+		buf.Write(b)
+		return nil
+	case "f32":
+		f, ok := toFloat(v)
+		if !ok {
+			return fmt.Errorf("want float, got %T", v)
+		}
+		return binary.Write(buf, binary.LittleEndian, float32(f))
+	case "f64":
+		f, ok := toFloat(v)
+		if !ok {
+			return fmt.Errorf("want float, got %T", v)
+		}
+		return binary.Write(buf, binary.LittleEndian, f)
+	}
+
+	size := primitiveSizes[t.Primitive]
+	if size <= 0 {
+		return fmt.Errorf("unsupported type %q", t.Primitive)
+	}
+	signed := t.Primitive[0] == 'i'
+	bits := uint(size * 8)
+
+	var raw uint64
+	if signed {
+		n, ok := toInt(v)
+		if !ok {
+			return fmt.Errorf("want %s, got %T", t.Primitive, v)
+		}
+		if bits < 64 && (n < -(1<<(bits-1)) || n >= 1<<(bits-1)) {
+			return fmt.Errorf("%d overflows %s", n, t.Primitive)
+		}
+		raw = uint64(n)
+	} else {
+		n, ok := toUint(v)
+		if !ok {
+			return fmt.Errorf("want %s, got %v (%T)", t.Primitive, v, v)
+		}
+		if bits < 64 && n >= 1<<bits {
+			return fmt.Errorf("%d overflows %s", n, t.Primitive)
+		}
+		raw = n
+	}
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], raw)
+	buf.Write(b[:size])
+	return nil
+}
+
+func writeLength(buf *bytes.Buffer, n int) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(n))
+	buf.Write(b[:])
+}
+
+// byteSlice accepts []byte and byte arrays such as [32]byte or solana.PublicKey.
+func byteSlice(v interface{}) ([]byte, bool) {
+	if b, ok := v.([]byte); ok {
+		return b, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	b := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(b), rv)
+	return b, true
+}
+
+func toInt(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func toUint(v interface{}) (uint64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, false
+		}
+		return uint64(rv.Int()), true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), true
+	case float64:
+		return f, true
+	}
+	return 0, false
+}
+
+// assign copies decoded fields into v. A *map[string]interface{} receives them
+// as they are; a struct pointer receives them by `anchor:"field_name"` tags.
+// []byte values fill byte arrays, so [32]byte fields take [u8; 32] data.
+// Fields without a tag are left alone, and a tag naming a field the IDL does
+// not have is an error, so layout changes surface instead of leaving zeros.
+func assign(fields map[string]interface{}, v interface{}) error {
+	if m, ok := v.(*map[string]interface{}); ok {
+		*m = fields
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a struct pointer, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		name, ok := rt.Field(i).Tag.Lookup("anchor")
+		if !ok {
+			continue
+		}
+		value, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("%s.%s: IDL has no field %s", rt.Name(), rt.Field(i).Name, name)
+		}
+		if err := setField(rv.Field(i), value); err != nil {
+			return fmt.Errorf("%s.%s: %w", rt.Name(), rt.Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+func setField(dst reflect.Value, value interface{}) error {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+	if b, ok := value.([]byte); ok && dst.Kind() == reflect.Array && dst.Type().Elem().Kind() == reflect.Uint8 {
+		if len(b) != dst.Len() {
+			return fmt.Errorf("want %d bytes, got %d", dst.Len(), len(b))
+		}
+		reflect.Copy(dst, src)
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr && src.Type().AssignableTo(dst.Type().Elem()) {
+		p := reflect.New(dst.Type().Elem())
+		p.Elem().Set(src)
+		dst.Set(p)
+		return nil
+	}
+	if !src.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("cannot assign %s to %s", src.Type(), dst.Type())
+	}
+	dst.Set(src)
+	return nil
+}
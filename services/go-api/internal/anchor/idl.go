@@ -0,0 +1,377 @@
+package anchor
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// casinoIDL is a copy of frontend/src/idl/casino.json; TestEmbeddedIDLMatchesFrontend
+// fails when the two drift apart.
+//
+//go:embed idl/casino.json
+var casinoIDL []byte
+
+var (
+	casinoOnce   sync.Once
+	casinoParsed *IDL
+)
+
+// Casino returns the casino program's IDL. The embedded file is validated by
+// the tests, so a parse failure here is a build defect and panics.
+func Casino() *IDL {
+	casinoOnce.Do(func() {
+		idl, err := Parse(casinoIDL)
+		if err != nil {
+			panic(fmt.Sprintf("anchor: embedded casino IDL: %v", err))
+		}
+		casinoParsed = idl
+	})
+	return casinoParsed
+}
+
+// Discriminator is the 8-byte prefix Anchor puts in front of instruction data
+// and account data.
+type Discriminator [8]byte
+
+func (d *Discriminator) UnmarshalJSON(data []byte) error {
+	var ints []int
+	if err := json.Unmarshal(data, &ints); err != nil {
+		return err
+	}
+	if len(ints) != 8 {
+		return fmt.Errorf("discriminator must be 8 bytes, got %d", len(ints))
+	}
+	for i, v := range ints {
+		if v < 0 || v > 255 {
+			return fmt.Errorf("discriminator byte %d out of range", v)
+		}
+		d[i] = byte(v)
+	}
+	return nil
+}
+
+// Matches reports whether data starts with d.
+func (d Discriminator) Matches(data []byte) bool {
+	return len(data) >= 8 && bytes.Equal(data[:8], d[:])
+}
+
+// IDL is an Anchor (0.30+) program IDL. Only what the Go side needs to encode
+// and decode is kept.
+type IDL struct {
+	Address      string         `json:"address"`
+	Metadata     Metadata       `json:"metadata"`
+	Instructions []*Instruction `json:"instructions"`
+	Accounts     []*AccountDef  `json:"accounts"`
+	Types        []*TypeDef     `json:"types"`
+	Errors       []ErrorDef     `json:"errors"`
+
+	types map[string]*TypeDef
+}
+
+type Metadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Spec    string `json:"spec"`
+}
+
+// Instruction is one program instruction: its discriminator, the accounts it
+// expects in order, and its Borsh-encoded arguments.
+type Instruction struct {
+	Name          string               `json:"name"`
+	Discriminator Discriminator        `json:"discriminator"`
+	Accounts      []InstructionAccount `json:"accounts"`
+	Args          []Field              `json:"args"`
+
+	idl *IDL
+}
+
+type InstructionAccount struct {
+	Name     string `json:"name"`
+	Writable bool   `json:"writable"`
+	Signer   bool   `json:"signer"`
+	// Address is set for accounts with a fixed address, like the system program.
+	Address string `json:"address"`
+}
+
+// AccountDef ties an account discriminator to the type describing its layout.
+type AccountDef struct {
+	Name          string        `json:"name"`
+	Discriminator Discriminator `json:"discriminator"`
+}
+
+type TypeDef struct {
+	Name string `json:"name"`
+	Type struct {
+		Kind     string    `json:"kind"` // "struct" or "enum"
+		Fields   []Field   `json:"fields"`
+		Variants []Variant `json:"variants"`
+	} `json:"type"`
+}
+
+type Variant struct {
+	Name   string  `json:"name"`
+	Fields []Field `json:"fields"`
+}
+
+type Field struct {
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+}
+
+type ErrorDef struct {
+	Code int    `json:"code"`
+	Name string `json:"name"`
+	Msg  string `json:"msg"`
+}
+
+// Parse decodes an IDL and checks that every defined type it references exists.
+func Parse(data []byte) (*IDL, error) {
+	var idl IDL
+	if err := json.Unmarshal(data, &idl); err != nil {
+		return nil, fmt.Errorf("invalid IDL: %w", err)
+	}
+
+	idl.types = make(map[string]*TypeDef, len(idl.Types))
+	for _, t := range idl.Types {
+		idl.types[t.Name] = t
+	}
+	for _, t := range idl.Types {
+		if t.Type.Kind != "struct" && t.Type.Kind != "enum" {
+			return nil, fmt.Errorf("type %s: unsupported kind %q", t.Name, t.Type.Kind)
+		}
+		for _, f := range t.Type.Fields {
+			if err := idl.checkType(f.Type); err != nil {
+				return nil, fmt.Errorf("type %s field %s: %w", t.Name, f.Name, err)
+			}
+		}
+		for _, v := range t.Type.Variants {
+			if len(v.Fields) > 0 {
+				return nil, fmt.Errorf("type %s: enum variant %s with fields is not supported", t.Name, v.Name)
+			}
+		}
+	}
+	for _, acc := range idl.Accounts {
+		if _, ok := idl.types[acc.Name]; !ok {
+			return nil, fmt.Errorf("account %s has no type definition", acc.Name)
+		}
+	}
+	for _, ix := range idl.Instructions {
+		ix.idl = &idl
+		for _, arg := range ix.Args {
+			if err := idl.checkType(arg.Type); err != nil {
+				return nil, fmt.Errorf("instruction %s arg %s: %w", ix.Name, arg.Name, err)
+			}
+		}
+	}
+	return &idl, nil
+}
+
+func (idl *IDL) checkType(t Type) error {
+	switch {
+	case t.Defined != "":
+		if _, ok := idl.types[t.Defined]; !ok {
+			return fmt.Errorf("undefined type %q", t.Defined)
+		}
+		return nil
+	case t.Elem != nil:
+		return idl.checkType(*t.Elem)
+	default:
+		if _, ok := primitiveSizes[t.Primitive]; !ok {
+			return fmt.Errorf("unsupported type %q", t.Primitive)
+		}
+		return nil
+	}
+}
+
+// Instruction looks up an instruction by its snake_case name.
+func (idl *IDL) Instruction(name string) (*Instruction, error) {
+	for _, ix := range idl.Instructions {
+		if ix.Name == name {
+			return ix, nil
+		}
+	}
+	return nil, fmt.Errorf("IDL %s has no instruction %q", idl.Metadata.Name, name)
+}
+
+// MustInstruction is Instruction for names the caller knows are in the IDL.
+func (idl *IDL) MustInstruction(name string) *Instruction {
+	ix, err := idl.Instruction(name)
+	if err != nil {
+		panic(err)
+	}
+	return ix
+}
+
+// Account looks up an account by its type name, e.g. "UserBalance".
+func (idl *IDL) Account(name string) (*AccountDef, error) {
+	for _, acc := range idl.Accounts {
+		if acc.Name == name {
+			return acc, nil
+		}
+	}
+	return nil, fmt.Errorf("IDL %s has no account %q", idl.Metadata.Name, name)
+}
+
+// MustAccount is Account for names the caller knows are in the IDL.
+func (idl *IDL) MustAccount(name string) *AccountDef {
+	acc, err := idl.Account(name)
+	if err != nil {
+		panic(err)
+	}
+	return acc
+}
+
+// Error returns the program error with the given custom error code.
+func (idl *IDL) Error(code int) (ErrorDef, bool) {
+	for _, e := range idl.Errors {
+		if e.Code == code {
+			return e, true
+		}
+	}
+	return ErrorDef{}, false
+}
+
+// DecodeAccount identifies account data by its discriminator and decodes it.
+func (idl *IDL) DecodeAccount(data []byte) (string, map[string]interface{}, error) {
+	for _, acc := range idl.Accounts {
+		if acc.Discriminator.Matches(data) {
+			fields, err := idl.decodeFields(data[8:], idl.types[acc.Name].Type.Fields)
+			if err != nil {
+				return "", nil, fmt.Errorf("decoding %s: %w", acc.Name, err)
+			}
+			return acc.Name, fields, nil
+		}
+	}
+	return "", nil, fmt.Errorf("data matches no account of %s", idl.Metadata.Name)
+}
+
+// DecodeAccountInto decodes account data that must be of type name into v,
+// a pointer to a struct with `anchor:"field_name"` tags or to a map.
+func (idl *IDL) DecodeAccountInto(name string, data []byte, v interface{}) error {
+	acc, err := idl.Account(name)
+	if err != nil {
+		return err
+	}
+	if !acc.Discriminator.Matches(data) {
+		return fmt.Errorf("data is not a %s account", name)
+	}
+	fields, err := idl.decodeFields(data[8:], idl.types[name].Type.Fields)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return assign(fields, v)
+}
+
+// EncodeAccount returns the data of a name account holding fields, the
+// discriminator first. Every field of the type must be given.
+func (idl *IDL) EncodeAccount(name string, fields map[string]interface{}) ([]byte, error) {
+	acc, err := idl.Account(name)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(append([]byte(nil), acc.Discriminator[:]...))
+	if err := idl.encodeValue(buf, Type{Defined: name}, fields); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeInstruction identifies instruction data by its discriminator and
+// decodes its arguments.
+func (idl *IDL) DecodeInstruction(data []byte) (*Instruction, map[string]interface{}, error) {
+	for _, ix := range idl.Instructions {
+		if ix.Discriminator.Matches(data) {
+			args, err := idl.decodeFields(data[8:], ix.Args)
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding %s: %w", ix.Name, err)
+			}
+			return ix, args, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("data matches no instruction of %s", idl.Metadata.Name)
+}
+
+// Matches reports whether data is an encoded call of ix.
+func (ix *Instruction) Matches(data []byte) bool {
+	return ix.Discriminator.Matches(data)
+}
+
+// AccountIndex returns the position of the named account, or -1.
+func (ix *Instruction) AccountIndex(name string) int {
+	for i, acc := range ix.Accounts {
+		if acc.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// DecodeArgs decodes the arguments of ix from instruction data (discriminator
+// included) into v, a pointer to a struct with `anchor` tags or to a map.
+func (ix *Instruction) DecodeArgs(data []byte, v interface{}) error {
+	if !ix.Matches(data) {
+		return fmt.Errorf("data is not a %s instruction", ix.Name)
+	}
+	args, err := ix.idl.decodeFields(data[8:], ix.Args)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", ix.Name, err)
+	}
+	return assign(args, v)
+}
+
+// EncodeData returns the instruction data for args: the discriminator followed
+// by every argument in IDL order. Missing or extra arguments are an error.
+func (ix *Instruction) EncodeData(args map[string]interface{}) ([]byte, error) {
+	if len(args) != len(ix.Args) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", ix.Name, len(ix.Args), len(args))
+	}
+
+	buf := bytes.NewBuffer(append([]byte(nil), ix.Discriminator[:]...))
+	for _, arg := range ix.Args {
+		v, ok := args[arg.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing argument %s", ix.Name, arg.Name)
+		}
+		if err := ix.idl.encodeValue(buf, arg.Type, v); err != nil {
+			return nil, fmt.Errorf("%s: argument %s: %w", ix.Name, arg.Name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Build encodes a call of ix. accounts maps account names to addresses;
+// accounts with a fixed address in the IDL may be left out. Writable and
+// signer flags come from the IDL.
+func (ix *Instruction) Build(programID solana.PublicKey, accounts map[string]solana.PublicKey, args map[string]interface{}) (solana.Instruction, error) {
+	data, err := ix.EncodeData(args)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range accounts {
+		if ix.AccountIndex(name) < 0 {
+			return nil, fmt.Errorf("%s: takes no account %s", ix.Name, name)
+		}
+	}
+
+	metas := make(solana.AccountMetaSlice, 0, len(ix.Accounts))
+	for _, acc := range ix.Accounts {
+		key, ok := accounts[acc.Name]
+		if !ok {
+			if acc.Address == "" {
+				return nil, fmt.Errorf("%s: missing account %s", ix.Name, acc.Name)
+			}
+			if key, err = solana.PublicKeyFromBase58(acc.Address); err != nil {
+				return nil, fmt.Errorf("%s: account %s: %w", ix.Name, acc.Name, err)
+			}
+		}
+		metas = append(metas, &solana.AccountMeta{PublicKey: key, IsWritable: acc.Writable, IsSigner: acc.Signer})
+	}
+
+	return solana.NewInstruction(programID, metas, data), nil
+}
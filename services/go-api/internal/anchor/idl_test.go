@@ -0,0 +1,127 @@
+package anchor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestEmbeddedIDLMatchesFrontend(t *testing.T) {
+	frontend, err := os.ReadFile(filepath.Join("..", "..", "..", "..", "frontend", "src", "idl", "casino.json"))
+	if os.IsNotExist(err) {
+		t.Skip("frontend IDL not available")
+	}
+	if err != nil {
+		t.Fatalf("Reading frontend IDL failed: %v", err)
+	}
+	if !bytes.Equal(frontend, casinoIDL) {
+		t.Fatalf("internal/anchor/idl/casino.json differs from frontend/src/idl/casino.json; copy the new IDL over")
+	}
+}
+
+func TestCasinoDiscriminators(t *testing.T) {
+	idl := Casino()
+
+	for _, ix := range idl.Instructions {
+		hash := sha256.Sum256([]byte("global:" + ix.Name))
+		if !bytes.Equal(ix.Discriminator[:], hash[:8]) {
+			t.Errorf("Instruction %s: discriminator %v, want %v", ix.Name, ix.Discriminator, hash[:8])
+		}
+	}
+	for _, acc := range idl.Accounts {
+		hash := sha256.Sum256([]byte("account:" + acc.Name))
+		if !bytes.Equal(acc.Discriminator[:], hash[:8]) {
+			t.Errorf("Account %s: discriminator %v, want %v", acc.Name, acc.Discriminator, hash[:8])
+		}
+	}
+}
+
+func TestWithdrawRoundTrip(t *testing.T) {
+	ix := Casino().MustInstruction("withdraw")
+
+	signature := bytes.Repeat([]byte{7}, 64)
+	data, err := ix.EncodeData(map[string]interface{}{
+		"amount":      uint64(1_500_000_000),
+		"nonce":       int64(3),
+		"expires_at":  int64(-1),
+		"signature":   signature,
+		"recovery_id": 1,
+	})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	// discriminator, amount, nonce, expires_at, signature, recovery_id
+	want := append([]byte(nil), ix.Discriminator[:]...)
+	want = binary.LittleEndian.AppendUint64(want, 1_500_000_000)
+	want = binary.LittleEndian.AppendUint64(want, 3)
+	want = binary.LittleEndian.AppendUint64(want, ^uint64(0))
+	want = append(want, signature...)
+	want = append(want, 1)
+	if !bytes.Equal(data, want) {
+		t.Fatalf("Encoded %x, want %x", data, want)
+	}
+
+	var args struct {
+		Amount     uint64   `anchor:"amount"`
+		Nonce      uint64   `anchor:"nonce"`
+		ExpiresAt  int64    `anchor:"expires_at"`
+		Signature  [64]byte `anchor:"signature"`
+		RecoveryID uint8    `anchor:"recovery_id"`
+	}
+	if err := ix.DecodeArgs(data, &args); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if args.Amount != 1_500_000_000 || args.Nonce != 3 || args.ExpiresAt != -1 || args.RecoveryID != 1 ||
+		!bytes.Equal(args.Signature[:], signature) {
+		t.Errorf("Unexpected args: %+v", args)
+	}
+
+	if _, err := ix.EncodeData(map[string]interface{}{
+		"amount": uint64(1), "nonce": uint64(1), "expires_at": int64(0), "signature": signature, "recovery_id": 256,
+	}); err == nil {
+		t.Errorf("Expected recovery_id 256 to overflow u8")
+	}
+	if err := ix.DecodeArgs(data[:20], &args); err == nil {
+		t.Errorf("Expected truncated data to fail")
+	}
+}
+
+func TestDecodeUserBalance(t *testing.T) {
+	idl := Casino()
+	user := solana.NewWallet().PublicKey()
+
+	data := append([]byte(nil), idl.MustAccount("UserBalance").Discriminator[:]...)
+	data = append(data, user.Bytes()...)
+	data = binary.LittleEndian.AppendUint64(data, 42)
+	data = binary.LittleEndian.AppendUint64(data, 9)
+	data = append(data, 254)
+
+	if size, ok := idl.AccountSize("UserBalance"); !ok || size != len(data) {
+		t.Fatalf("AccountSize = %d, %v; want %d", size, ok, len(data))
+	}
+
+	name, fields, err := idl.DecodeAccount(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if name != "UserBalance" || !fields["user"].(solana.PublicKey).Equals(user) ||
+		fields["amount"] != uint64(42) || fields["last_withdrawal_nonce"] != uint64(9) || fields["bump"] != uint8(254) {
+		t.Errorf("Unexpected decode: %s %+v", name, fields)
+	}
+
+	var wrong struct {
+		Amount uint64 `anchor:"balance"`
+	}
+	if err := idl.DecodeAccountInto("UserBalance", data, &wrong); err == nil {
+		t.Errorf("Expected a tag the IDL does not know to fail")
+	}
+	if err := idl.DecodeAccountInto("CasinoVault", data, &wrong); err == nil {
+		t.Errorf("Expected a UserBalance not to decode as CasinoVault")
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/risk"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+)
+
+type AdminHandler struct {
+	authorityService      *service.AuthorityService
+	bankroll              *risk.Manager
+	reconciliationService *service.ReconciliationService
+}
+
+func NewAdminHandler(authorityService *service.AuthorityService, bankroll *risk.Manager, reconciliationService *service.ReconciliationService) *AdminHandler {
+	return &AdminHandler{
+		authorityService:      authorityService,
+		bankroll:              bankroll,
+		reconciliationService: reconciliationService,
+	}
+}
+
+// RequireAdmin rejects requests that do not carry "Authorization: Bearer <token>"
+// with the configured admin token.
+func RequireAdmin(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(adminToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Admin authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetSigningAuthority GET /admin/signing-authority
+func (h *AdminHandler) GetSigningAuthority(c *gin.Context) {
+	status, err := h.authorityService.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Data: SigningAuthorityResponse{
+			Authority:            status.Authority,
+			OnChainAuthority:     status.OnChainAuthority,
+			OperationalAuthority: status.OperationalAuthority,
+			InSync:               status.Authority == status.OnChainAuthority,
+			Paused:               status.Paused,
+			RotationPending:      status.RotationPending,
+		},
+	})
+}
+
+// RotateSigningAuthority POST /admin/signing-authority/rotate
+func (h *AdminHandler) RotateSigningAuthority(c *gin.Context) {
+	rotation, err := h.authorityService.Rotate(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, domain.ErrRotationUnsupported) {
+			c.JSON(http.StatusNotImplemented, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Data: RotationResponse{
+			OldAuthority: rotation.OldAuthority,
+			NewAuthority: rotation.NewAuthority,
+			TxSignature:  rotation.TxSignature,
+			Resigned:     rotation.Resigned,
+			Failed:       rotation.Failed,
+		},
+	})
+}
+
+// GetBankroll GET /admin/bankroll
+func (h *AdminHandler) GetBankroll(c *gin.Context) {
+	bankroll, err := h.bankroll.Bankroll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Data: BankrollResponse{
+			Liquidity:   bankroll.Liquidity.String(),
+			Liabilities: bankroll.Liabilities.String(),
+			Free:        bankroll.Free().String(),
+			Utilization: bankroll.Utilization().StringFixed(4),
+			FetchedAt:   bankroll.FetchedAt,
+		},
+	})
+}
+
+// GetReconciliation GET /admin/reconciliation
+func (h *AdminHandler) GetReconciliation(c *gin.Context) {
+	var query ReconciliationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid query"})
+		return
+	}
+
+	run, err := h.reconciliationService.Latest(c.Request.Context(), !query.All)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if run == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "No reconciliation has run yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Data: run})
+}
+
+// RunReconciliation POST /admin/reconciliation/run
+func (h *AdminHandler) RunReconciliation(c *gin.Context) {
+	run, err := h.reconciliationService.Run(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Data: run})
+}
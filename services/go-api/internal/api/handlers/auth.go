@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+)
+
+// walletContextKey holds the authenticated wallet address in the gin context.
+const walletContextKey = "wallet_address"
+
+type AuthHandler struct {
+	authService *service.AuthService
+}
+
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
+}
+
+// Challenge POST /auth/challenge
+func (h *AuthHandler) Challenge(c *gin.Context) {
+	var req ChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	challenge, err := h.authService.Challenge(c.Request.Context(), req.WalletAddress)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Data: ChallengeResponse{
+			Nonce:     challenge.Nonce,
+			Message:   challenge.Message,
+			ExpiresAt: challenge.ExpiresAt,
+		},
+	})
+}
+
+// Login POST /auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	token, expiresAt, err := h.authService.Login(c.Request.Context(), req.WalletAddress, req.Nonce, req.Signature)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnauthorized) {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired challenge signature"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Data: LoginResponse{Token: token, ExpiresAt: expiresAt},
+	})
+}
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer <token>"
+// header and stores the token's wallet in the context.
+func (h *AuthHandler) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+			return
+		}
+
+		wallet, err := h.authService.Authenticate(c.Request.Context(), strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, domain.ErrUnauthorized) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired session token"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		c.Set(walletContextKey, wallet)
+		c.Next()
+	}
+}
+
+// authenticatedWallet returns the wallet set by RequireAuth.
+func authenticatedWallet(c *gin.Context) string {
+	return c.GetString(walletContextKey)
+}
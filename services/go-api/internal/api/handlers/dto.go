@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"time"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/shopspring/decimal"
 )
 
@@ -12,9 +15,29 @@ type SuccessResponse struct {
 	Data interface{} `json:"data"`
 }
 
+type ChallengeRequest struct {
+	WalletAddress string `json:"wallet_address" binding:"required"`
+}
+
+type ChallengeResponse struct {
+	Nonce     string    `json:"nonce"`
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type LoginRequest struct {
+	WalletAddress string `json:"wallet_address" binding:"required"`
+	Nonce         string `json:"nonce" binding:"required"`
+	Signature     string `json:"signature" binding:"required"` // hex encoded ed25519 signature of the challenge message
+}
+
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type WithdrawRequest struct {
-	WalletAddress string          `json:"wallet_address" binding:"required"`
-	Amount        decimal.Decimal `json:"amount" binding:"required"`
+	Amount decimal.Decimal `json:"amount" binding:"required"`
 }
 
 type WithdrawResponse struct {
@@ -22,42 +45,78 @@ type WithdrawResponse struct {
 	RecoveryID int    `json:"recovery_id"`
 	Nonce      int64  `json:"nonce"`
 	Amount     uint64 `json:"amount_lamports"`
+	ExpiresAt  int64  `json:"expires_at"` // unix seconds, signed into the authorization
 }
 
-type WalletOnlyRequest struct {
-	WalletAddress string `json:"wallet_address" binding:"required"`
+type WithdrawalsQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=signed confirmed refunded expired"`
 }
 
 type BalanceResponse struct {
 	Balance string `json:"balance_sol"`
 }
 
-type InitSessionRequest struct {
-	WalletAddress string `json:"wallet_address" binding:"required"`
-}
-
 type InitSessionResponse struct {
 	SessionID          string `json:"session_id"`
 	NextServerSeedHash string `json:"next_server_seed_hash"`
 }
 
 type SpinRequest struct {
-	WalletAddress string          `json:"wallet_address" binding:"required"`
-	BetAmount     decimal.Decimal `json:"bet_amount" binding:"required"`
-	ClientSeed    string          `json:"client_seed" binding:"required"`
+	GameID     string          `json:"game_id"`
+	BetAmount  decimal.Decimal `json:"bet_amount" binding:"required"`
+	ClientSeed string          `json:"client_seed" binding:"required"`
 }
 
 type SpinResponse struct {
-	SpinID     string  `json:"spin_id"`
-	SpinNonce  int64   `json:"spin_nonce"`
-	Outcome    [][]int `json:"outcome"`
-	IsWin      bool    `json:"is_win"`
-	Payout     string  `json:"payout_sol"`
-	ServerSeed string  `json:"server_seed"`
-	NextHash   string  `json:"next_server_seed_hash"`
+	SpinID     string           `json:"spin_id"`
+	SpinNonce  int64            `json:"spin_nonce"`
+	GameID     string           `json:"game_id"`
+	Version    int              `json:"game_version"`
+	Outcome    [][]int          `json:"outcome"`
+	IsWin      bool             `json:"is_win"`
+	Wins       []domain.SpinWin `json:"wins"`
+	Payout     string           `json:"payout_sol"`
+	ServerSeed string           `json:"server_seed"`
+	NextHash   string           `json:"next_server_seed_hash"`
+}
+
+type GameLimitResponse struct {
+	GameID        string  `json:"game_id"`
+	Version       int     `json:"version"`
+	MaxMultiplier float64 `json:"max_multiplier"`
+	MaxBet        string  `json:"max_bet"`
 }
 
 type SyncRequest struct {
-	WalletAddress string `json:"wallet_address" binding:"required"`
-	TxSignature   string `json:"tx_signature" binding:"required"`
+	TxSignature string `json:"tx_signature" binding:"required"`
+}
+
+type SigningAuthorityResponse struct {
+	Authority            string `json:"authority"`
+	OnChainAuthority     string `json:"on_chain_authority"`
+	OperationalAuthority string `json:"operational_authority"`
+	InSync               bool   `json:"in_sync"`
+	Paused               bool   `json:"paused"`
+	RotationPending      bool   `json:"rotation_pending"`
+}
+
+type RotationResponse struct {
+	OldAuthority string `json:"old_authority"`
+	NewAuthority string `json:"new_authority"`
+	TxSignature  string `json:"tx_signature,omitempty"`
+	Resigned     int    `json:"resigned_withdrawals"`
+	Failed       int    `json:"failed_withdrawals"`
+}
+
+type BankrollResponse struct {
+	Liquidity   string    `json:"liquidity"`
+	Liabilities string    `json:"liabilities"`
+	Free        string    `json:"free"`
+	Utilization string    `json:"utilization"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+type ReconciliationQuery struct {
+	// All includes wallets without discrepancies.
+	All bool `form:"all"`
 }
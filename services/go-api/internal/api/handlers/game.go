@@ -1,30 +1,27 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/verify"
 )
 
 type GameHandler struct {
 	gameService *service.GameService
+	verifier    *verify.Verifier
 }
 
-func NewGameHandler(gameService *service.GameService) *GameHandler {
-	return &GameHandler{gameService: gameService}
+func NewGameHandler(gameService *service.GameService, verifier *verify.Verifier) *GameHandler {
+	return &GameHandler{gameService: gameService, verifier: verifier}
 }
 
 // InitSession POST /game/session
 func (h *GameHandler) InitSession(c *gin.Context) {
-	var req InitSessionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
-		return
-	}
-
-	session, err := h.gameService.InitiateSession(c.Request.Context(), req.WalletAddress)
+	session, err := h.gameService.InitiateSession(c.Request.Context(), authenticatedWallet(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
@@ -46,7 +43,19 @@ func (h *GameHandler) Spin(c *gin.Context) {
 		return
 	}
 
-	spin, nextHash, err := h.gameService.ExecuteSpin(c.Request.Context(), req.WalletAddress, req.BetAmount, req.ClientSeed)
+	spin, nextHash, err := h.gameService.ExecuteSpin(c.Request.Context(), authenticatedWallet(c), req.GameID, req.BetAmount, req.ClientSeed)
+	if err != nil {
+		// A spin is played in a single transaction, so a failed one left nothing behind.
+		retrySafe(c)
+	}
+	if errors.Is(err, domain.ErrInvalidBet) || errors.Is(err, domain.ErrBetAboveMax) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, domain.ErrBankrollUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		switch err {
 		case domain.ErrInsufficientFunds:
@@ -60,18 +69,23 @@ func (h *GameHandler) Spin(c *gin.Context) {
 	}
 
 	rawReels := spin.Outcome.Reels
-	displayMatrix := make([][]int, 3)
+	rows := spin.Outcome.Rows
+	cols := len(rawReels) / rows
+	displayMatrix := make([][]int, rows)
 
-	displayMatrix[0] = rawReels[0:3]
-	displayMatrix[1] = rawReels[3:6]
-	displayMatrix[2] = rawReels[6:9]
+	for row := 0; row < rows; row++ {
+		displayMatrix[row] = rawReels[row*cols : (row+1)*cols]
+	}
 
 	c.JSON(http.StatusOK, SuccessResponse{
 		Data: SpinResponse{
 			SpinID:     spin.SpinID.String(),
 			SpinNonce:  spin.SpinNonce,
+			GameID:     spin.GameID,
+			Version:    spin.GameVersion,
 			Outcome:    displayMatrix,
 			IsWin:      spin.Outcome.IsWin,
+			Wins:       spin.Outcome.Wins,
 			Payout:     spin.PayoutAmount.String(),
 			ServerSeed: spin.ServerSeed,
 			NextHash:   nextHash,
@@ -79,13 +93,34 @@ func (h *GameHandler) Spin(c *gin.Context) {
 	})
 }
 
-func (h *GameHandler) GetHistory(c *gin.Context) {
-	wallet := c.Query("wallet_address")
-	if wallet == "" {
-		c.JSON(400, ErrorResponse{Error: "wallet required"})
+// GetLimits GET /game/limits
+func (h *GameHandler) GetLimits(c *gin.Context) {
+	limits, err := h.gameService.Limits(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, domain.ErrBankrollUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	resp := make([]GameLimitResponse, 0, len(limits))
+	for _, l := range limits {
+		resp = append(resp, GameLimitResponse{
+			GameID:        l.GameID,
+			Version:       l.Version,
+			MaxMultiplier: l.MaxMultiplier,
+			MaxBet:        l.MaxBet.String(),
+		})
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Data: resp})
+}
+
+// GetHistory GET /game/history
+func (h *GameHandler) GetHistory(c *gin.Context) {
+	wallet := authenticatedWallet(c)
+
 	spins, err := h.gameService.GetUserHistory(c.Request.Context(), wallet)
 	if err != nil {
 		c.JSON(500, ErrorResponse{Error: err.Error()})
@@ -104,3 +139,21 @@ func (h *GameHandler) GetProof(c *gin.Context) {
 	}
 	c.JSON(200, SuccessResponse{Data: data})
 }
+
+// VerifySpin POST /game/verify
+// Accepts the payload returned by /game/proof/:spin_id and replays it.
+func (h *GameHandler) VerifySpin(c *gin.Context) {
+	var req verify.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	report, err := h.verifier.Verify(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Data: report})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+)
+
+const (
+	idempotencyHeader     = "Idempotency-Key"
+	maxIdempotencyKeyLen  = 128
+	idempotentReplayedHdr = "Idempotent-Replayed"
+)
+
+// retrySafeContextKey marks a failed request that wrote nothing.
+const retrySafeContextKey = "idempotency_retry_safe"
+
+// retrySafe tells Idempotent that the request failed before changing any
+// state, so its key is released instead of storing the failure for replay.
+func retrySafe(c *gin.Context) {
+	c.Set(retrySafeContextKey, true)
+}
+
+// responseRecorder keeps a copy of everything the handler writes.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent replays the stored response when a request is retried with the
+// same Idempotency-Key header, and rejects a key reused for a different
+// request with 409. Failures are replayed too, unless the handler marked them
+// with retrySafe. Requests without the header run normally. Must be mounted
+// after RequireAuth, since keys are scoped to the authenticated wallet.
+func Idempotent(idempotencyService *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Error: "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		wallet := authenticatedWallet(c)
+		fingerprint := append([]byte(c.Request.Method+" "+c.FullPath()+"\n"), body...)
+
+		replay, err := idempotencyService.Begin(c.Request.Context(), wallet, key, fingerprint)
+		if err != nil {
+			if errors.Is(err, domain.ErrIdempotencyReuse) || errors.Is(err, domain.ErrRequestInFlight) {
+				c.AbortWithStatusJSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if replay != nil {
+			c.Header(idempotentReplayedHdr, "true")
+			c.Data(*replay.ResponseStatus, "application/json; charset=utf-8", replay.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Deferred so that a panicking handler, answered with a 500 by the
+		// Recovery middleware, is settled as well. A panic may come after
+		// writes, so its 500 is stored like any other response.
+		completed := false
+		defer func() {
+			// The outcome must be stored even if the client has already gone away.
+			ctx := context.WithoutCancel(c.Request.Context())
+			if completed && c.GetBool(retrySafeContextKey) {
+				if err := idempotencyService.Release(ctx, wallet, key); err != nil {
+					log.Printf("⚠️  Failed to release idempotency key %s: %v", key, err)
+				}
+				return
+			}
+			status, body := recorder.Status(), recorder.body.Bytes()
+			if !completed {
+				status = http.StatusInternalServerError
+				body, _ = json.Marshal(ErrorResponse{Error: "Internal server error"})
+			}
+			if err := idempotencyService.Finish(ctx, wallet, key, status, body); err != nil {
+				log.Printf("⚠️  Failed to store idempotent response for key %s: %v", key, err)
+			}
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+)
+
+// idempotencyRepo keeps idempotency keys in memory.
+type idempotencyRepo struct {
+	repository.Repository
+
+	mu   sync.Mutex
+	keys map[string]*domain.IdempotencyRecord
+}
+
+func (r *idempotencyRepo) ReserveIdempotencyKey(_ context.Context, walletAddress string, key string, requestHash string) (*domain.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rec, ok := r.keys[walletAddress+"/"+key]; ok {
+		return rec, false, nil
+	}
+	r.keys[walletAddress+"/"+key] = &domain.IdempotencyRecord{WalletAddress: walletAddress, Key: key, RequestHash: requestHash}
+	return nil, true, nil
+}
+
+func (r *idempotencyRepo) CompleteIdempotencyKey(_ context.Context, walletAddress string, key string, status int, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.keys[walletAddress+"/"+key]
+	rec.ResponseStatus, rec.ResponseBody = &status, body
+	return nil
+}
+
+func (r *idempotencyRepo) ReleaseIdempotencyKey(_ context.Context, walletAddress string, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, walletAddress+"/"+key)
+	return nil
+}
+
+func TestIdempotentSettlesFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &idempotencyRepo{keys: map[string]*domain.IdempotencyRecord{}}
+
+	calls := 0
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/panic", Idempotent(service.NewIdempotencyService(repo)), func(c *gin.Context) {
+		calls++
+		panic("boom")
+	})
+	router.POST("/unavailable", Idempotent(service.NewIdempotencyService(repo)), func(c *gin.Context) {
+		calls++
+		if calls == 2 {
+			retrySafe(c)
+			c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "paused"})
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: calls})
+	})
+
+	send := func(path string, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"amount":"1"}`))
+		req.Header.Set(idempotencyHeader, key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A panic may come after writes: its 500 is replayed, not run again.
+	if rec := send("/panic", "key-1"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the panic to answer 500, got %d", rec.Code)
+	}
+	if rec := send("/panic", "key-1"); rec.Code != http.StatusInternalServerError || rec.Header().Get(idempotentReplayedHdr) != "true" {
+		t.Fatalf("Expected the 500 to be replayed, got %d", rec.Code)
+	}
+
+	// A failure marked retry-safe releases the key.
+	if rec := send("/unavailable", "key-2"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+	if rec := send("/unavailable", "key-2"); rec.Code != http.StatusOK || rec.Header().Get(idempotentReplayedHdr) != "" {
+		t.Fatalf("Expected the retry to run the handler again, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send("/unavailable", "key-2"); rec.Code != http.StatusOK || rec.Header().Get(idempotentReplayedHdr) != "true" {
+		t.Errorf("Expected the stored response to be replayed, got %d", rec.Code)
+	}
+	if calls != 3 {
+		t.Errorf("Expected the handlers to run 3 times, ran %d times", calls)
+	}
+}
@@ -71,7 +71,6 @@ func McElieceEncryptHandler(c *gin.Context) {
 
 	fmt.Printf("\n--- McEliece Encrypt ---\n")
 	fmt.Printf("[HANDLER] Received PlainText: %q\n", req.PlainText)
-	fmt.Printf("[HANDLER] Received Public Key N (start): %.30s...\n", req.PublicKey)
 
 	pubKey := &mceliece.PublicKey{
 		G:      req.PublicKey.G,
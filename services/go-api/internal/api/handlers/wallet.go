@@ -1,12 +1,14 @@
 package handlers
 
 import (
-	"encoding/hex"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
 	"github.com/shopspring/decimal"
 )
 
@@ -18,15 +20,9 @@ func NewWalletHandler(walletService *service.WalletService) *WalletHandler {
 	return &WalletHandler{walletService: walletService}
 }
 
-// GetBalance GET /wallet/balance/:address
+// GetBalance GET /wallet/balance
 func (h *WalletHandler) GetBalance(c *gin.Context) {
-	address := c.Param("address")
-	if address == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Address required"})
-		return
-	}
-
-	balance, err := h.walletService.GetBalance(c.Request.Context(), address)
+	balance, err := h.walletService.GetBalance(c.Request.Context(), authenticatedWallet(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
@@ -45,11 +41,22 @@ func (h *WalletHandler) Withdraw(c *gin.Context) {
 		return
 	}
 
-	sig, recid, nonce, err := h.walletService.AuthorizeWithdrawal(c.Request.Context(), req.WalletAddress, req.Amount)
+	withdrawal, err := h.walletService.AuthorizeWithdrawal(c.Request.Context(), authenticatedWallet(c), req.Amount)
+	if err != nil && withdrawal == nil {
+		retrySafe(c)
+	}
+	if errors.Is(err, signer.ErrPaused) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		switch err {
 		case domain.ErrInsufficientFunds:
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Insufficient funds"})
+		case domain.ErrInvalidAmount:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case domain.ErrWithdrawalNonceTaken:
+			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		}
@@ -60,14 +67,33 @@ func (h *WalletHandler) Withdraw(c *gin.Context) {
 
 	c.JSON(http.StatusOK, SuccessResponse{
 		Data: WithdrawResponse{
-			Signature:  hex.EncodeToString(sig),
-			RecoveryID: recid,
-			Nonce:      nonce,
+			Signature:  withdrawal.Signature,
+			RecoveryID: *withdrawal.RecoveryID,
+			Nonce:      withdrawal.Nonce,
 			Amount:     lamports,
+			ExpiresAt:  withdrawal.ExpiresAt.Unix(),
 		},
 	})
 }
 
+// ListWithdrawals GET /wallet/withdrawals
+func (h *WalletHandler) ListWithdrawals(c *gin.Context) {
+	var query WithdrawalsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid status filter"})
+		return
+	}
+
+	withdrawals, err := h.walletService.ListWithdrawals(c.Request.Context(), authenticatedWallet(c), query.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Data: withdrawals})
+}
+
+// SyncDeposit POST /wallet/sync
 func (h *WalletHandler) SyncDeposit(c *gin.Context) {
 	var req SyncRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -75,8 +101,10 @@ func (h *WalletHandler) SyncDeposit(c *gin.Context) {
 		return
 	}
 
-	err := h.walletService.SyncDeposit(c.Request.Context(), req.WalletAddress, req.TxSignature)
+	err := h.walletService.SyncDeposit(c.Request.Context(), authenticatedWallet(c), req.TxSignature)
 	if err != nil {
+		// Deposits are credited once per instruction, so syncing again is safe.
+		retrySafe(c)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -84,30 +112,34 @@ func (h *WalletHandler) SyncDeposit(c *gin.Context) {
 	c.JSON(http.StatusOK, SuccessResponse{Data: "Deposit synced successfully"})
 }
 
+// CompleteWithdrawal POST /wallet/complete-withdraw
 func (h *WalletHandler) CompleteWithdrawal(c *gin.Context) {
 	var req struct {
-		WalletAddress string `json:"wallet_address" binding:"required"`
+		TxSignature string `json:"tx_signature"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
 		return
 	}
-	err := h.walletService.CompleteWithdrawal(c.Request.Context(), req.WalletAddress)
+	err := h.walletService.CompleteWithdrawal(c.Request.Context(), authenticatedWallet(c), req.TxSignature)
 	if err != nil {
+		if errors.Is(err, domain.ErrWithdrawalNotExecuted) {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, SuccessResponse{Data: "Withdrawal completed"})
 }
 
+// RequestRefund POST /wallet/refund
 func (h *WalletHandler) RequestRefund(c *gin.Context) {
-	var req WalletOnlyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	err := h.walletService.AttemptRefund(c.Request.Context(), authenticatedWallet(c))
+	if errors.Is(err, domain.ErrWithdrawalNotExpired) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
 		return
 	}
-
-	err := h.walletService.AttemptRefund(c.Request.Context(), req.WalletAddress)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
@@ -3,24 +3,39 @@ package api
 import (
 	"log"
 
-	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/api/handlers"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository/postgres"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/risk"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/verify"
 )
 
-func RegisterRoutes(router *gin.Engine, dbPool *pgxpool.Pool, serverPrivKey string, rpcClient *rpc.Client, vaultAddress string) {
+func RegisterRoutes(router *gin.Engine, dbPool *pgxpool.Pool, games *game.Registry, withdrawalSigner signer.Signer, rpcClient solana_parser.ChainClient, tracker *txconfirm.Tracker, vaultAddress string, programID string, authDomain string, bankroll *risk.Manager, authoritySvc *service.AuthorityService, reconciliationSvc *service.ReconciliationService, adminToken string) {
 	repo := postgres.NewPostgresRepo(dbPool)
 
-	gameSvc := service.NewGameService(repo)
-	walletSvc, err := service.NewWalletService(repo, serverPrivKey, rpcClient, vaultAddress)
+	gameSvc := service.NewGameService(repo, games, bankroll)
+	walletSvc, err := service.NewWalletService(repo, withdrawalSigner, rpcClient, tracker, programID, vaultAddress)
 	if err != nil {
 		log.Fatalf("Failed to initialize WalletService: %v", err)
 	}
 
-	gameH := handlers.NewGameHandler(gameSvc)
+	verifier, err := verify.NewVerifier(games, rpcClient, programID)
+	if err != nil {
+		log.Fatalf("Failed to initialize Verifier: %v", err)
+	}
+
+	authSvc := service.NewAuthService(repo, authDomain)
+	idempotencySvc := service.NewIdempotencyService(repo)
+	idempotent := handlers.Idempotent(idempotencySvc)
+
+	authH := handlers.NewAuthHandler(authSvc)
+	gameH := handlers.NewGameHandler(gameSvc, verifier)
 	walletH := handlers.NewWalletHandler(walletSvc)
 
 	stegoH := handlers.NewStegoHandler()
@@ -29,23 +44,51 @@ func RegisterRoutes(router *gin.Engine, dbPool *pgxpool.Pool, serverPrivKey stri
 	{
 		v1 := apiGroup.Group("/v1")
 		{
+			authRoutes := v1.Group("/auth")
+			{
+				authRoutes.POST("/challenge", authH.Challenge)
+				authRoutes.POST("/login", authH.Login)
+			}
+
 			gameRoutes := v1.Group("/game")
 			{
-				gameRoutes.POST("/session", gameH.InitSession)
-				gameRoutes.POST("/spin", gameH.Spin)
-				gameRoutes.GET("/history", gameH.GetHistory)
+				// Proofs and verification are public so anyone can audit a spin.
 				gameRoutes.GET("/proof/:spin_id", gameH.GetProof)
+				gameRoutes.POST("/verify", gameH.VerifySpin)
+				gameRoutes.GET("/limits", gameH.GetLimits)
+
+				playerRoutes := gameRoutes.Group("", authH.RequireAuth())
+				playerRoutes.POST("/session", gameH.InitSession)
+				playerRoutes.POST("/spin", idempotent, gameH.Spin)
+				playerRoutes.GET("/history", gameH.GetHistory)
 			}
 
-			walletRoutes := v1.Group("/wallet")
+			walletRoutes := v1.Group("/wallet", authH.RequireAuth())
 			{
-				walletRoutes.GET("/balance/:address", walletH.GetBalance)
-				walletRoutes.POST("/withdraw", walletH.Withdraw)
-				walletRoutes.POST("/sync", walletH.SyncDeposit)
+				walletRoutes.GET("/balance", walletH.GetBalance)
+				walletRoutes.POST("/withdraw", idempotent, walletH.Withdraw)
+				walletRoutes.GET("/withdrawals", walletH.ListWithdrawals)
+				walletRoutes.POST("/sync", idempotent, walletH.SyncDeposit)
 				walletRoutes.POST("/refund", walletH.RequestRefund)
 				walletRoutes.POST("/complete-withdraw", walletH.CompleteWithdrawal)
 			}
 
+			if adminToken != "" {
+				adminH := handlers.NewAdminHandler(authoritySvc, bankroll, reconciliationSvc)
+				adminRoutes := v1.Group("/admin", handlers.RequireAdmin(adminToken))
+				{
+					adminRoutes.GET("/bankroll", adminH.GetBankroll)
+					adminRoutes.GET("/reconciliation", adminH.GetReconciliation)
+					adminRoutes.POST("/reconciliation/run", adminH.RunReconciliation)
+
+					// Rotation needs the operational wallet.
+					if authoritySvc != nil {
+						adminRoutes.GET("/signing-authority", adminH.GetSigningAuthority)
+						adminRoutes.POST("/signing-authority/rotate", adminH.RotateSigningAuthority)
+					}
+				}
+			}
+
 			cipher := v1.Group("/cipher")
 			{
 				stbGroup := cipher.Group("/stb")
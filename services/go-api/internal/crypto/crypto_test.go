@@ -12,8 +12,9 @@ func TestSignWithdrawal(t *testing.T) {
 	userAddress := "AMyC4nrskq9PERnZfFZv3KRhEm23VUpRV4VrggAjYiiU"
 	amount := uint64(1000000000)
 	nonce := uint64(1)
+	expiresAt := int64(1767225600)
 
-	sig, recid, err := SignWithdrawal(privKeyHex, userAddress, amount, nonce)
+	sig, recid, err := SignWithdrawal(privKeyHex, userAddress, amount, nonce, expiresAt)
 	if err != nil {
 		t.Fatalf("Signing failed: %v", err)
 	}
@@ -40,3 +41,60 @@ func TestMerkleTree(t *testing.T) {
 	}
 	fmt.Printf("Merkle Root: %s\n", root)
 }
+
+// mth is the recursive RFC 6962 definition, used as a reference.
+func mth(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return hashLeaf(leaves[0])
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	return hashNode(mth(leaves[:k]), mth(leaves[k:]))
+}
+
+func TestMerkleProofRFC6962(t *testing.T) {
+	for n := 1; n <= 13; n++ {
+		var leaves []string
+		var raw [][]byte
+		for i := 0; i < n; i++ {
+			h := HashStringSHA256(fmt.Sprintf("spin%d", i))
+			leaves = append(leaves, h)
+			b, _ := hex.DecodeString(h)
+			raw = append(raw, b)
+		}
+
+		root, err := ComputeMerkleRootRFC6962(leaves)
+		if err != nil {
+			t.Fatalf("Merkle failed: %v", err)
+		}
+		if want := hex.EncodeToString(mth(raw)); root != want {
+			t.Fatalf("n=%d: root %s differs from RFC 6962 reference %s", n, root, want)
+		}
+
+		for i := range leaves {
+			proof, err := GenerateMerkleProofRFC6962(leaves, i)
+			if err != nil {
+				t.Fatalf("Proof failed: %v", err)
+			}
+			ok, err := VerifyMerkleProof(leaves[i], proof, root)
+			if err != nil || !ok {
+				t.Errorf("n=%d index=%d: proof did not verify (%v)", n, i, err)
+			}
+			if n > 1 {
+				proof[0].Left = !proof[0].Left
+				if ok, _ := VerifyMerkleProof(leaves[i], proof, root); ok {
+					t.Errorf("n=%d index=%d: flipped direction still verified", n, i)
+				}
+			}
+		}
+	}
+
+	// An internal node (here the root itself) must not be accepted as a leaf.
+	leaves := []string{HashStringSHA256("a"), HashStringSHA256("b")}
+	root, _ := ComputeMerkleRootRFC6962(leaves)
+	if ok, _ := VerifyMerkleProof(root, nil, root); ok {
+		t.Errorf("Internal node accepted as a leaf")
+	}
+}
@@ -91,3 +91,34 @@ func GenerateMerkleProof(leafHashes []string, index int) ([]string, error) {
 
 	return proof, nil
 }
+
+// ComputeRootFromProof walks a proof produced by GenerateMerkleProof back up to
+// the root. The leaf index decides on which side each sibling is hashed.
+func ComputeRootFromProof(leafHash string, proof []string, index int) (string, error) {
+	if index < 0 {
+		return "", fmt.Errorf("index out of bounds")
+	}
+
+	current, err := hex.DecodeString(leafHash)
+	if err != nil {
+		return "", err
+	}
+
+	for _, siblingHex := range proof {
+		sibling, err := hex.DecodeString(siblingHex)
+		if err != nil {
+			return "", err
+		}
+
+		var combined []byte
+		if index%2 == 0 {
+			combined = append(append(combined, current...), sibling...)
+		} else {
+			combined = append(append(combined, sibling...), current...)
+		}
+		current = HashDataSHA256(combined)
+		index = index / 2
+	}
+
+	return hex.EncodeToString(current), nil
+}
@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// MerkleFormat identifies how a batch tree was built. It is stored on every
+// batch so that proofs for old batches keep verifying after format changes.
+type MerkleFormat int
+
+const (
+	// MerkleFormatLegacy: parent = sha256(left || right), odd nodes are paired
+	// with themselves, proofs carry no direction. See ComputeMerkleRoot.
+	MerkleFormatLegacy MerkleFormat = 1
+	// MerkleFormatRFC6962: leaf = sha256(0x00 || leaf_hash),
+	// parent = sha256(0x01 || left || right), an odd node is promoted to the
+	// next level unchanged. This is the RFC 6962 Merkle Tree Hash.
+	MerkleFormatRFC6962 MerkleFormat = 2
+	// MerkleFormatRFC6962V2 builds the same tree as MerkleFormatRFC6962 over
+	// v2 spin leaves, which also bind the game, its version and the server
+	// seed commitment, and hash the payout rounded to lamports.
+	MerkleFormatRFC6962V2 MerkleFormat = 3
+)
+
+// CurrentMerkleFormat is used for newly played spins and the batches they go in.
+const CurrentMerkleFormat = MerkleFormatRFC6962V2
+
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// MerkleProofStep is one sibling on the path from a leaf to the root.
+// Left is true when the sibling sits on the left of the running hash.
+type MerkleProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+func hashLeaf(leaf []byte) []byte {
+	return HashDataSHA256(append([]byte{leafPrefix}, leaf...))
+}
+
+func hashNode(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, nodePrefix)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	return HashDataSHA256(buf)
+}
+
+func decodeLeaves(leafHashes []string) ([][]byte, error) {
+	level := make([][]byte, 0, len(leafHashes))
+	for _, h := range leafHashes {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, err
+		}
+		level = append(level, hashLeaf(b))
+	}
+	return level, nil
+}
+
+func nextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 < len(level) {
+			next = append(next, hashNode(level[i], level[i+1]))
+		} else {
+			next = append(next, level[i])
+		}
+	}
+	return next
+}
+
+// MerkleTree keeps every level of an RFC 6962 tree so that proofs for all
+// leaves can be produced without rebuilding it. Levels[0] holds the leaf nodes.
+type MerkleTree struct {
+	Levels [][][]byte
+}
+
+// BuildMerkleTreeRFC6962 builds the tree over hex-encoded leaf hashes.
+func BuildMerkleTreeRFC6962(leafHashes []string) (*MerkleTree, error) {
+	if len(leafHashes) == 0 {
+		return nil, fmt.Errorf("cannot build a tree without leaves")
+	}
+
+	level, err := decodeLeaves(leafHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &MerkleTree{Levels: [][][]byte{level}}
+	for len(level) > 1 {
+		level = nextLevel(level)
+		tree.Levels = append(tree.Levels, level)
+	}
+	return tree, nil
+}
+
+// Root returns the hex-encoded root hash.
+func (t *MerkleTree) Root() string {
+	return hex.EncodeToString(t.Levels[len(t.Levels)-1][0])
+}
+
+// Proof returns the audit path for the leaf at index.
+func (t *MerkleTree) Proof(index int) ([]MerkleProofStep, error) {
+	if index >= len(t.Levels[0]) || index < 0 {
+		return nil, fmt.Errorf("index out of bounds")
+	}
+
+	proof := []MerkleProofStep{}
+	for _, level := range t.Levels[:len(t.Levels)-1] {
+		if index%2 == 1 {
+			proof = append(proof, MerkleProofStep{Hash: hex.EncodeToString(level[index-1]), Left: true})
+		} else if index+1 < len(level) {
+			proof = append(proof, MerkleProofStep{Hash: hex.EncodeToString(level[index+1]), Left: false})
+		}
+		index = index / 2
+	}
+	return proof, nil
+}
+
+// ComputeMerkleRootRFC6962 returns the hex-encoded RFC 6962 root of the given
+// hex-encoded leaf hashes.
+func ComputeMerkleRootRFC6962(leafHashes []string) (string, error) {
+	if len(leafHashes) == 0 {
+		return "", nil
+	}
+
+	tree, err := BuildMerkleTreeRFC6962(leafHashes)
+	if err != nil {
+		return "", err
+	}
+	return tree.Root(), nil
+}
+
+// GenerateMerkleProofRFC6962 returns the audit path for leafHashes[index].
+func GenerateMerkleProofRFC6962(leafHashes []string, index int) ([]MerkleProofStep, error) {
+	if index >= len(leafHashes) || index < 0 {
+		return nil, fmt.Errorf("index out of bounds")
+	}
+
+	tree, err := BuildMerkleTreeRFC6962(leafHashes)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Proof(index)
+}
+
+// ComputeRootFromProofRFC6962 walks an RFC 6962 proof from a leaf hash to the root.
+func ComputeRootFromProofRFC6962(leafHash string, proof []MerkleProofStep) (string, error) {
+	leaf, err := hex.DecodeString(leafHash)
+	if err != nil {
+		return "", err
+	}
+
+	current := hashLeaf(leaf)
+	for _, step := range proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return "", err
+		}
+		if len(sibling) != len(current) {
+			return "", fmt.Errorf("invalid sibling length %d", len(sibling))
+		}
+
+		if step.Left {
+			current = hashNode(sibling, current)
+		} else {
+			current = hashNode(current, sibling)
+		}
+	}
+
+	return hex.EncodeToString(current), nil
+}
+
+// VerifyMerkleProof reports whether proof links leafHash to rootHex in an
+// RFC 6962 tree.
+func VerifyMerkleProof(leafHash string, proof []MerkleProofStep, rootHex string) (bool, error) {
+	root, err := ComputeRootFromProofRFC6962(leafHash, proof)
+	if err != nil {
+		return false, err
+	}
+	return root == rootHex, nil
+}
+
+// ComputeMerkleRootFormat builds the root in the given tree format.
+func ComputeMerkleRootFormat(format MerkleFormat, leafHashes []string) (string, error) {
+	switch format {
+	case MerkleFormatLegacy:
+		return ComputeMerkleRoot(leafHashes)
+	case MerkleFormatRFC6962, MerkleFormatRFC6962V2:
+		return ComputeMerkleRootRFC6962(leafHashes)
+	default:
+		return "", fmt.Errorf("unknown merkle format %d", format)
+	}
+}
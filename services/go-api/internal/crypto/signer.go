@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"crypto/ecdsa"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -11,8 +12,9 @@ import (
 )
 
 // SignWithdrawal generates the signature required by the Smart Contract.
-// matches: keccak(user_pubkey + amount_le + nonce_le)
-func SignWithdrawal(privateKeyHex string, userAddress string, amount uint64, nonce uint64) ([]byte, int, error) {
+// matches: keccak(user_pubkey + amount_le + nonce_le + expires_at_le)
+// expiresAt is a unix timestamp; the program rejects the withdrawal after it.
+func SignWithdrawal(privateKeyHex string, userAddress string, amount uint64, nonce uint64, expiresAt int64) ([]byte, int, error) {
 	privKeyBytes, err := hex.DecodeString(privateKeyHex)
 	if err != nil {
 		return nil, 0, fmt.Errorf("invalid private key hex: %w", err)
@@ -23,15 +25,24 @@ func SignWithdrawal(privateKeyHex string, userAddress string, amount uint64, non
 		return nil, 0, fmt.Errorf("failed to parse ECDSA key: %w", err)
 	}
 
+	messageHash, err := WithdrawalHash(userAddress, amount, nonce, expiresAt)
+	if err != nil {
+		return nil, 0, err
+	}
+	return SignHash(privKey, messageHash)
+}
+
+// WithdrawalHash is the keccak digest the program recovers the signer from.
+func WithdrawalHash(userAddress string, amount uint64, nonce uint64, expiresAt int64) ([]byte, error) {
 	userPubkeyBytes, err := base58.Decode(userAddress)
 	if err != nil {
-		return nil, 0, fmt.Errorf("invalid user address: %w", err)
+		return nil, fmt.Errorf("invalid user address: %w", err)
 	}
 	if len(userPubkeyBytes) != 32 {
-		return nil, 0, fmt.Errorf("user address must be 32 bytes")
+		return nil, fmt.Errorf("user address must be 32 bytes")
 	}
 
-	buf := make([]byte, 0, 32+8+8)
+	buf := make([]byte, 0, 32+8+8+8)
 	buf = append(buf, userPubkeyBytes...)
 
 	amountBytes := make([]byte, 8)
@@ -42,10 +53,18 @@ func SignWithdrawal(privateKeyHex string, userAddress string, amount uint64, non
 	binary.LittleEndian.PutUint64(nonceBytes, nonce)
 	buf = append(buf, nonceBytes...)
 
+	expiresAtBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(expiresAtBytes, uint64(expiresAt))
+	buf = append(buf, expiresAtBytes...)
+
 	hasher := sha3.NewLegacyKeccak256()
 	hasher.Write(buf)
-	messageHash := hasher.Sum(nil)
+	return hasher.Sum(nil), nil
+}
 
+// SignHash signs a 32-byte digest and splits the result into the 64-byte
+// (r, s) signature and the recovery id the program expects.
+func SignHash(privKey *ecdsa.PrivateKey, messageHash []byte) ([]byte, int, error) {
 	sig, err := crypto.Sign(messageHash, privKey)
 	if err != nil {
 		return nil, 0, fmt.Errorf("signing failed: %w", err)
@@ -60,3 +79,28 @@ func SignWithdrawal(privateKeyHex string, userAddress string, amount uint64, non
 
 	return r_s_bytes, recoveryID, nil
 }
+
+// SigningAuthority is the keccak hash of the uncompressed public key (without
+// the 0x04 prefix), the value stored as signing_authority in the CasinoVault.
+func SigningAuthority(pub *ecdsa.PublicKey) []byte {
+	pubKeyBytes := crypto.FromECDSAPub(pub)
+
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(pubKeyBytes[1:])
+	return hasher.Sum(nil)
+}
+
+// RecoverSigningAuthority returns the signing authority that produced sig over
+// messageHash.
+func RecoverSigningAuthority(messageHash []byte, sig []byte, recoveryID int) ([]byte, error) {
+	if len(sig) != 64 {
+		return nil, fmt.Errorf("signature must be 64 bytes")
+	}
+	full := append(append([]byte{}, sig...), byte(recoveryID))
+
+	pub, err := crypto.SigToPub(messageHash, full)
+	if err != nil {
+		return nil, fmt.Errorf("recovering public key: %w", err)
+	}
+	return SigningAuthority(pub), nil
+}
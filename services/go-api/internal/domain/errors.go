@@ -7,4 +7,19 @@ var (
 	ErrSessionInactive   = errors.New("session is inactive")
 	ErrInvalidSeed       = errors.New("invalid client seed")
 	ErrBatchClosed       = errors.New("batch is already closed")
+	ErrDepositProcessed  = errors.New("deposit already processed")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrIdempotencyReuse  = errors.New("idempotency key was already used for a different request")
+	ErrRequestInFlight   = errors.New("a request with this idempotency key is still in progress")
+
+	ErrInvalidAmount         = errors.New("amount must be positive with at most 9 decimal places")
+	ErrWithdrawalNotExecuted = errors.New("withdrawal has not been executed on-chain yet")
+	ErrWithdrawalNonceTaken  = errors.New("withdrawal nonce was taken by a concurrent withdrawal, retry")
+	ErrWithdrawalNotExpired  = errors.New("withdrawal authorization is still valid, funds are returned once it expires")
+
+	ErrRotationUnsupported = errors.New("signing key rotation requires SIGNER_BACKEND=keystore")
+
+	ErrInvalidBet          = errors.New("bet must be positive with at most 9 decimal places")
+	ErrBetAboveMax         = errors.New("bet exceeds the maximum the bankroll can cover")
+	ErrBankrollUnavailable = errors.New("bankroll is unavailable, spins are paused")
 )
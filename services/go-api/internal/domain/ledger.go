@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Ledger accounts. Player and pending-withdrawal accounts exist per wallet;
+// house and vault are the casino's side of bets and on-chain flows.
+const (
+	LedgerAccountPlayer            = "player"
+	LedgerAccountPendingWithdrawal = "pending_withdrawal"
+	LedgerAccountHouse             = "house"
+	LedgerAccountVault             = "vault"
+)
+
+// Ledger entry types, one per kind of balance movement.
+const (
+	LedgerDeposit           = "deposit"            // vault -> player, source: tx sig
+	LedgerBet               = "bet"                // player -> house, source: spin
+	LedgerPayout            = "payout"             // house -> player, source: spin
+	LedgerWithdrawalHold    = "withdrawal_hold"    // player -> pending, source: withdrawal nonce
+	LedgerWithdrawalRelease = "withdrawal_release" // pending -> vault, source: withdrawal nonce
+	LedgerRefund            = "refund"             // pending -> player, source: withdrawal nonce
+	LedgerOpeningBalance    = "opening_balance"    // balances that predate the ledger
+)
+
+// LedgerEntry is one leg of a journal. The legs of a journal sum to zero.
+type LedgerEntry struct {
+	EntryID       int64           `json:"entry_id" db:"entry_id"`
+	JournalID     uuid.UUID       `json:"journal_id" db:"journal_id"`
+	Account       string          `json:"account" db:"account"`
+	WalletAddress string          `json:"wallet_address" db:"wallet_address"`
+	EntryType     string          `json:"entry_type" db:"entry_type"`
+	Amount        decimal.Decimal `json:"amount" db:"amount"`
+
+	SourceTxSig  *string    `json:"source_tx_sig" db:"source_tx_sig"`
+	SourceSpinID *uuid.UUID `json:"source_spin_id" db:"source_spin_id"`
+	SourceNonce  *int64     `json:"source_nonce" db:"source_nonce"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LedgerMismatch is a wallet whose stored balances differ from its ledger.
+type LedgerMismatch struct {
+	WalletAddress string          `json:"wallet_address"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+	StoredBalance decimal.Decimal `json:"stored_balance"`
+	LedgerPending decimal.Decimal `json:"ledger_pending"`
+	StoredPending decimal.Decimal `json:"stored_pending"`
+}
+
+// LedgerReport is the result of reconciling the ledger against stored balances.
+type LedgerReport struct {
+	Wallets            int              `json:"wallets"`
+	Mismatches         []LedgerMismatch `json:"mismatches"`
+	UnbalancedJournals []uuid.UUID      `json:"unbalanced_journals"`
+}
+
+// Balanced reports whether every journal sums to zero and every balance matches.
+func (r *LedgerReport) Balanced() bool {
+	return len(r.Mismatches) == 0 && len(r.UnbalancedJournals) == 0
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,28 +9,92 @@ import (
 )
 
 type User struct {
-	WalletAddress              string          `json:"wallet_address" db:"wallet_address"`
-	NextWithdrawalNonce        int64           `json:"next_withdrawal_nonce" db:"next_withdrawal_nonce"`
-	PendingWithdrawalAmount    decimal.Decimal `json:"pending_withdrawal_amount" db:"pending_withdrawal_amount"`
-	PendingWithdrawalSignature string          `json:"pending_withdrawal_signature" db:"pending_withdrawal_signature"`
-	CreatedAt                  time.Time       `json:"created_at" db:"created_at"`
+	WalletAddress       string    `json:"wallet_address" db:"wallet_address"`
+	NextWithdrawalNonce int64     `json:"next_withdrawal_nonce" db:"next_withdrawal_nonce"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
 }
 
-type Session struct {
-	SessionID       uuid.UUID       `json:"session_id" db:"session_id"`
+const (
+	WithdrawalStatusSigned    = "signed"    // authorization handed out, funds held
+	WithdrawalStatusConfirmed = "confirmed" // nonce consumed by the program
+	WithdrawalStatusRefunded  = "refunded"  // never executed, funds returned
+	WithdrawalStatusExpired   = "expired"   // authorization lapsed, funds returned
+)
+
+// Withdrawal is one signed withdrawal authorization. Nonces are consumed on
+// chain strictly in order, so a wallet's signed withdrawals form a queue.
+type Withdrawal struct {
+	WalletAddress string          `json:"wallet_address" db:"wallet_address"`
+	Nonce         int64           `json:"nonce" db:"nonce"`
+	Amount        decimal.Decimal `json:"amount" db:"amount"`
+	Signature     string          `json:"signature" db:"signature"`
+	RecoveryID    *int            `json:"recovery_id" db:"recovery_id"`
+	Status        string          `json:"status" db:"status"` // WithdrawalStatus*
+	SolanaTxSig   *string         `json:"solana_tx_sig" db:"solana_tx_sig"`
+	// ExpiresAt is signed into the authorization; the program rejects it afterwards.
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AuthChallenge is a single-use Sign-In-With-Solana nonce and the exact
+// message the wallet is expected to sign for it.
+type AuthChallenge struct {
+	Nonce         string     `json:"nonce" db:"nonce"`
+	WalletAddress string     `json:"wallet_address" db:"wallet_address"`
+	Message       string     `json:"message" db:"message"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt        *time.Time `json:"used_at" db:"used_at"`
+}
+
+// IdempotencyRecord is a request seen under an Idempotency-Key. ResponseStatus
+// is nil while the original request is still running.
+type IdempotencyRecord struct {
+	WalletAddress  string     `json:"wallet_address" db:"wallet_address"`
+	Key            string     `json:"idempotency_key" db:"idempotency_key"`
+	RequestHash    string     `json:"request_hash" db:"request_hash"`
+	ResponseStatus *int       `json:"response_status" db:"response_status"`
+	ResponseBody   []byte     `json:"-" db:"response_body"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// Account holds a wallet's playable balance. It outlives sessions.
+type Account struct {
 	WalletAddress   string          `json:"wallet_address" db:"wallet_address"`
 	PlayableBalance decimal.Decimal `json:"playable_balance" db:"playable_balance"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Session is the provably-fair context spins are played in: the committed
+// server seed and the nonce counter. Money lives on Account.
+type Session struct {
+	SessionID     uuid.UUID `json:"session_id" db:"session_id"`
+	WalletAddress string    `json:"wallet_address" db:"wallet_address"`
 
 	NextServerSeed     string `json:"-" db:"next_server_seed"`
 	NextServerSeedHash string `json:"next_server_seed_hash" db:"next_server_seed_hash"`
+	// SpinNonce is the nonce of the last spin played in this session.
+	SpinNonce int64 `json:"spin_nonce" db:"spin_nonce"`
 
 	IsActive  bool      `json:"is_active" db:"is_active"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 type SpinOutcome struct {
-	Reels []int `json:"reels"`
-	IsWin bool  `json:"is_win"`
+	Reels []int     `json:"reels"`
+	Rows  int       `json:"rows,omitempty"`
+	IsWin bool      `json:"is_win"`
+	Wins  []SpinWin `json:"wins,omitempty"`
+}
+
+// SpinWin records why part of a payout was awarded.
+type SpinWin struct {
+	Kind       string  `json:"kind"`    // 'line', 'scatter'
+	Payline    int     `json:"payline"` // -1 for scatter wins
+	Symbol     int     `json:"symbol"`
+	Count      int     `json:"count"`
+	Multiplier float64 `json:"multiplier"`
 }
 
 type Spin struct {
@@ -38,6 +103,9 @@ type Spin struct {
 	WalletAddress string    `json:"wallet_address" db:"wallet_address"`
 	SpinNonce     int64     `json:"spin_nonce" db:"spin_nonce"`
 
+	GameID      string `json:"game_id" db:"game_id"`
+	GameVersion int    `json:"game_version" db:"game_version"`
+
 	ServerSeed     string `json:"server_seed" db:"server_seed"`
 	ClientSeed     string `json:"client_seed" db:"client_seed"`
 	ServerSeedHash string `json:"server_seed_hash" db:"server_seed_hash"`
@@ -47,16 +115,42 @@ type Spin struct {
 
 	Outcome SpinOutcome `json:"outcome" db:"-"`
 
-	LeafHash  string    `json:"leaf_hash" db:"leaf_hash"`
-	BatchID   *int64    `json:"batch_id" db:"batch_id"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	LeafHash string `json:"leaf_hash" db:"leaf_hash"`
+	// LeafFormat is the crypto.MerkleFormat whose leaf LeafHash was built
+	// for; the spin is only batched into trees of that format.
+	LeafFormat int       `json:"-" db:"leaf_format"`
+	BatchID    *int64    `json:"batch_id" db:"batch_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
+const (
+	BatchStatusOpen      = "OPEN"      // spins linked, nothing sent yet
+	BatchStatusSubmitted = "SUBMITTED" // commit tx sent, not yet seen on chain
+	BatchStatusCommitted = "COMMITTED" // BatchCommit account holds our root
+	BatchStatusFailed    = "FAILED"    // gave up, needs operator attention
+)
+
 type Batch struct {
-	BatchID     int64      `json:"batch_id" db:"batch_id"`
-	Status      string     `json:"status" db:"status"` // 'OPEN', 'COMMITTED', 'FAILED'
-	MerkleRoot  *string    `json:"merkle_root" db:"merkle_root"`
-	SolanaTxSig *string    `json:"solana_tx_sig" db:"solana_tx_sig"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	CommittedAt *time.Time `json:"committed_at" db:"committed_at"`
+	BatchID       int64      `json:"batch_id" db:"batch_id"`
+	Status        string     `json:"status" db:"status"`           // BatchStatus*
+	TreeFormat    int        `json:"tree_format" db:"tree_format"` // crypto.MerkleFormat
+	MerkleRoot    *string    `json:"merkle_root" db:"merkle_root"`
+	SolanaTxSig   *string    `json:"solana_tx_sig" db:"solana_tx_sig"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	SubmittedAt   *time.Time `json:"submitted_at" db:"submitted_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	CommittedAt   *time.Time `json:"committed_at" db:"committed_at"`
+	// CommittedSlot is the slot the commit transaction landed in, nil if unknown.
+	CommittedSlot *int64 `json:"committed_slot" db:"committed_slot"`
+}
+
+// MerkleProof is the audit path of a spin inside its batch, stored at commit
+// time. Proof holds the format-specific JSON (see Batch.TreeFormat).
+type MerkleProof struct {
+	SpinID    uuid.UUID       `json:"spin_id" db:"spin_id"`
+	BatchID   int64           `json:"batch_id" db:"batch_id"`
+	LeafIndex int             `json:"leaf_index" db:"leaf_index"`
+	Proof     json.RawMessage `json:"proof" db:"proof_hashes"`
 }
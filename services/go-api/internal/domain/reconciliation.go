@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Discrepancies found when a wallet's UserBalance PDA is compared to Postgres.
+const (
+	DiscrepancyMissingOnChain       = "missing_onchain"        // deposits credited, but no UserBalance account
+	DiscrepancyMissingOffChain      = "missing_offchain"       // UserBalance account for a wallet Postgres does not know
+	DiscrepancyAmountMismatch       = "amount_mismatch"        // on-chain amount != deposited - withdrawn
+	DiscrepancyUnconfirmedExecution = "unconfirmed_withdrawal" // a signed withdrawal was executed but not confirmed
+	DiscrepancyConfirmedNotExecuted = "confirmed_not_executed" // a confirmed withdrawal is beyond the on-chain nonce
+	DiscrepancyStaleNonce           = "stale_nonce"            // the next nonce would be rejected on chain
+)
+
+// WalletPosition is what Postgres knows about a wallet's funds. Amounts are in SOL.
+type WalletPosition struct {
+	WalletAddress       string
+	NextWithdrawalNonce int64
+	Deposited           decimal.Decimal // credited deposits
+	Withdrawn           decimal.Decimal // confirmed withdrawals
+	Pending             decimal.Decimal // signed withdrawals, not executed yet
+	PlayableBalance     decimal.Decimal
+	LastConfirmedNonce  int64
+	FirstSignedNonce    *int64 // lowest nonce still in the signed state
+}
+
+// ReconciliationItem compares one wallet. On-chain fields are nil when the
+// wallet has no UserBalance account, NextWithdrawalNonce when Postgres has no user.
+type ReconciliationItem struct {
+	WalletAddress       string           `json:"wallet_address" db:"wallet_address"`
+	OnChainAmount       *decimal.Decimal `json:"onchain_amount" db:"onchain_amount"`
+	OnChainLastNonce    *int64           `json:"onchain_last_nonce" db:"onchain_last_nonce"`
+	Deposited           decimal.Decimal  `json:"deposited" db:"deposited"`
+	Withdrawn           decimal.Decimal  `json:"withdrawn" db:"withdrawn"`
+	PlayableBalance     decimal.Decimal  `json:"playable_balance" db:"playable_balance"`
+	PendingWithdrawals  decimal.Decimal  `json:"pending_withdrawals" db:"pending_withdrawals"`
+	NextWithdrawalNonce *int64           `json:"next_withdrawal_nonce" db:"next_withdrawal_nonce"`
+	// Drift is the playable balance plus pending withdrawals minus the on-chain
+	// amount: the player's net game result, which UserBalance never sees.
+	Drift  decimal.Decimal `json:"drift" db:"drift"`
+	Issues []string        `json:"issues" db:"issues"` // Discrepancy*
+}
+
+// ReconciliationRun is one report over all wallets. Liabilities are the
+// playable balances plus pending withdrawals the vault has to cover.
+type ReconciliationRun struct {
+	RunID              int64                `json:"run_id" db:"run_id"`
+	Wallets            int                  `json:"wallets" db:"wallets"`
+	Discrepancies      int                  `json:"discrepancies" db:"discrepancies"`
+	OnChainTotal       decimal.Decimal      `json:"onchain_total" db:"onchain_total"`
+	Liabilities        decimal.Decimal      `json:"liabilities" db:"liabilities"`
+	VaultLiquidity     decimal.Decimal      `json:"vault_liquidity" db:"vault_liquidity"`
+	LiabilitiesCovered bool                 `json:"liabilities_covered" db:"liabilities_covered"`
+	CreatedAt          time.Time            `json:"created_at" db:"created_at"`
+	Items              []ReconciliationItem `json:"items"`
+}
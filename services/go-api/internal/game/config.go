@@ -1,5 +1,15 @@
 package game
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
 type Symbol int
 
 const (
@@ -12,31 +22,259 @@ const (
 	SymSeven                 // 6
 	SymDiamond               // 7
 	SymWild                  // 8
+	SymScatter               // 9
 )
 
-var PayoutMultipliers = map[Symbol]float64{
-	SymCherry:  2.0,
-	SymLemon:   3.0,
-	SymPlum:    5.0,
-	SymBar:     10.0,
-	SymBell:    20.0,
-	SymSeven:   50.0,
-	SymDiamond: 100.0,
-	SymWild:    500.0,
-}
-
-var MainReelStrip = []Symbol{
-	SymWild, SymCherry, SymLemon, SymPlum, SymLemon, SymBar, SymCherry,
-	SymSeven, SymLemon, SymPlum, SymCherry, SymBell, SymPlum, SymLemon,
-	SymDiamond, SymCherry, SymPlum, SymLemon, SymBar, SymCherry, SymLemon,
-	SymSeven, SymPlum, SymLemon, SymCherry, SymBar, SymLemon, SymPlum,
-	SymCherry, SymBell, SymBar, SymBell,
-}
-
-var Paylines = [][][]int{
-	{{0, 0}, {0, 1}, {0, 2}}, // Top Row
-	{{1, 0}, {1, 1}, {1, 2}}, // Middle Row
-	{{2, 0}, {2, 1}, {2, 2}}, // Bottom Row
-	{{0, 0}, {1, 1}, {2, 2}}, // Top-Left to Bottom-Right
-	{{2, 0}, {1, 1}, {0, 2}}, // Bottom-Left to Top-Right
+// Definition is a compiled, validated slot game. Reels are indexed by column,
+// Paylines hold the row index hit on each reel. Paytable maps a symbol to the
+// multiplier paid for a given left-to-right match count.
+type Definition struct {
+	ID       string
+	Version  int
+	RNG      RNGScheme
+	Rows     int
+	Reels    [][]Symbol
+	Paylines [][]int
+	Paytable map[Symbol]map[int]float64
+
+	// Wild substitutes for every symbol except the scatter. Nil when the game has no wild.
+	Wild *Symbol
+	// Scatter pays anywhere on the grid by total count. Nil when the game has no scatter.
+	Scatter     *Symbol
+	ScatterPays map[int]float64
+
+	names map[Symbol]string
+}
+
+// definitionFile is the on-disk (JSON/YAML) representation of a Definition.
+// Symbols are referenced by name so that files stay readable; the numeric IDs
+// are what ends up in spin outcomes and leaf hashes.
+type definitionFile struct {
+	ID       string                     `json:"id"`
+	Version  int                        `json:"version"`
+	RNG      RNGScheme                  `json:"rng,omitempty"`
+	Rows     int                        `json:"rows"`
+	Symbols  map[string]int             `json:"symbols"`
+	Reels    [][]string                 `json:"reels"`
+	Paylines [][]int                    `json:"paylines"`
+	Paytable map[string]map[int]float64 `json:"paytable"`
+	Wild     string                     `json:"wild,omitempty"`
+	Scatter  *scatterFile               `json:"scatter,omitempty"`
+}
+
+type scatterFile struct {
+	Symbol string          `json:"symbol"`
+	Pays   map[int]float64 `json:"pays"`
+}
+
+// ParseDefinition decodes a game definition. The format is picked from the
+// file name extension (.json, .yaml, .yml).
+func ParseDefinition(name string, data []byte) (*Definition, error) {
+	var file definitionFile
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid game definition json %s: %w", name, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid game definition yaml %s: %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported game definition format: %s", name)
+	}
+
+	return file.compile()
+}
+
+func (f *definitionFile) compile() (*Definition, error) {
+	if f.ID == "" {
+		return nil, fmt.Errorf("game definition is missing an id")
+	}
+	if f.Version <= 0 {
+		return nil, fmt.Errorf("game %s: version must be positive", f.ID)
+	}
+	if f.Rows <= 0 {
+		return nil, fmt.Errorf("game %s: rows must be positive", f.ID)
+	}
+	if len(f.Reels) == 0 {
+		return nil, fmt.Errorf("game %s: at least one reel is required", f.ID)
+	}
+
+	// Definitions written before the stream RNG existed carry no rng field.
+	if f.RNG == "" {
+		f.RNG = RNGLegacy
+	}
+	switch f.RNG {
+	case RNGLegacy:
+		if len(f.Reels) > 8 {
+			return nil, fmt.Errorf("game %s: rng %s supports at most 8 reels", f.ID, f.RNG)
+		}
+	case RNGStream:
+	default:
+		return nil, fmt.Errorf("game %s: unknown rng %q", f.ID, f.RNG)
+	}
+
+	lookup := func(name string) (Symbol, error) {
+		id, ok := f.Symbols[name]
+		if !ok {
+			return SymEmpty, fmt.Errorf("game %s: unknown symbol %q", f.ID, name)
+		}
+		return Symbol(id), nil
+	}
+
+	def := &Definition{
+		ID:       f.ID,
+		Version:  f.Version,
+		RNG:      f.RNG,
+		Rows:     f.Rows,
+		Reels:    make([][]Symbol, len(f.Reels)),
+		Paylines: f.Paylines,
+		Paytable: make(map[Symbol]map[int]float64, len(f.Paytable)),
+		names:    make(map[Symbol]string, len(f.Symbols)),
+	}
+	for name, id := range f.Symbols {
+		def.names[Symbol(id)] = name
+	}
+
+	if f.Wild != "" {
+		wild, err := lookup(f.Wild)
+		if err != nil {
+			return nil, err
+		}
+		def.Wild = &wild
+	}
+
+	if f.Scatter != nil {
+		scatter, err := lookup(f.Scatter.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		if def.Wild != nil && *def.Wild == scatter {
+			return nil, fmt.Errorf("game %s: wild and scatter must be different symbols", f.ID)
+		}
+		for count, mult := range f.Scatter.Pays {
+			if count <= 0 || mult < 0 {
+				return nil, fmt.Errorf("game %s: invalid scatter pay %d => %v", f.ID, count, mult)
+			}
+		}
+		def.Scatter = &scatter
+		def.ScatterPays = f.Scatter.Pays
+	}
+
+	for col, strip := range f.Reels {
+		if len(strip) < f.Rows {
+			return nil, fmt.Errorf("game %s: reel %d is shorter than the grid height", f.ID, col)
+		}
+		def.Reels[col] = make([]Symbol, len(strip))
+		for i, name := range strip {
+			sym, err := lookup(name)
+			if err != nil {
+				return nil, err
+			}
+			def.Reels[col][i] = sym
+		}
+	}
+
+	for lineIdx, line := range f.Paylines {
+		if len(line) != len(f.Reels) {
+			return nil, fmt.Errorf("game %s: payline %d must have one row per reel", f.ID, lineIdx)
+		}
+		for _, row := range line {
+			if row < 0 || row >= f.Rows {
+				return nil, fmt.Errorf("game %s: payline %d references row %d outside the grid", f.ID, lineIdx, row)
+			}
+		}
+	}
+
+	for name, pays := range f.Paytable {
+		sym, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		if def.Scatter != nil && sym == *def.Scatter {
+			return nil, fmt.Errorf("game %s: scatter %q cannot have line pays", f.ID, name)
+		}
+		for count, mult := range pays {
+			if count <= 0 || count > len(f.Reels) {
+				return nil, fmt.Errorf("game %s: %q pays for %d symbols on a %d reel line", f.ID, name, count, len(f.Reels))
+			}
+			if mult < 0 {
+				return nil, fmt.Errorf("game %s: negative multiplier for %q", f.ID, name)
+			}
+		}
+		def.Paytable[sym] = pays
+	}
+
+	return def, nil
+}
+
+// Cols returns the number of reels in the grid.
+func (d *Definition) Cols() int {
+	return len(d.Reels)
+}
+
+// SymbolName returns the name a symbol was given in the definition file.
+func (d *Definition) SymbolName(sym Symbol) string {
+	if name, ok := d.names[sym]; ok {
+		return name
+	}
+	return fmt.Sprintf("symbol(%d)", sym)
+}
+
+// LinePay returns the multiplier for count matching symbols on a payline. A
+// run longer than any count the paytable defines pays at the highest count
+// below it, so five of a kind on a symbol paying only 3 and 4 pays as 4.
+func (d *Definition) LinePay(sym Symbol, count int) (float64, bool) {
+	pays := d.Paytable[sym]
+	for ; count > 0; count-- {
+		if mult, ok := pays[count]; ok {
+			return mult, true
+		}
+	}
+	return 0, false
+}
+
+// MaxMultiplier bounds the total multiplier of a single spin: every payline
+// hitting the best line pay at once, plus the best scatter pay.
+func (d *Definition) MaxMultiplier() float64 {
+	bestLine := 0.0
+	for _, pays := range d.Paytable {
+		for _, mult := range pays {
+			bestLine = math.Max(bestLine, mult)
+		}
+	}
+	bestScatter := 0.0
+	for _, mult := range d.ScatterPays {
+		bestScatter = math.Max(bestScatter, mult)
+	}
+	return bestLine*float64(len(d.Paylines)) + bestScatter
+}
+
+// IsWild reports whether sym substitutes for other symbols.
+func (d *Definition) IsWild(sym Symbol) bool {
+	return d.Wild != nil && *d.Wild == sym
+}
+
+// IsScatter reports whether sym is the scatter symbol.
+func (d *Definition) IsScatter(sym Symbol) bool {
+	return d.Scatter != nil && *d.Scatter == sym
+}
+
+// ReelStops derives the reel stops for a spin using the definition's RNG scheme.
+func (d *Definition) ReelStops(serverSeed string, clientSeed string, nonce int64) ([]int, string, error) {
+	if d.RNG == RNGStream {
+		return DrawReelStops(serverSeed, clientSeed, nonce, d.ReelLengths())
+	}
+	return GenerateReelStops(serverSeed, clientSeed, nonce, d.ReelLengths())
+}
+
+// ReelLengths returns the strip length of every reel, in column order.
+func (d *Definition) ReelLengths() []int {
+	lengths := make([]int, len(d.Reels))
+	for i, strip := range d.Reels {
+		lengths[i] = len(strip)
+	}
+	return lengths
 }
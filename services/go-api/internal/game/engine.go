@@ -4,52 +4,142 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+type WinKind string
+
+const (
+	WinLine    WinKind = "line"
+	WinScatter WinKind = "scatter"
+)
+
+// Win explains a single component of a payout.
+type Win struct {
+	Kind       WinKind
+	Payline    int // index into Definition.Paylines, -1 for scatter wins
+	Symbol     Symbol
+	Count      int
+	Multiplier float64
+}
+
 type SpinResult struct {
 	Matrix      [][]Symbol
-	PaylineWins []int
+	Wins        []Win
 	TotalPayout decimal.Decimal
 	LeafHash    string
 }
 
-// CalculateSpin performs the full slot logic
-func CalculateSpin(serverSeed string, clientSeed string, nonce int64, betAmount decimal.Decimal) (*SpinResult, error) {
-	reelLength := len(MainReelStrip)
-	stops, leafHash, err := GenerateReelStops(serverSeed, clientSeed, nonce, reelLength)
+// RoundPayout rounds a payout to whole lamports, the precision balances and
+// spins are stored with.
+func RoundPayout(payout decimal.Decimal) decimal.Decimal {
+	return payout.Round(9)
+}
+
+// CalculateSpin performs the full slot logic for the given game definition
+func CalculateSpin(def *Definition, serverSeed string, clientSeed string, nonce int64, betAmount decimal.Decimal) (*SpinResult, error) {
+	stops, leafHash, err := def.ReelStops(serverSeed, clientSeed, nonce)
 	if err != nil {
 		return nil, err
 	}
 
-	matrix := make([][]Symbol, 3)
-	for row := 0; row < 3; row++ {
-		matrix[row] = make([]Symbol, 3)
-		for col := 0; col < 3; col++ {
-			stripIndex := (stops[col] + row) % reelLength
-			matrix[row][col] = MainReelStrip[stripIndex]
-		}
-	}
+	matrix := def.Grid(stops)
+	wins := def.Evaluate(matrix)
 
 	totalMultiplier := 0.0
-	var winningLines []int
-
-	for lineIdx, coords := range Paylines {
-		s1 := matrix[coords[0][0]][coords[0][1]]
-		s2 := matrix[coords[1][0]][coords[1][1]]
-		s3 := matrix[coords[2][0]][coords[2][1]]
-
-		if s1 == s2 && s2 == s3 {
-			if mult, ok := PayoutMultipliers[s1]; ok {
-				totalMultiplier += mult
-				winningLines = append(winningLines, lineIdx)
-			}
-		}
+	for _, w := range wins {
+		totalMultiplier += w.Multiplier
 	}
 
 	payout := betAmount.Mul(decimal.NewFromFloat(totalMultiplier))
 
 	return &SpinResult{
 		Matrix:      matrix,
-		PaylineWins: winningLines,
+		Wins:        wins,
 		TotalPayout: payout,
 		LeafHash:    leafHash,
 	}, nil
 }
+
+// Grid builds the visible symbol matrix (rows x reels) for the given reel stops.
+func (d *Definition) Grid(stops []int) [][]Symbol {
+	matrix := make([][]Symbol, d.Rows)
+	for row := 0; row < d.Rows; row++ {
+		matrix[row] = make([]Symbol, d.Cols())
+		for col, strip := range d.Reels {
+			stripIndex := (stops[col] + row) % len(strip)
+			matrix[row][col] = strip[stripIndex]
+		}
+	}
+	return matrix
+}
+
+// Evaluate returns every line and scatter win on a grid.
+func (d *Definition) Evaluate(matrix [][]Symbol) []Win {
+	var wins []Win
+
+	line := make([]Symbol, d.Cols())
+	for lineIdx, rows := range d.Paylines {
+		for col, row := range rows {
+			line[col] = matrix[row][col]
+		}
+		if win, ok := d.evaluateLine(line); ok {
+			win.Payline = lineIdx
+			wins = append(wins, win)
+		}
+	}
+
+	if d.Scatter != nil {
+		count := 0
+		for _, row := range matrix {
+			for _, sym := range row {
+				if sym == *d.Scatter {
+					count++
+				}
+			}
+		}
+		if mult, ok := d.ScatterPays[count]; ok && mult > 0 {
+			wins = append(wins, Win{
+				Kind:       WinScatter,
+				Payline:    -1,
+				Symbol:     *d.Scatter,
+				Count:      count,
+				Multiplier: mult,
+			})
+		}
+	}
+
+	return wins
+}
+
+// evaluateLine pays the longest left-to-right run on a payline. Wilds stand in
+// for the first non-wild symbol of the run; a leading run of wilds may also pay
+// as wilds on its own, and the better of the two is taken.
+func (d *Definition) evaluateLine(line []Symbol) (Win, bool) {
+	if d.IsScatter(line[0]) {
+		return Win{}, false
+	}
+
+	wildRun := 0
+	for wildRun < len(line) && d.IsWild(line[wildRun]) {
+		wildRun++
+	}
+
+	best := Win{Kind: WinLine}
+
+	if wildRun < len(line) && !d.IsScatter(line[wildRun]) {
+		target := line[wildRun]
+		count := wildRun
+		for count < len(line) && (line[count] == target || d.IsWild(line[count])) {
+			count++
+		}
+		if mult, ok := d.LinePay(target, count); ok {
+			best.Symbol, best.Count, best.Multiplier = target, count, mult
+		}
+	}
+
+	if wildRun > 0 {
+		if mult, ok := d.LinePay(*d.Wild, wildRun); ok && mult > best.Multiplier {
+			best.Symbol, best.Count, best.Multiplier = *d.Wild, wildRun, mult
+		}
+	}
+
+	return best, best.Multiplier > 0
+}
@@ -13,7 +13,16 @@ func TestCalculateSpin(t *testing.T) {
 	nonce := int64(1)
 	bet := decimal.NewFromFloat(1.0)
 
-	result, err := CalculateSpin(serverSeed, clientSeed, nonce, bet)
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+	def, err := registry.Latest(DefaultGameID)
+	if err != nil {
+		t.Fatalf("Default game missing: %v", err)
+	}
+
+	result, err := CalculateSpin(def, serverSeed, clientSeed, nonce, bet)
 	if err != nil {
 		t.Fatalf("Engine failed: %v", err)
 	}
@@ -23,7 +32,7 @@ func TestCalculateSpin(t *testing.T) {
 		fmt.Printf("%v\n", row)
 	}
 
-	fmt.Printf("Winning Lines: %v\n", result.PaylineWins)
+	fmt.Printf("Wins: %+v\n", result.Wins)
 	fmt.Printf("Total Payout: %s\n", result.TotalPayout.String())
 	fmt.Printf("Leaf Hash: %s\n", result.LeafHash)
 
@@ -34,3 +43,113 @@ func TestCalculateSpin(t *testing.T) {
 		t.Errorf("Expected 3 cols, got %d", len(result.Matrix[0]))
 	}
 }
+
+func TestEvaluateWildScatterAndPartialLines(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+	def, err := registry.Get(DefaultGameID, 2)
+	if err != nil {
+		t.Fatalf("Classic v2 missing: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		matrix [][]Symbol
+		want   []Win
+	}{
+		{
+			name: "wild substitutes in the middle",
+			matrix: [][]Symbol{
+				{SymSeven, SymWild, SymSeven},
+				{SymLemon, SymPlum, SymBar},
+				{SymBar, SymLemon, SymPlum},
+			},
+			want: []Win{{Kind: WinLine, Payline: 0, Symbol: SymSeven, Count: 3, Multiplier: 25}},
+		},
+		{
+			name: "two of a kind from the left",
+			matrix: [][]Symbol{
+				{SymLemon, SymPlum, SymBar},
+				{SymBell, SymBell, SymCherry},
+				{SymBar, SymLemon, SymPlum},
+			},
+			want: []Win{{Kind: WinLine, Payline: 1, Symbol: SymBell, Count: 2, Multiplier: 1}},
+		},
+		{
+			name: "leading wilds pay as wilds when better",
+			matrix: [][]Symbol{
+				{SymWild, SymWild, SymCherry},
+				{SymLemon, SymPlum, SymBar},
+				{SymBar, SymLemon, SymBar},
+			},
+			want: []Win{{Kind: WinLine, Payline: 0, Symbol: SymWild, Count: 2, Multiplier: 10}},
+		},
+		{
+			name: "scatter pays anywhere",
+			matrix: [][]Symbol{
+				{SymScatter, SymLemon, SymPlum},
+				{SymLemon, SymPlum, SymScatter},
+				{SymBar, SymScatter, SymPlum},
+			},
+			want: []Win{{Kind: WinScatter, Payline: -1, Symbol: SymScatter, Count: 3, Multiplier: 10}},
+		},
+	}
+
+	for _, tc := range cases {
+		got := def.Evaluate(tc.matrix)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: expected %d wins, got %+v", tc.name, len(tc.want), got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: expected %+v, got %+v", tc.name, tc.want[i], got[i])
+			}
+		}
+	}
+}
+
+func TestBuiltinGamesRTP(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+
+	for _, id := range registry.IDs() {
+		def, err := registry.Latest(id)
+		if err != nil {
+			t.Fatalf("Latest %s failed: %v", id, err)
+		}
+		stats := def.ExactStats()
+		fmt.Printf("%s v%d exact RTP: %.4f%%\n", def.ID, def.Version, stats.RTP()*100)
+
+		if stats.RTP() >= 1 {
+			t.Errorf("%s v%d pays out more than it takes: RTP %.4f", def.ID, def.Version, stats.RTP())
+		}
+	}
+}
+
+func TestEvaluateRunLongerThanPaytable(t *testing.T) {
+	def, err := ParseDefinition("long.json", []byte(`{
+		"id": "long", "version": 1, "rows": 1,
+		"symbols": {"cherry": 1, "seven": 6},
+		"reels": [["seven"], ["seven"], ["seven"], ["seven"], ["seven"]],
+		"paylines": [[0, 0, 0, 0, 0]],
+		"paytable": {"cherry": {"4": 4}, "seven": {"3": 10, "4": 40}}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	got := def.Evaluate([][]Symbol{{SymSeven, SymSeven, SymSeven, SymSeven, SymSeven}})
+	want := Win{Kind: WinLine, Payline: 0, Symbol: SymSeven, Count: 5, Multiplier: 40}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Expected five sevens to pay as four, got %+v", got)
+	}
+
+	if got := def.Evaluate([][]Symbol{{SymCherry, SymCherry, SymCherry, SymSeven, SymSeven}}); len(got) != 0 {
+		t.Errorf("Expected three cherries below the shortest pay to lose, got %+v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+)
+
+// DefaultGameID is used when a request does not name a game.
+const DefaultGameID = "classic"
+
+//go:embed definitions/*
+var builtinDefinitions embed.FS
+
+// Registry keeps every loaded version of every game. New spins are played on
+// the latest version, older versions stay available so historic spins can be
+// replayed with the paytable they were played under.
+type Registry struct {
+	mu    sync.RWMutex
+	games map[string]map[int]*Definition
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		games: make(map[string]map[int]*Definition),
+	}
+}
+
+// NewDefaultRegistry returns a registry preloaded with the built-in definitions.
+func NewDefaultRegistry() (*Registry, error) {
+	r := NewRegistry()
+	if err := r.LoadFS(builtinDefinitions, "definitions"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register adds a definition. Re-registering an existing id/version is an
+// error, since published versions must never change underneath recorded spins.
+func (r *Registry) Register(def *Definition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, ok := r.games[def.ID]
+	if !ok {
+		versions = make(map[int]*Definition)
+		r.games[def.ID] = versions
+	}
+	if _, exists := versions[def.Version]; exists {
+		return fmt.Errorf("game %s version %d is already registered", def.ID, def.Version)
+	}
+	versions[def.Version] = def
+	return nil
+}
+
+// LoadDir registers every .json/.yaml/.yml definition found in dir.
+func (r *Registry) LoadDir(dir string) error {
+	return r.LoadFS(os.DirFS(dir), ".")
+}
+
+func (r *Registry) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read game definitions: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch path.Ext(entry.Name()) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		def, err := ParseDefinition(entry.Name(), data)
+		if err != nil {
+			return err
+		}
+		if err := r.Register(def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a specific version of a game.
+func (r *Registry) Get(id string, version int) (*Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, ok := r.games[id][version]
+	if !ok {
+		return nil, fmt.Errorf("game %s version %d not found", id, version)
+	}
+	return def, nil
+}
+
+// Latest returns the highest registered version of a game.
+func (r *Registry) Latest(id string) (*Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *Definition
+	for _, def := range r.games[id] {
+		if latest == nil || def.Version > latest.Version {
+			latest = def
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("game %s not found", id)
+	}
+	return latest, nil
+}
+
+// IDs lists the registered game ids in sorted order.
+func (r *Registry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.games))
+	for id := range r.games {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
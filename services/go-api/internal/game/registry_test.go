@@ -0,0 +1,80 @@
+package game
+
+import (
+	"testing"
+)
+
+const testDefinitionYAML = `
+id: mini
+version: 2
+rows: 1
+symbols:
+  cherry: 1
+  seven: 6
+reels:
+  - [cherry, seven]
+  - [cherry, seven]
+  - [cherry, seven]
+  - [cherry, seven]
+paylines:
+  - [0, 0, 0, 0]
+paytable:
+  cherry: {4: 4}
+  seven: {3: 10, 4: 40}
+`
+
+func TestRegistryVersions(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+
+	def, err := ParseDefinition("mini.yaml", []byte(testDefinitionYAML))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if def.Cols() != 4 || def.Rows != 1 {
+		t.Errorf("Expected 1x4 grid, got %dx%d", def.Rows, def.Cols())
+	}
+	if mult, _ := def.LinePay(SymSeven, 4); mult != 40 {
+		t.Errorf("Expected four sevens to pay 40, got %v", mult)
+	}
+
+	if err := registry.Register(def); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register(def); err == nil {
+		t.Errorf("Expected duplicate version to be rejected")
+	}
+
+	latest, err := registry.Latest("mini")
+	if err != nil || latest.Version != 2 {
+		t.Errorf("Expected latest mini version 2, got %v (%v)", latest, err)
+	}
+	if _, err := registry.Get("mini", 1); err == nil {
+		t.Errorf("Expected unknown version lookup to fail")
+	}
+}
+
+func TestParseDefinitionRejectsBadPayline(t *testing.T) {
+	bad := `{"id":"bad","version":1,"rows":1,"symbols":{"cherry":1},"reels":[["cherry"],["cherry"]],"paylines":[[0,1]],"paytable":{"cherry":{"2":1}}}`
+	if _, err := ParseDefinition("bad.json", []byte(bad)); err == nil {
+		t.Errorf("Expected out-of-grid payline to be rejected")
+	}
+}
+
+func TestMaxMultiplier(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+
+	v1, _ := registry.Get("classic", 1)
+	if got := v1.MaxMultiplier(); got != 2500 {
+		t.Errorf("Expected 5 paylines of 500x wilds to bound classic v1 at 2500x, got %v", got)
+	}
+	v3, _ := registry.Get("classic", 3)
+	if got := v3.MaxMultiplier(); got != 510 {
+		t.Errorf("Expected 5 paylines of 100x wilds plus a 10x scatter to bound classic v3 at 510x, got %v", got)
+	}
+}
@@ -6,10 +6,24 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"math"
 )
 
-// GenerateReelStops uses HMAC-SHA256 to generate deterministic reel positions.
-func GenerateReelStops(serverSeed string, clientSeed string, nonce int64, reelLength int) ([]int, string, error) {
+type RNGScheme string
+
+const (
+	// RNGLegacy is the original derivation: a single HMAC-SHA256(server, "client:nonce")
+	// split into 4-byte chunks, each reduced modulo the reel length. It is biased
+	// and limited to 8 reels, and is kept only so old game versions replay exactly.
+	RNGLegacy RNGScheme = "hmac-sha256"
+	// RNGStream draws from Stream with rejection sampling. See Stream for the
+	// exact derivation.
+	RNGStream RNGScheme = "hmac-stream"
+)
+
+// GenerateReelStops uses HMAC-SHA256 to generate deterministic reel positions,
+// one per entry in reelLengths. This is the RNGLegacy derivation.
+func GenerateReelStops(serverSeed string, clientSeed string, nonce int64, reelLengths []int) ([]int, string, error) {
 	input := fmt.Sprintf("%s:%d", clientSeed, nonce)
 
 	serverKey, err := hex.DecodeString(serverSeed)
@@ -21,8 +35,12 @@ func GenerateReelStops(serverSeed string, clientSeed string, nonce int64, reelLe
 	h.Write([]byte(input))
 	hash := h.Sum(nil)
 
-	stops := make([]int, 3)
-	for i := 0; i < 3; i++ {
+	if len(reelLengths)*4 > len(hash) {
+		return nil, "", fmt.Errorf("too many reels: %d", len(reelLengths))
+	}
+
+	stops := make([]int, len(reelLengths))
+	for i, reelLength := range reelLengths {
 		chunk := hash[i*4 : (i+1)*4]
 
 		val := binary.BigEndian.Uint32(chunk)
@@ -32,3 +50,107 @@ func GenerateReelStops(serverSeed string, clientSeed string, nonce int64, reelLe
 
 	return stops, hex.EncodeToString(hash), nil
 }
+
+// Stream is a deterministic, unbounded byte stream for provably fair draws.
+//
+// The stream is the concatenation of 32-byte blocks
+//
+//	block[cursor] = HMAC-SHA256(key = hex_decode(server_seed),
+//	                            msg = "<client_seed>:<nonce>:<round>:<cursor>")
+//
+// for cursor = 0, 1, 2, ... with integers written in base 10. Integers are read
+// as big-endian uint32 values, 4 bytes at a time, in stream order.
+//
+// Intn(n) draws a uint32 v and accepts it only if v < 2^32 - (2^32 mod n),
+// returning v mod n; rejected values are discarded and the next uint32 is read.
+// This makes every result in [0, n) equally likely. Round separates independent
+// draws within one bet (e.g. a bonus round) so they never share bytes.
+type Stream struct {
+	mac        []byte
+	clientSeed string
+	nonce      int64
+	round      int
+	cursor     int
+	buf        []byte
+}
+
+// NewStream creates the stream for a given seed pair, nonce and round.
+func NewStream(serverSeed string, clientSeed string, nonce int64, round int) (*Stream, error) {
+	serverKey, err := hex.DecodeString(serverSeed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server seed hex: %w", err)
+	}
+	return &Stream{
+		mac:        serverKey,
+		clientSeed: clientSeed,
+		nonce:      nonce,
+		round:      round,
+	}, nil
+}
+
+// Block returns block[cursor] of the stream without advancing it.
+func (s *Stream) Block(cursor int) []byte {
+	h := hmac.New(sha256.New, s.mac)
+	fmt.Fprintf(h, "%s:%d:%d:%d", s.clientSeed, s.nonce, s.round, cursor)
+	return h.Sum(nil)
+}
+
+// Read fills p with the next bytes of the stream.
+func (s *Stream) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.buf) == 0 {
+			s.buf = s.Block(s.cursor)
+			s.cursor++
+		}
+		c := copy(p[n:], s.buf)
+		s.buf = s.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Uint32 reads the next big-endian uint32 from the stream.
+func (s *Stream) Uint32() uint32 {
+	var b [4]byte
+	s.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// Intn returns an unbiased integer in [0, n). It panics if n <= 0 or n > 2^32.
+func (s *Stream) Intn(n int) int {
+	if n <= 0 || uint64(n) > math.MaxUint32+1 {
+		panic(fmt.Sprintf("game: invalid Intn bound %d", n))
+	}
+	bound := uint64(n)
+	limit := (math.MaxUint32 + 1) - (math.MaxUint32+1)%bound
+	for {
+		v := uint64(s.Uint32())
+		if v < limit {
+			return int(v % bound)
+		}
+	}
+}
+
+// Float64 returns a uniform float in [0, 1) built from 52 bits of the stream.
+func (s *Stream) Float64() float64 {
+	var b [8]byte
+	s.Read(b[:])
+	return float64(binary.BigEndian.Uint64(b[:])>>12) / (1 << 52)
+}
+
+// DrawReelStops draws one unbiased stop per reel from round 0 of the stream.
+// It returns the stops and the hex encoding of the first stream block.
+func DrawReelStops(serverSeed string, clientSeed string, nonce int64, reelLengths []int) ([]int, string, error) {
+	stream, err := NewStream(serverSeed, clientSeed, nonce, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	stops := make([]int, len(reelLengths))
+	for i, reelLength := range reelLengths {
+		stops[i] = stream.Intn(reelLength)
+	}
+
+	return stops, hex.EncodeToString(stream.Block(0)), nil
+}
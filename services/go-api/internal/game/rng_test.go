@@ -0,0 +1,65 @@
+package game
+
+import (
+	"testing"
+)
+
+const testServerSeed = "a1a2c3d4e5f6a1f2c3d5e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+
+func TestStreamDeterministic(t *testing.T) {
+	a, err := NewStream(testServerSeed, "client", 7, 0)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	b, _ := NewStream(testServerSeed, "client", 7, 0)
+	other, _ := NewStream(testServerSeed, "client", 7, 1)
+
+	same := true
+	for i := 0; i < 100; i++ {
+		x, y, z := a.Uint32(), b.Uint32(), other.Uint32()
+		if x != y {
+			t.Fatalf("Draw %d differs between identical streams", i)
+		}
+		if x != z {
+			same = false
+		}
+	}
+	if same {
+		t.Errorf("Expected a different round to produce a different stream")
+	}
+}
+
+func TestDrawReelStopsManyReels(t *testing.T) {
+	lengths := make([]int, 40)
+	for i := range lengths {
+		lengths[i] = 7 + i
+	}
+
+	stops, digest, err := DrawReelStops(testServerSeed, "client", 1, lengths)
+	if err != nil {
+		t.Fatalf("Draw failed: %v", err)
+	}
+	if len(stops) != len(lengths) || len(digest) != 64 {
+		t.Fatalf("Expected %d stops and a 32 byte digest, got %d and %q", len(lengths), len(stops), digest)
+	}
+	for i, stop := range stops {
+		if stop < 0 || stop >= lengths[i] {
+			t.Errorf("Reel %d stop %d out of range [0, %d)", i, stop, lengths[i])
+		}
+	}
+}
+
+func TestIntnUniform(t *testing.T) {
+	stream, _ := NewStream(testServerSeed, "uniform", 1, 0)
+
+	const n, draws = 6, 60000
+	counts := make([]int, n)
+	for i := 0; i < draws; i++ {
+		counts[stream.Intn(n)]++
+	}
+	for face, c := range counts {
+		if c < draws/n*9/10 || c > draws/n*11/10 {
+			t.Errorf("Face %d drawn %d times, expected about %d", face, c, draws/n)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// Stats accumulates per-spin multipliers (payout / bet) so that RTP and
+// volatility can be derived for a game definition.
+type Stats struct {
+	Spins      int64
+	Hits       int64
+	Total      float64
+	SumSquares float64
+	MaxWin     float64
+
+	ByPayline map[int]float64    // -1 holds scatter returns
+	BySymbol  map[Symbol]float64 // return contributed by each paying symbol
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		ByPayline: make(map[int]float64),
+		BySymbol:  make(map[Symbol]float64),
+	}
+}
+
+// Add records a single spin with the given wins.
+func (s *Stats) Add(wins []Win) {
+	total := 0.0
+	for _, w := range wins {
+		total += w.Multiplier
+		s.ByPayline[w.Payline] += w.Multiplier
+		s.BySymbol[w.Symbol] += w.Multiplier
+	}
+
+	s.Spins++
+	s.Total += total
+	s.SumSquares += total * total
+	if total > 0 {
+		s.Hits++
+	}
+	if total > s.MaxWin {
+		s.MaxWin = total
+	}
+}
+
+// Merge folds other into s.
+func (s *Stats) Merge(other *Stats) {
+	s.Spins += other.Spins
+	s.Hits += other.Hits
+	s.Total += other.Total
+	s.SumSquares += other.SumSquares
+	if other.MaxWin > s.MaxWin {
+		s.MaxWin = other.MaxWin
+	}
+	for k, v := range other.ByPayline {
+		s.ByPayline[k] += v
+	}
+	for k, v := range other.BySymbol {
+		s.BySymbol[k] += v
+	}
+}
+
+// RTP is the mean multiplier returned per unit bet.
+func (s *Stats) RTP() float64 {
+	if s.Spins == 0 {
+		return 0
+	}
+	return s.Total / float64(s.Spins)
+}
+
+func (s *Stats) HitFrequency() float64 {
+	if s.Spins == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Spins)
+}
+
+// Variance of the per-spin multiplier.
+func (s *Stats) Variance() float64 {
+	if s.Spins == 0 {
+		return 0
+	}
+	mean := s.RTP()
+	return s.SumSquares/float64(s.Spins) - mean*mean
+}
+
+func (s *Stats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Combinations returns the number of distinct reel-stop combinations.
+func (d *Definition) Combinations() (int64, error) {
+	total := int64(1)
+	for _, length := range d.ReelLengths() {
+		if total > math.MaxInt64/int64(length) {
+			return 0, fmt.Errorf("game %s: reel-stop space overflows int64", d.ID)
+		}
+		total *= int64(length)
+	}
+	return total, nil
+}
+
+// ExactStats evaluates every reel-stop combination once, which gives the
+// theoretical RTP of the definition. The work is split across CPUs by the
+// stop of the first reel.
+func (d *Definition) ExactStats() *Stats {
+	lengths := d.ReelLengths()
+	jobs := make(chan int)
+	results := make(chan *Stats)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := NewStats()
+			stops := make([]int, len(lengths))
+			for first := range jobs {
+				stops[0] = first
+				d.enumerate(stops, 1, lengths, local)
+			}
+			results <- local
+		}()
+	}
+
+	go func() {
+		for first := 0; first < lengths[0]; first++ {
+			jobs <- first
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	total := NewStats()
+	for local := range results {
+		total.Merge(local)
+	}
+	return total
+}
+
+func (d *Definition) enumerate(stops []int, reel int, lengths []int, stats *Stats) {
+	if reel == len(lengths) {
+		stats.Add(d.Evaluate(d.Grid(stops)))
+		return
+	}
+	for stop := 0; stop < lengths[reel]; stop++ {
+		stops[reel] = stop
+		d.enumerate(stops, reel+1, lengths, stats)
+	}
+}
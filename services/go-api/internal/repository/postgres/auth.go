@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+)
+
+func (r *PostgresRepo) CreateAuthChallenge(ctx context.Context, challenge *domain.AuthChallenge) error {
+	query := `
+		INSERT INTO auth_challenges (nonce, wallet_address, message, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.db.Exec(ctx, query, challenge.Nonce, challenge.WalletAddress, challenge.Message, challenge.ExpiresAt)
+	return err
+}
+
+// ConsumeAuthChallenge marks an unexpired challenge as used and returns it.
+// A challenge can only be consumed once; nil is returned if it is unknown,
+// expired, already used or issued to a different wallet.
+func (r *PostgresRepo) ConsumeAuthChallenge(ctx context.Context, nonce string, walletAddress string) (*domain.AuthChallenge, error) {
+	query := `
+		UPDATE auth_challenges
+		SET used_at = NOW()
+		WHERE nonce = $1 AND wallet_address = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING nonce, wallet_address, message, expires_at, used_at
+	`
+	var c domain.AuthChallenge
+	err := r.db.QueryRow(ctx, query, nonce, walletAddress).Scan(
+		&c.Nonce, &c.WalletAddress, &c.Message, &c.ExpiresAt, &c.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *PostgresRepo) CreateAuthSession(ctx context.Context, tokenHash string, walletAddress string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO auth_sessions (token_hash, wallet_address, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	_, err := r.db.Exec(ctx, query, tokenHash, walletAddress, expiresAt)
+	return err
+}
+
+// GetAuthSessionWallet returns the wallet a token was issued to, or "" if the
+// token is unknown or expired.
+func (r *PostgresRepo) GetAuthSessionWallet(ctx context.Context, tokenHash string) (string, error) {
+	query := `SELECT wallet_address FROM auth_sessions WHERE token_hash = $1 AND expires_at > NOW()`
+	var wallet string
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&wallet)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return wallet, nil
+}
@@ -3,46 +3,155 @@ package postgres
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 )
 
-func (r *PostgresRepo) GetOpenBatch(ctx context.Context) (*domain.Batch, error) {
-	query := `SELECT batch_id, status, created_at FROM batches WHERE status = 'OPEN' ORDER BY created_at ASC LIMIT 1`
+const batchColumns = `
+	batch_id, status, tree_format, merkle_root, solana_tx_sig,
+	attempts, last_error, next_attempt_at, submitted_at, created_at, committed_at, committed_slot
+`
+
+func scanBatch(row pgx.Row) (*domain.Batch, error) {
 	var b domain.Batch
-	err := r.db.QueryRow(ctx, query).Scan(&b.BatchID, &b.Status, &b.CreatedAt)
+	err := row.Scan(
+		&b.BatchID, &b.Status, &b.TreeFormat, &b.MerkleRoot, &b.SolanaTxSig,
+		&b.Attempts, &b.LastError, &b.NextAttemptAt, &b.SubmittedAt, &b.CreatedAt, &b.CommittedAt, &b.CommittedSlot,
+	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
 	return &b, nil
 }
 
-func (r *PostgresRepo) CreateBatch(ctx context.Context) (*domain.Batch, error) {
-	query := `INSERT INTO batches (status) VALUES ('OPEN') RETURNING batch_id, status, created_at`
-	var b domain.Batch
-	err := r.db.QueryRow(ctx, query).Scan(&b.BatchID, &b.Status, &b.CreatedAt)
+// GetPendingBatches returns OPEN and SUBMITTED batches that are due for another attempt.
+func (r *PostgresRepo) GetPendingBatches(ctx context.Context) ([]domain.Batch, error) {
+	query := `SELECT ` + batchColumns + `
+		FROM batches
+		WHERE status IN ('OPEN', 'SUBMITTED') AND next_attempt_at <= NOW()
+		ORDER BY batch_id ASC
+	`
+	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	return &b, nil
+	defer rows.Close()
+
+	var batches []domain.Batch
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *b)
+	}
+	return batches, rows.Err()
 }
 
-func (r *PostgresRepo) AddSpinToBatch(ctx context.Context, spinID string, batchID int64) error {
-	query := `UPDATE spins SET batch_id = $1 WHERE spin_id = $2`
-	_, err := r.db.Exec(ctx, query, batchID, spinID)
-	return err
+// CreateBatch opens a batch and links the given spins to it atomically, so a
+// crash can never leave spins attached to a batch that does not know about them.
+func (r *PostgresRepo) CreateBatch(ctx context.Context, treeFormat int, spinIDs []string) (*domain.Batch, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO batches (status, tree_format) VALUES ('OPEN', $1) RETURNING ` + batchColumns
+	b, err := scanBatch(tx.QueryRow(ctx, query, treeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE spins SET batch_id = $1 WHERE spin_id = ANY($2::uuid[]) AND batch_id IS NULL`, b.BatchID, spinIDs)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() != int64(len(spinIDs)) {
+		return nil, errors.New("some spins were already batched")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return b, nil
 }
 
-func (r *PostgresRepo) CloseBatch(ctx context.Context, batchID int64, merkleRoot string, txSig string) error {
+// MarkBatchSubmitted records a sent commit transaction. Attempts are counted
+// by RecordBatchFailure alone, so a send that then fails counts once.
+func (r *PostgresRepo) MarkBatchSubmitted(ctx context.Context, batchID int64, merkleRoot string, txSig string) error {
 	query := `
-		UPDATE batches 
-		SET status = 'COMMITTED', merkle_root = $1, solana_tx_sig = $2, committed_at = NOW() 
+		UPDATE batches
+		SET status = 'SUBMITTED', merkle_root = $1, solana_tx_sig = $2,
+		    last_error = NULL, submitted_at = NOW()
 		WHERE batch_id = $3
 	`
 	_, err := r.db.Exec(ctx, query, merkleRoot, txSig, batchID)
 	return err
 }
+
+// RecordBatchFailure stores the error and schedules the next attempt. A final
+// failure moves the batch to FAILED, where it is no longer retried.
+func (r *PostgresRepo) RecordBatchFailure(ctx context.Context, batchID int64, reason string, retryIn time.Duration, final bool) error {
+	query := `
+		UPDATE batches
+		SET attempts = attempts + 1,
+		    last_error = $1,
+		    next_attempt_at = NOW() + make_interval(secs => $2),
+		    status = CASE WHEN $3 THEN 'FAILED' ELSE status END
+		WHERE batch_id = $4
+	`
+	_, err := r.db.Exec(ctx, query, reason, retryIn.Seconds(), final, batchID)
+	return err
+}
+
+// CloseBatch stores every spin's proof and marks the batch committed in one
+// transaction. A zero slot is stored as NULL (landed slot unknown).
+func (r *PostgresRepo) CloseBatch(ctx context.Context, batchID int64, merkleRoot string, txSig string, slot uint64, proofs []domain.MerkleProof) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows := make([][]interface{}, 0, len(proofs))
+	for _, p := range proofs {
+		rows = append(rows, []interface{}{p.SpinID, p.BatchID, p.LeafIndex, p.Proof})
+	}
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"merkle_proofs"},
+		[]string{"spin_id", "batch_id", "leaf_index", "proof_hashes"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE batches 
+		SET status = 'COMMITTED', merkle_root = $1, solana_tx_sig = $2, committed_at = NOW(),
+		    committed_slot = NULLIF($3, 0), last_error = NULL
+		WHERE batch_id = $4
+	`
+	_, err = tx.Exec(ctx, query, merkleRoot, txSig, int64(slot), batchID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepo) GetMerkleProof(ctx context.Context, spinID string) (*domain.MerkleProof, error) {
+	query := `SELECT spin_id, batch_id, leaf_index, proof_hashes FROM merkle_proofs WHERE spin_id = $1`
+	var p domain.MerkleProof
+	err := r.db.QueryRow(ctx, query, spinID).Scan(&p.SpinID, &p.BatchID, &p.LeafIndex, &p.Proof)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
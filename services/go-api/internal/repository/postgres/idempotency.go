@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+)
+
+// inFlightTimeout bounds how long a request can hold its idempotency key
+// without finishing; every request finishes well within it.
+const inFlightTimeout = 5 * time.Minute
+
+// ReserveIdempotencyKey claims a key for a request. reserved is true when the
+// caller should run the request; otherwise the earlier record is returned.
+// Keys older than a day can be reused, as can a reservation left without a
+// response for longer than inFlightTimeout by a process that died mid-request.
+func (r *PostgresRepo) ReserveIdempotencyKey(ctx context.Context, walletAddress string, key string, requestHash string) (*domain.IdempotencyRecord, bool, error) {
+	reserveQuery := `
+		INSERT INTO idempotency_keys (wallet_address, idempotency_key, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (wallet_address, idempotency_key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, response_status = NULL, response_body = NULL,
+		    created_at = NOW(), completed_at = NULL
+		WHERE idempotency_keys.created_at < NOW() - INTERVAL '24 hours'
+		   OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $4))
+		RETURNING wallet_address
+	`
+	var reserved string
+	err := r.db.QueryRow(ctx, reserveQuery, walletAddress, key, requestHash, inFlightTimeout.Seconds()).Scan(&reserved)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+
+	query := `
+		SELECT wallet_address, idempotency_key, request_hash, response_status, response_body, created_at, completed_at
+		FROM idempotency_keys
+		WHERE wallet_address = $1 AND idempotency_key = $2
+	`
+	var rec domain.IdempotencyRecord
+	err = r.db.QueryRow(ctx, query, walletAddress, key).Scan(
+		&rec.WalletAddress, &rec.Key, &rec.RequestHash, &rec.ResponseStatus, &rec.ResponseBody, &rec.CreatedAt, &rec.CompletedAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	return &rec, false, nil
+}
+
+func (r *PostgresRepo) CompleteIdempotencyKey(ctx context.Context, walletAddress string, key string, status int, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET response_status = $1, response_body = $2, completed_at = NOW()
+		WHERE wallet_address = $3 AND idempotency_key = $4
+	`
+	_, err := r.db.Exec(ctx, query, status, body, walletAddress, key)
+	return err
+}
+
+// ReleaseIdempotencyKey forgets a reservation whose request did not produce a
+// result worth replaying, so the client may retry with the same key.
+func (r *PostgresRepo) ReleaseIdempotencyKey(ctx context.Context, walletAddress string, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE wallet_address = $1 AND idempotency_key = $2 AND completed_at IS NULL`
+	_, err := r.db.Exec(ctx, query, walletAddress, key)
+	return err
+}
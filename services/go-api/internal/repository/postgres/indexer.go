@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetIndexerCursor returns the last signature an indexer has fully processed,
+// or "" if it has never run.
+func (r *PostgresRepo) GetIndexerCursor(ctx context.Context, name string) (string, error) {
+	var sig string
+	err := r.db.QueryRow(ctx, `SELECT last_signature FROM indexer_cursors WHERE name = $1`, name).Scan(&sig)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return sig, nil
+}
+
+func (r *PostgresRepo) SetIndexerCursor(ctx context.Context, name string, signature string, slot uint64) error {
+	query := `
+		INSERT INTO indexer_cursors (name, last_signature, last_slot, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET last_signature = EXCLUDED.last_signature, last_slot = EXCLUDED.last_slot, updated_at = NOW()
+	`
+	_, err := r.db.Exec(ctx, query, name, signature, int64(slot))
+	return err
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/shopspring/decimal"
+)
+
+// ledgerLeg is one side of a balance movement.
+type ledgerLeg struct {
+	account string
+	amount  decimal.Decimal
+}
+
+// ledgerSource links a journal to the row that caused it.
+type ledgerSource struct {
+	txSig  *string
+	spinID *uuid.UUID
+	nonce  *int64
+}
+
+// postJournal writes a balanced set of legs for one wallet inside tx. Every
+// balance mutation in the repository goes through here, in the same
+// transaction as the balance update it describes.
+func postJournal(ctx context.Context, tx pgx.Tx, walletAddress string, entryType string, src ledgerSource, legs ...ledgerLeg) error {
+	sum := decimal.Zero
+	for _, leg := range legs {
+		sum = sum.Add(leg.amount)
+	}
+	if !sum.IsZero() {
+		return fmt.Errorf("unbalanced %s journal for %s: legs sum to %s", entryType, walletAddress, sum)
+	}
+
+	journalID := uuid.New()
+	query := `
+		INSERT INTO ledger_entries (
+			journal_id, account, wallet_address, entry_type, amount,
+			source_tx_sig, source_spin_id, source_nonce
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	batch := &pgx.Batch{}
+	for _, leg := range legs {
+		batch.Queue(query, journalID, leg.account, walletAddress, entryType, leg.amount, src.txSig, src.spinID, src.nonce)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// transfer books amount moving from one account to another.
+func transfer(from string, to string, amount decimal.Decimal) []ledgerLeg {
+	return []ledgerLeg{
+		{account: from, amount: amount.Neg()},
+		{account: to, amount: amount},
+	}
+}
+
+// ReconcileLedger compares every wallet's ledger totals with its stored
+// balances (see the ledger_reconciliation view) and checks that each journal
+// sums to zero.
+func (r *PostgresRepo) ReconcileLedger(ctx context.Context) (*domain.LedgerReport, error) {
+	report := &domain.LedgerReport{}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT wallet_address, ledger_balance, stored_balance, ledger_pending, stored_pending
+		FROM ledger_reconciliation
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m domain.LedgerMismatch
+		if err := rows.Scan(&m.WalletAddress, &m.LedgerBalance, &m.StoredBalance, &m.LedgerPending, &m.StoredPending); err != nil {
+			return nil, err
+		}
+		report.Wallets++
+		if !m.LedgerBalance.Equal(m.StoredBalance) || !m.LedgerPending.Equal(m.StoredPending) {
+			report.Mismatches = append(report.Mismatches, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	journalRows, err := r.db.Query(ctx, `
+		SELECT journal_id FROM ledger_entries GROUP BY journal_id HAVING SUM(amount) <> 0
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer journalRows.Close()
+
+	for journalRows.Next() {
+		var id uuid.UUID
+		if err := journalRows.Scan(&id); err != nil {
+			return nil, err
+		}
+		report.UnbalancedJournals = append(report.UnbalancedJournals, id)
+	}
+	return report, journalRows.Err()
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+)
+
+// reconciliationRetention is how long reports are kept.
+const reconciliationRetention = "7 days"
+
+// GetWalletPositions returns deposits, withdrawals and balances of every user.
+func (r *PostgresRepo) GetWalletPositions(ctx context.Context) ([]domain.WalletPosition, error) {
+	query := `
+		WITH deposited AS (SELECT wallet_address, SUM(amount_lamports) AS lamports
+		                   FROM processed_deposits
+		                   GROUP BY wallet_address),
+		     withdrawn AS (SELECT wallet_address,
+		                          COALESCE(SUM(amount) FILTER (WHERE status = $1), 0) AS confirmed,
+		                          COALESCE(SUM(amount) FILTER (WHERE status = $2), 0) AS pending,
+		                          COALESCE(MAX(nonce) FILTER (WHERE status = $1), 0)  AS last_confirmed,
+		                          MIN(nonce) FILTER (WHERE status = $2)               AS first_signed
+		                   FROM withdrawals
+		                   GROUP BY wallet_address)
+		SELECT u.wallet_address,
+		       u.next_withdrawal_nonce,
+		       (COALESCE(d.lamports, 0) / 1000000000.0)::DECIMAL(20, 9),
+		       COALESCE(w.confirmed, 0),
+		       COALESCE(w.pending, 0),
+		       COALESCE(a.playable_balance, 0),
+		       COALESCE(w.last_confirmed, 0),
+		       w.first_signed
+		FROM users u
+		         LEFT JOIN deposited d ON d.wallet_address = u.wallet_address
+		         LEFT JOIN withdrawn w ON w.wallet_address = u.wallet_address
+		         LEFT JOIN accounts a ON a.wallet_address = u.wallet_address
+		ORDER BY u.wallet_address
+	`
+	rows, err := r.db.Query(ctx, query, domain.WithdrawalStatusConfirmed, domain.WithdrawalStatusSigned)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []domain.WalletPosition
+	for rows.Next() {
+		var p domain.WalletPosition
+		if err := rows.Scan(
+			&p.WalletAddress, &p.NextWithdrawalNonce, &p.Deposited, &p.Withdrawn,
+			&p.Pending, &p.PlayableBalance, &p.LastConfirmedNonce, &p.FirstSignedNonce,
+		); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, rows.Err()
+}
+
+// SaveReconciliation stores a report with all its items and drops reports
+// older than reconciliationRetention.
+func (r *PostgresRepo) SaveReconciliation(ctx context.Context, run *domain.ReconciliationRun) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO reconciliation_runs (
+			wallets, discrepancies, onchain_total, liabilities, vault_liquidity, liabilities_covered
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING run_id, created_at
+	`, run.Wallets, run.Discrepancies, run.OnChainTotal, run.Liabilities, run.VaultLiquidity, run.LiabilitiesCovered,
+	).Scan(&run.RunID, &run.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO reconciliation_items (
+			run_id, wallet_address, onchain_amount, onchain_last_nonce, deposited, withdrawn,
+			playable_balance, pending_withdrawals, next_withdrawal_nonce, drift, issues
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	batch := &pgx.Batch{}
+	for _, item := range run.Items {
+		batch.Queue(query, run.RunID, item.WalletAddress, item.OnChainAmount, item.OnChainLastNonce,
+			item.Deposited, item.Withdrawn, item.PlayableBalance, item.PendingWithdrawals,
+			item.NextWithdrawalNonce, item.Drift, item.Issues)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM reconciliation_runs WHERE created_at < NOW() - $1::INTERVAL`, reconciliationRetention)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetLatestReconciliation returns the newest report, nil if there is none.
+// With discrepanciesOnly, items without issues are left out.
+func (r *PostgresRepo) GetLatestReconciliation(ctx context.Context, discrepanciesOnly bool) (*domain.ReconciliationRun, error) {
+	var run domain.ReconciliationRun
+	err := r.db.QueryRow(ctx, `
+		SELECT run_id, wallets, discrepancies, onchain_total, liabilities, vault_liquidity,
+		       liabilities_covered, created_at
+		FROM reconciliation_runs
+		ORDER BY run_id DESC
+		LIMIT 1
+	`).Scan(&run.RunID, &run.Wallets, &run.Discrepancies, &run.OnChainTotal, &run.Liabilities,
+		&run.VaultLiquidity, &run.LiabilitiesCovered, &run.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT wallet_address, onchain_amount, onchain_last_nonce, deposited, withdrawn,
+		       playable_balance, pending_withdrawals, next_withdrawal_nonce, drift, issues
+		FROM reconciliation_items
+		WHERE run_id = $1 AND (NOT $2 OR cardinality(issues) > 0)
+		ORDER BY wallet_address
+	`, run.RunID, discrepanciesOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	run.Items = []domain.ReconciliationItem{}
+	for rows.Next() {
+		var item domain.ReconciliationItem
+		if err := rows.Scan(
+			&item.WalletAddress, &item.OnChainAmount, &item.OnChainLastNonce, &item.Deposited, &item.Withdrawn,
+			&item.PlayableBalance, &item.PendingWithdrawals, &item.NextWithdrawalNonce, &item.Drift, &item.Issues,
+		); err != nil {
+			return nil, err
+		}
+		run.Items = append(run.Items, item)
+	}
+	return &run, rows.Err()
+}
@@ -3,25 +3,90 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
 )
 
-func (r *PostgresRepo) CreateSpin(ctx context.Context, spin *domain.Spin) error {
-	query := `
+// PlaySpin runs a whole spin in one transaction: the active session and the
+// wallet's account are locked, play is called with the next nonce, then the spin
+// is inserted, the account balance is settled and the session's nonce and seed
+// are advanced together. Concurrent spins on the same wallet are serialized, so
+// a nonce or server seed is never reused and the balance cannot go negative.
+func (r *PostgresRepo) PlaySpin(ctx context.Context, walletAddress string, play repository.SpinFunc) (*domain.Spin, *domain.Session, *domain.Account, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	lockQuery := `
+		SELECT session_id, wallet_address, next_server_seed, next_server_seed_hash, spin_nonce, is_active, created_at
+		FROM sessions
+		WHERE wallet_address = $1 AND is_active = TRUE
+		LIMIT 1
+		FOR UPDATE
+	`
+	var session domain.Session
+	err = tx.QueryRow(ctx, lockQuery, walletAddress).Scan(
+		&session.SessionID,
+		&session.WalletAddress,
+		&session.NextServerSeed,
+		&session.NextServerSeedHash,
+		&session.SpinNonce,
+		&session.IsActive,
+		&session.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, nil, domain.ErrSessionInactive
+		}
+		return nil, nil, nil, err
+	}
+
+	if err := ensureAccount(ctx, tx, walletAddress); err != nil {
+		return nil, nil, nil, err
+	}
+	var account domain.Account
+	err = tx.QueryRow(ctx, `
+		SELECT wallet_address, playable_balance, updated_at
+		FROM accounts
+		WHERE wallet_address = $1
+		FOR UPDATE
+	`, walletAddress).Scan(&account.WalletAddress, &account.PlayableBalance, &account.UpdatedAt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	spinNonce := session.SpinNonce + 1
+	spin, nextSeed, nextHash, err := play(&session, &account, spinNonce)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	newBalance := account.PlayableBalance.Sub(spin.BetAmount).Add(spin.PayoutAmount)
+	if newBalance.IsNegative() {
+		return nil, nil, nil, domain.ErrInsufficientFunds
+	}
+
+	insertQuery := `
 		INSERT INTO spins (
 			spin_id, session_id, wallet_address, spin_nonce,
+			game_id, game_version,
 			server_seed, client_seed, server_seed_hash,
-			bet_amount, payout_amount, outcome_json, leaf_hash
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			bet_amount, payout_amount, outcome_json, leaf_hash, leaf_format
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
-	_, err := r.db.Exec(ctx, query,
+	_, err = tx.Exec(ctx, insertQuery,
 		spin.SpinID,
-		spin.SessionID,
+		session.SessionID,
 		spin.WalletAddress,
-		spin.SpinNonce,
+		spinNonce,
+		spin.GameID,
+		spin.GameVersion,
 		spin.ServerSeed,
 		spin.ClientSeed,
 		spin.ServerSeedHash,
@@ -29,13 +94,60 @@ func (r *PostgresRepo) CreateSpin(ctx context.Context, spin *domain.Spin) error
 		spin.PayoutAmount,
 		spin.Outcome,
 		spin.LeafHash,
+		spin.LeafFormat,
 	)
-	return err
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = postJournal(ctx, tx, walletAddress, domain.LedgerBet, ledgerSource{spinID: &spin.SpinID},
+		transfer(domain.LedgerAccountPlayer, domain.LedgerAccountHouse, spin.BetAmount)...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if spin.PayoutAmount.IsPositive() {
+		err = postJournal(ctx, tx, walletAddress, domain.LedgerPayout, ledgerSource{spinID: &spin.SpinID},
+			transfer(domain.LedgerAccountHouse, domain.LedgerAccountPlayer, spin.PayoutAmount)...)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE accounts
+		SET playable_balance = $1, updated_at = NOW()
+		WHERE wallet_address = $2
+		RETURNING updated_at
+	`, newBalance, walletAddress).Scan(&account.UpdatedAt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	updateQuery := `
+		UPDATE sessions
+		SET spin_nonce = $1, next_server_seed = $2, next_server_seed_hash = $3
+		WHERE session_id = $4
+	`
+	_, err = tx.Exec(ctx, updateQuery, spinNonce, nextSeed, nextHash, session.SessionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+
+	account.PlayableBalance = newBalance
+	session.SpinNonce = spinNonce
+	session.NextServerSeed = nextSeed
+	session.NextServerSeedHash = nextHash
+	return spin, &session, &account, nil
 }
 
 func (r *PostgresRepo) GetSpinsByWallet(ctx context.Context, walletAddress string, limit int, offset int) ([]domain.Spin, error) {
 	query := `
 		SELECT spin_id, session_id, wallet_address, spin_nonce,
+		       game_id, game_version,
 		       server_seed, client_seed, server_seed_hash,
 		       bet_amount, payout_amount, outcome_json, leaf_hash, batch_id, created_at
 		FROM spins
@@ -54,6 +166,7 @@ func (r *PostgresRepo) GetSpinsByWallet(ctx context.Context, walletAddress strin
 		var s domain.Spin
 		err := rows.Scan(
 			&s.SpinID, &s.SessionID, &s.WalletAddress, &s.SpinNonce,
+			&s.GameID, &s.GameVersion,
 			&s.ServerSeed, &s.ClientSeed, &s.ServerSeedHash,
 			&s.BetAmount, &s.PayoutAmount, &s.Outcome, &s.LeafHash, &s.BatchID, &s.CreatedAt,
 		)
@@ -65,24 +178,12 @@ func (r *PostgresRepo) GetSpinsByWallet(ctx context.Context, walletAddress strin
 	return spins, nil
 }
 
-func (r *PostgresRepo) GetSpinCount(ctx context.Context, sessionID uuid.UUID) (int64, error) {
-	query := `SELECT COUNT(*) FROM spins WHERE session_id = $1`
-
-	var count int64
-	err := r.db.QueryRow(ctx, query, sessionID).Scan(&count)
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
 func (r *PostgresRepo) GetUnbatchedSpins(ctx context.Context, limit int) ([]domain.Spin, error) {
 	query := `
-			SELECT spin_id, leaf_hash 
+			SELECT spin_id, leaf_hash, leaf_format
 			FROM spins 
 			WHERE batch_id IS NULL 
-			ORDER BY created_at 
+			ORDER BY created_at, spin_id
 			LIMIT $1
 		`
 	rows, err := r.db.Query(ctx, query, limit)
@@ -94,7 +195,7 @@ func (r *PostgresRepo) GetUnbatchedSpins(ctx context.Context, limit int) ([]doma
 	var spins []domain.Spin
 	for rows.Next() {
 		var s domain.Spin
-		if err := rows.Scan(&s.SpinID, &s.LeafHash); err != nil {
+		if err := rows.Scan(&s.SpinID, &s.LeafHash, &s.LeafFormat); err != nil {
 			return nil, err
 		}
 		spins = append(spins, s)
@@ -103,7 +204,7 @@ func (r *PostgresRepo) GetUnbatchedSpins(ctx context.Context, limit int) ([]doma
 }
 
 func (r *PostgresRepo) GetBatchSpins(ctx context.Context, batchID int64) ([]domain.Spin, error) {
-	query := `SELECT spin_id, leaf_hash FROM spins WHERE batch_id = $1 ORDER BY created_at ASC`
+	query := `SELECT spin_id, leaf_hash FROM spins WHERE batch_id = $1 ORDER BY created_at ASC, spin_id ASC`
 	rows, err := r.db.Query(ctx, query, batchID)
 	if err != nil {
 		return nil, err
@@ -122,19 +223,15 @@ func (r *PostgresRepo) GetBatchSpins(ctx context.Context, batchID int64) ([]doma
 }
 
 func (r *PostgresRepo) GetBatch(ctx context.Context, batchID int64) (*domain.Batch, error) {
-	query := `SELECT batch_id, status, merkle_root, solana_tx_sig FROM batches WHERE batch_id = $1`
-	var b domain.Batch
-	err := r.db.QueryRow(ctx, query, batchID).Scan(&b.BatchID, &b.Status, &b.MerkleRoot, &b.SolanaTxSig)
-	if err != nil {
-		return nil, err
-	}
-	return &b, nil
+	query := `SELECT ` + batchColumns + ` FROM batches WHERE batch_id = $1`
+	return scanBatch(r.db.QueryRow(ctx, query, batchID))
 }
 
 func (r *PostgresRepo) GetSpin(ctx context.Context, spinID string) (*domain.Spin, error) {
 	query := `
 		SELECT 
 			spin_id, session_id, wallet_address, spin_nonce,
+			game_id, game_version,
 			server_seed, client_seed, server_seed_hash,
 			bet_amount, payout_amount, outcome_json, leaf_hash, batch_id, created_at
 		FROM spins
@@ -149,6 +246,8 @@ func (r *PostgresRepo) GetSpin(ctx context.Context, spinID string) (*domain.Spin
 		&s.SessionID,
 		&s.WalletAddress,
 		&s.SpinNonce,
+		&s.GameID,
+		&s.GameVersion,
 		&s.ServerSeed,
 		&s.ClientSeed,
 		&s.ServerSeedHash,
@@ -9,139 +9,97 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+// CreateUser registers a wallet together with its (empty) balance account.
 func (r *PostgresRepo) CreateUser(ctx context.Context, walletAddress string) error {
-	query := `
-		INSERT INTO users (wallet_address, next_withdrawal_nonce)
-		VALUES ($1, 1)
-		ON CONFLICT (wallet_address) DO NOTHING
-	`
-	_, err := r.db.Exec(ctx, query, walletAddress)
-	return err
-}
-
-func (r *PostgresRepo) GetUser(ctx context.Context, walletAddress string) (*domain.User, error) {
-	query := `
-		SELECT wallet_address, next_withdrawal_nonce, pending_withdrawal_amount, pending_withdrawal_signature, created_at
-		FROM users
-		WHERE wallet_address = $1
-	`
-	var user domain.User
-	err := r.db.QueryRow(ctx, query, walletAddress).Scan(
-		&user.WalletAddress,
-		&user.NextWithdrawalNonce,
-		&user.PendingWithdrawalAmount,
-		&user.PendingWithdrawalSignature,
-		&user.CreatedAt,
-	)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &user, nil
-}
-
-func (r *PostgresRepo) SetPendingWithdrawal(ctx context.Context, walletAddress string, amount decimal.Decimal, signature string) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	querySession := `
-		UPDATE sessions 
-		SET playable_balance = playable_balance - $1 
-		WHERE wallet_address = $2 AND is_active = TRUE AND playable_balance >= $1
-	`
-	tag, err := tx.Exec(ctx, querySession, amount, walletAddress)
-	if err != nil {
+	if err := ensureAccount(ctx, tx, walletAddress); err != nil {
 		return err
 	}
-	if tag.RowsAffected() == 0 {
-		return errors.New("insufficient funds or no active session")
-	}
+	return tx.Commit(ctx)
+}
 
-	queryUser := `
-		UPDATE users 
-		SET pending_withdrawal_amount = $1,
-		    pending_withdrawal_signature = $2,
-		    next_withdrawal_nonce = next_withdrawal_nonce + 1
-		WHERE wallet_address = $3
-	`
-	_, err = tx.Exec(ctx, queryUser, amount, signature, walletAddress)
+// ensureAccount creates the user and account rows for a wallet if missing.
+func ensureAccount(ctx context.Context, tx pgx.Tx, walletAddress string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO users (wallet_address, next_withdrawal_nonce)
+		VALUES ($1, 1)
+		ON CONFLICT (wallet_address) DO NOTHING
+	`, walletAddress)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit(ctx)
-}
-
-func (r *PostgresRepo) CompleteWithdrawal(ctx context.Context, walletAddress string) error {
-	query := `
-		UPDATE users 
-		SET pending_withdrawal_amount = 0,
-		    pending_withdrawal_signature = ''
-		WHERE wallet_address = $1
-	`
-	_, err := r.db.Exec(ctx, query, walletAddress)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO accounts (wallet_address)
+		VALUES ($1)
+		ON CONFLICT (wallet_address) DO NOTHING
+	`, walletAddress)
 	return err
 }
 
-func (r *PostgresRepo) RefundWithdrawal(ctx context.Context, walletAddress string, amount decimal.Decimal, correctNextNonce int64, fallbackSession *domain.Session) error {
-	tx, err := r.db.Begin(ctx)
+// GetAccount returns the wallet's balance account, or nil if it has none yet.
+func (r *PostgresRepo) GetAccount(ctx context.Context, walletAddress string) (*domain.Account, error) {
+	query := `SELECT wallet_address, playable_balance, updated_at FROM accounts WHERE wallet_address = $1`
+	var a domain.Account
+	err := r.db.QueryRow(ctx, query, walletAddress).Scan(&a.WalletAddress, &a.PlayableBalance, &a.UpdatedAt)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
 	}
-	defer tx.Rollback(ctx)
+	return &a, nil
+}
 
-	queryUser := `
-		UPDATE users 
-		SET pending_withdrawal_amount = 0,
-		    pending_withdrawal_signature = '',
-		    next_withdrawal_nonce = $1
-		WHERE wallet_address = $2
+// GetLiabilities returns what the casino owes players: every playable balance
+// plus the withdrawals that were signed but not executed yet.
+func (r *PostgresRepo) GetLiabilities(ctx context.Context) (decimal.Decimal, error) {
+	query := `
+		SELECT (SELECT COALESCE(SUM(playable_balance), 0) FROM accounts)
+		     + (SELECT COALESCE(SUM(amount), 0) FROM withdrawals WHERE status = $1)
 	`
-	_, err = tx.Exec(ctx, queryUser, correctNextNonce, walletAddress)
-	if err != nil {
+	var liabilities decimal.Decimal
+	err := r.db.QueryRow(ctx, query, domain.WithdrawalStatusSigned).Scan(&liabilities)
+	return liabilities, err
+}
+
+// creditAccount adds amount to the wallet's playable balance.
+func creditAccount(ctx context.Context, tx pgx.Tx, walletAddress string, amount decimal.Decimal) error {
+	if err := ensureAccount(ctx, tx, walletAddress); err != nil {
 		return err
 	}
+	_, err := tx.Exec(ctx, `
+		UPDATE accounts
+		SET playable_balance = playable_balance + $1, updated_at = NOW()
+		WHERE wallet_address = $2
+	`, amount, walletAddress)
+	return err
+}
 
-	querySession := `
-		UPDATE sessions 
-		SET playable_balance = playable_balance + $1 
-		WHERE wallet_address = $2 AND is_active = TRUE
+func (r *PostgresRepo) GetUser(ctx context.Context, walletAddress string) (*domain.User, error) {
+	query := `
+		SELECT wallet_address, next_withdrawal_nonce, created_at
+		FROM users
+		WHERE wallet_address = $1
 	`
-	tag, err := tx.Exec(ctx, querySession, amount, walletAddress)
+	var user domain.User
+	err := r.db.QueryRow(ctx, query, walletAddress).Scan(
+		&user.WalletAddress,
+		&user.NextWithdrawalNonce,
+		&user.CreatedAt,
+	)
 	if err != nil {
-		return err
-	}
-
-	if tag.RowsAffected() == 0 {
-		_, err = tx.Exec(ctx, `UPDATE sessions SET is_active = FALSE WHERE wallet_address = $1`, walletAddress)
-		if err != nil {
-			return err
-		}
-
-		queryInsert := `
-			INSERT INTO sessions (
-				session_id, wallet_address, playable_balance, 
-				next_server_seed, next_server_seed_hash, is_active
-			) VALUES ($1, $2, $3, $4, $5, TRUE)
-		`
-		_, err = tx.Exec(ctx, queryInsert,
-			fallbackSession.SessionID,
-			fallbackSession.WalletAddress,
-			amount,
-			fallbackSession.NextServerSeed,
-			fallbackSession.NextServerSeedHash,
-		)
-		if err != nil {
-			return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
 		}
+		return nil, err
 	}
-
-	return tx.Commit(ctx)
+	return &user, nil
 }
 
 func (r *PostgresRepo) GetNextWithdrawalNonce(ctx context.Context, walletAddress string) (int64, error) {
@@ -161,66 +119,55 @@ func (r *PostgresRepo) IncrementWithdrawalNonce(ctx context.Context, walletAddre
 	return err
 }
 
-func (r *PostgresRepo) CheckDepositProcessed(ctx context.Context, txSig string) (bool, error) {
+func (r *PostgresRepo) CheckDepositProcessed(ctx context.Context, txSig string, ixIndex int) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM processed_deposits WHERE tx_sig = $1)`
-	err := r.db.QueryRow(ctx, query, txSig).Scan(&exists)
+	query := `SELECT EXISTS(SELECT 1 FROM processed_deposits WHERE tx_sig = $1 AND ix_index IN ($2, $3))`
+	err := r.db.QueryRow(ctx, query, txSig, ixIndex, legacyDepositIxIndex).Scan(&exists)
 	return exists, err
 }
 
-func (r *PostgresRepo) RecordDeposit(ctx context.Context, txSig string, walletAddress string, amount uint64, fallbackSession *domain.Session) error {
+// legacyDepositIxIndex marks a deposit row written before deposits were keyed
+// by instruction. Those were credited once per transaction, so the row covers
+// every instruction of it.
+const legacyDepositIxIndex = -1
+
+// RecordDeposit credits one deposit instruction. Deposits are keyed by tx and
+// instruction index; crediting the same one twice returns domain.ErrDepositProcessed.
+// A transaction with a legacy row counts as processed as a whole.
+func (r *PostgresRepo) RecordDeposit(ctx context.Context, txSig string, ixIndex int, walletAddress string, amount uint64) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, `
-		INSERT INTO users (wallet_address, next_withdrawal_nonce)
-		VALUES ($1, 1)
-		ON CONFLICT (wallet_address) DO NOTHING
-	`, walletAddress)
-	if err != nil {
+	if err := ensureAccount(ctx, tx, walletAddress); err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, `
-		INSERT INTO processed_deposits (tx_sig, wallet_address, amount_lamports)
-		VALUES ($1, $2, $3)
-	`, txSig, walletAddress, amount)
+	inserted, err := tx.Exec(ctx, `
+		INSERT INTO processed_deposits (tx_sig, ix_index, wallet_address, amount_lamports)
+		SELECT $1::VARCHAR, $2::INT, $3::VARCHAR, $4::BIGINT
+		WHERE NOT EXISTS (SELECT 1 FROM processed_deposits WHERE tx_sig = $1 AND ix_index = $5)
+		ON CONFLICT (tx_sig, ix_index) DO NOTHING
+	`, txSig, ixIndex, walletAddress, amount, legacyDepositIxIndex)
 	if err != nil {
 		return err
 	}
+	if inserted.RowsAffected() == 0 {
+		return domain.ErrDepositProcessed
+	}
 
 	amountSol := decimal.NewFromInt(int64(amount)).Div(decimal.NewFromInt(1_000_000_000))
 
-	queryUpdate := `
-		UPDATE sessions 
-		SET playable_balance = playable_balance + $1 
-		WHERE wallet_address = $2 AND is_active = TRUE
-	`
-	tag, err := tx.Exec(ctx, queryUpdate, amountSol, walletAddress)
+	err = postJournal(ctx, tx, walletAddress, domain.LedgerDeposit, ledgerSource{txSig: &txSig},
+		transfer(domain.LedgerAccountVault, domain.LedgerAccountPlayer, amountSol)...)
 	if err != nil {
 		return err
 	}
 
-	if tag.RowsAffected() == 0 {
-		queryInsert := `
-			INSERT INTO sessions (
-				session_id, wallet_address, playable_balance, 
-				next_server_seed, next_server_seed_hash, is_active
-			) VALUES ($1, $2, $3, $4, $5, TRUE)
-		`
-		_, err = tx.Exec(ctx, queryInsert,
-			fallbackSession.SessionID,
-			fallbackSession.WalletAddress,
-			amountSol,
-			fallbackSession.NextServerSeed,
-			fallbackSession.NextServerSeedHash,
-		)
-		if err != nil {
-			return err
-		}
+	if err := creditAccount(ctx, tx, walletAddress, amountSol); err != nil {
+		return err
 	}
 
 	return tx.Commit(ctx)
@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+)
+
+const withdrawalColumns = `wallet_address, nonce, amount, signature, recovery_id, status, solana_tx_sig, expires_at, created_at, updated_at`
+
+func scanWithdrawal(row pgx.Row) (domain.Withdrawal, error) {
+	var w domain.Withdrawal
+	err := row.Scan(
+		&w.WalletAddress,
+		&w.Nonce,
+		&w.Amount,
+		&w.Signature,
+		&w.RecoveryID,
+		&w.Status,
+		&w.SolanaTxSig,
+		&w.ExpiresAt,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	return w, err
+}
+
+func collectWithdrawals(rows pgx.Rows) ([]domain.Withdrawal, error) {
+	defer rows.Close()
+
+	var withdrawals []domain.Withdrawal
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, w)
+	}
+	return withdrawals, rows.Err()
+}
+
+// CreateWithdrawal queues a signed withdrawal: the amount moves from the
+// player's balance to pending and the wallet's next nonce advances past
+// w.Nonce. The nonce must be the wallet's current next nonce; if another
+// withdrawal took it first, domain.ErrWithdrawalNonceTaken is returned.
+func (r *PostgresRepo) CreateWithdrawal(ctx context.Context, w *domain.Withdrawal) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET next_withdrawal_nonce = next_withdrawal_nonce + 1
+		WHERE wallet_address = $1 AND next_withdrawal_nonce = $2
+	`, w.WalletAddress, w.Nonce)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWithdrawalNonceTaken
+	}
+
+	tag, err = tx.Exec(ctx, `
+		UPDATE accounts
+		SET playable_balance = playable_balance - $1, updated_at = NOW()
+		WHERE wallet_address = $2 AND playable_balance >= $1
+	`, w.Amount, w.WalletAddress)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInsufficientFunds
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO withdrawals (wallet_address, nonce, amount, signature, recovery_id, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`, w.WalletAddress, w.Nonce, w.Amount, w.Signature, w.RecoveryID, domain.WithdrawalStatusSigned, w.ExpiresAt).Scan(&w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	w.Status = domain.WithdrawalStatusSigned
+
+	err = postJournal(ctx, tx, w.WalletAddress, domain.LedgerWithdrawalHold, ledgerSource{nonce: &w.Nonce},
+		transfer(domain.LedgerAccountPlayer, domain.LedgerAccountPendingWithdrawal, w.Amount)...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ConfirmWithdrawals marks the wallet's signed withdrawals with a nonce at or
+// below lastOnChainNonce as confirmed, i.e. executed by the program, and
+// releases their funds to the vault. It returns the withdrawals it confirmed.
+func (r *PostgresRepo) ConfirmWithdrawals(ctx context.Context, walletAddress string, lastOnChainNonce int64) ([]domain.Withdrawal, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE withdrawals
+		SET status = $1, updated_at = NOW()
+		WHERE wallet_address = $2 AND status = $3 AND nonce <= $4
+		RETURNING `+withdrawalColumns,
+		domain.WithdrawalStatusConfirmed, walletAddress, domain.WithdrawalStatusSigned, lastOnChainNonce)
+	if err != nil {
+		return nil, err
+	}
+	confirmed, err := collectWithdrawals(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range confirmed {
+		w := &confirmed[i]
+		err = postJournal(ctx, tx, walletAddress, domain.LedgerWithdrawalRelease, ledgerSource{nonce: &w.Nonce},
+			transfer(domain.LedgerAccountPendingWithdrawal, domain.LedgerAccountVault, w.Amount)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return confirmed, nil
+}
+
+// RefundWithdrawals cancels the wallet's signed withdrawals with a nonce above
+// lastOnChainNonce, returns their amounts to the player's balance and rewinds
+// the next nonce to lastOnChainNonce+1 so it matches the program again. The
+// withdrawals are marked with status (refunded or expired); their rows stay,
+// sharing the nonce with whatever withdrawal is issued on it next.
+//
+// Rewinding re-issues those nonces, so it is only done once every one of the
+// cancelled authorizations expired before chainNow; otherwise nothing changes
+// and domain.ErrWithdrawalNotExpired is returned.
+func (r *PostgresRepo) RefundWithdrawals(ctx context.Context, walletAddress string, lastOnChainNonce int64, status string, chainNow time.Time) ([]domain.Withdrawal, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `SELECT 1 FROM users WHERE wallet_address = $1 FOR UPDATE`, walletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var live int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM withdrawals
+		WHERE wallet_address = $1 AND status = $2 AND nonce > $3 AND expires_at >= $4
+	`, walletAddress, domain.WithdrawalStatusSigned, lastOnChainNonce, chainNow).Scan(&live)
+	if err != nil {
+		return nil, err
+	}
+	if live > 0 {
+		return nil, domain.ErrWithdrawalNotExpired
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE withdrawals
+		SET status = $1, updated_at = NOW()
+		WHERE wallet_address = $2 AND status = $3 AND nonce > $4
+		RETURNING `+withdrawalColumns,
+		status, walletAddress, domain.WithdrawalStatusSigned, lastOnChainNonce)
+	if err != nil {
+		return nil, err
+	}
+	refunded, err := collectWithdrawals(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(refunded) == 0 {
+		return nil, nil
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET next_withdrawal_nonce = $1 WHERE wallet_address = $2`,
+		lastOnChainNonce+1, walletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range refunded {
+		w := &refunded[i]
+		err = postJournal(ctx, tx, walletAddress, domain.LedgerRefund, ledgerSource{nonce: &w.Nonce},
+			transfer(domain.LedgerAccountPendingWithdrawal, domain.LedgerAccountPlayer, w.Amount)...)
+		if err != nil {
+			return nil, err
+		}
+		if err := creditAccount(ctx, tx, walletAddress, w.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return refunded, nil
+}
+
+// SetWithdrawalTxSig records the transaction that executed a withdrawal.
+// Refunded and expired withdrawals on the same nonce are left alone.
+func (r *PostgresRepo) SetWithdrawalTxSig(ctx context.Context, walletAddress string, nonce int64, txSig string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE withdrawals
+		SET solana_tx_sig = $1, updated_at = NOW()
+		WHERE wallet_address = $2 AND nonce = $3 AND status IN ($4, $5)
+	`, txSig, walletAddress, nonce, domain.WithdrawalStatusSigned, domain.WithdrawalStatusConfirmed)
+	return err
+}
+
+// UpdateWithdrawalSignature replaces the authorization of a withdrawal that is
+// still waiting to be executed. It reports false if the withdrawal has already
+// left the signed state.
+func (r *PostgresRepo) UpdateWithdrawalSignature(ctx context.Context, walletAddress string, nonce int64, signature string, recoveryID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE withdrawals
+		SET signature = $1, recovery_id = $2, updated_at = NOW()
+		WHERE wallet_address = $3 AND nonce = $4 AND status = $5
+	`, signature, recoveryID, walletAddress, nonce, domain.WithdrawalStatusSigned)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetWithdrawals returns the wallet's withdrawals, newest first. An empty status
+// returns all of them.
+func (r *PostgresRepo) GetWithdrawals(ctx context.Context, walletAddress string, status string, limit int, offset int) ([]domain.Withdrawal, error) {
+	query := `
+		SELECT ` + withdrawalColumns + `
+		FROM withdrawals
+		WHERE wallet_address = $1 AND ($2 = '' OR status = $2)
+		ORDER BY nonce DESC, withdrawal_id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, walletAddress, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return collectWithdrawals(rows)
+}
+
+// GetPendingWithdrawals returns every signed withdrawal that has not been
+// confirmed, refunded or expired yet, grouped by wallet in nonce order.
+func (r *PostgresRepo) GetPendingWithdrawals(ctx context.Context) ([]domain.Withdrawal, error) {
+	query := `
+		SELECT ` + withdrawalColumns + `
+		FROM withdrawals
+		WHERE status = $1
+		ORDER BY wallet_address, nonce
+	`
+	rows, err := r.db.Query(ctx, query, domain.WithdrawalStatusSigned)
+	if err != nil {
+		return nil, err
+	}
+	return collectWithdrawals(rows)
+}
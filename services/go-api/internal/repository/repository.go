@@ -2,39 +2,69 @@ package repository
 
 import (
 	"context"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/shopspring/decimal"
 )
 
+// SpinFunc plays one spin against a session and account that are locked for
+// the duration of the call. It returns the spin to record and the server seed
+// (and its hash) the session rotates to.
+type SpinFunc func(session *domain.Session, account *domain.Account, spinNonce int64) (spin *domain.Spin, nextSeed string, nextHash string, err error)
+
 type Repository interface {
 	CreateUser(ctx context.Context, walletAddress string) error
 	GetUser(ctx context.Context, walletAddress string) (*domain.User, error)
 	GetNextWithdrawalNonce(ctx context.Context, walletAddress string) (int64, error)
 	IncrementWithdrawalNonce(ctx context.Context, walletAddress string) error
-	SetPendingWithdrawal(ctx context.Context, walletAddress string, amount decimal.Decimal, signature string) error
-	CompleteWithdrawal(ctx context.Context, walletAddress string) error
-	RefundWithdrawal(ctx context.Context, walletAddress string, amount decimal.Decimal, correctNextNonce int64, fallbackSession *domain.Session) error
 
-	CheckDepositProcessed(ctx context.Context, txSig string) (bool, error)
-	RecordDeposit(ctx context.Context, txSig string, walletAddress string, amount uint64, fallbackSession *domain.Session) error
+	CreateWithdrawal(ctx context.Context, withdrawal *domain.Withdrawal) error
+	ConfirmWithdrawals(ctx context.Context, walletAddress string, lastOnChainNonce int64) ([]domain.Withdrawal, error)
+	RefundWithdrawals(ctx context.Context, walletAddress string, lastOnChainNonce int64, status string, chainNow time.Time) ([]domain.Withdrawal, error)
+	SetWithdrawalTxSig(ctx context.Context, walletAddress string, nonce int64, txSig string) error
+	UpdateWithdrawalSignature(ctx context.Context, walletAddress string, nonce int64, signature string, recoveryID int) (bool, error)
+	GetWithdrawals(ctx context.Context, walletAddress string, status string, limit int, offset int) ([]domain.Withdrawal, error)
+	GetPendingWithdrawals(ctx context.Context) ([]domain.Withdrawal, error)
+
+	CheckDepositProcessed(ctx context.Context, txSig string, ixIndex int) (bool, error)
+	RecordDeposit(ctx context.Context, txSig string, ixIndex int, walletAddress string, amount uint64) error
+
+	GetAccount(ctx context.Context, walletAddress string) (*domain.Account, error)
+	GetLiabilities(ctx context.Context) (decimal.Decimal, error)
+
+	GetWalletPositions(ctx context.Context) ([]domain.WalletPosition, error)
+	SaveReconciliation(ctx context.Context, run *domain.ReconciliationRun) error
+	GetLatestReconciliation(ctx context.Context, discrepanciesOnly bool) (*domain.ReconciliationRun, error)
 
 	CreateSession(ctx context.Context, session *domain.Session) error
 	GetActiveSession(ctx context.Context, walletAddress string) (*domain.Session, error)
-	GetLatestSession(ctx context.Context, walletAddress string) (*domain.Session, error)
-	UpdateSessionState(ctx context.Context, sessionID string, newBalance string, newSeed string, newHash string) error
 
-	CreateSpin(ctx context.Context, spin *domain.Spin) error
+	PlaySpin(ctx context.Context, walletAddress string, play SpinFunc) (*domain.Spin, *domain.Session, *domain.Account, error)
 	GetSpinsByWallet(ctx context.Context, walletAddress string, limit int, offset int) ([]domain.Spin, error)
-	GetSpinCount(ctx context.Context, sessionID uuid.UUID) (int64, error)
 	GetUnbatchedSpins(ctx context.Context, limit int) ([]domain.Spin, error)
 	GetBatchSpins(ctx context.Context, batchID int64) ([]domain.Spin, error)
 	GetSpin(ctx context.Context, spinIDStr string) (*domain.Spin, error)
 	GetBatch(ctx context.Context, batchID int64) (*domain.Batch, error)
 
-	GetOpenBatch(ctx context.Context) (*domain.Batch, error)
-	CreateBatch(ctx context.Context) (*domain.Batch, error)
-	AddSpinToBatch(ctx context.Context, spinID string, batchID int64) error
-	CloseBatch(ctx context.Context, batchID int64, merkleRoot string, txSig string) error
+	GetPendingBatches(ctx context.Context) ([]domain.Batch, error)
+	CreateBatch(ctx context.Context, treeFormat int, spinIDs []string) (*domain.Batch, error)
+	MarkBatchSubmitted(ctx context.Context, batchID int64, merkleRoot string, txSig string) error
+	RecordBatchFailure(ctx context.Context, batchID int64, reason string, retryIn time.Duration, final bool) error
+	CloseBatch(ctx context.Context, batchID int64, merkleRoot string, txSig string, slot uint64, proofs []domain.MerkleProof) error
+	GetMerkleProof(ctx context.Context, spinIDStr string) (*domain.MerkleProof, error)
+
+	GetIndexerCursor(ctx context.Context, name string) (string, error)
+	SetIndexerCursor(ctx context.Context, name string, signature string, slot uint64) error
+
+	CreateAuthChallenge(ctx context.Context, challenge *domain.AuthChallenge) error
+	ConsumeAuthChallenge(ctx context.Context, nonce string, walletAddress string) (*domain.AuthChallenge, error)
+	CreateAuthSession(ctx context.Context, tokenHash string, walletAddress string, expiresAt time.Time) error
+	GetAuthSessionWallet(ctx context.Context, tokenHash string) (string, error)
+
+	ReserveIdempotencyKey(ctx context.Context, walletAddress string, key string, requestHash string) (*domain.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, walletAddress string, key string, status int, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, walletAddress string, key string) error
+
+	ReconcileLedger(ctx context.Context) (*domain.LedgerReport, error)
 }
@@ -0,0 +1,238 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// A snapshot older than this is not used for limits, even if refreshing fails.
+	maxStaleness = 5 * time.Minute
+	// alertInterval spaces out repeated bankroll alerts.
+	alertInterval = 10 * time.Minute
+)
+
+var lamportsPerSOL = decimal.NewFromInt(1_000_000_000)
+
+// Config tunes how much of the bankroll is put at risk.
+type Config struct {
+	// MaxExposure is the share of free liquidity a single spin may win.
+	MaxExposure decimal.Decimal
+	// AlertRatio is the liabilities to liquidity ratio that raises an alert.
+	AlertRatio decimal.Decimal
+	// CacheTTL is how long a vault balance and liability snapshot is reused.
+	CacheTTL time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxExposure: decimal.NewFromFloat(0.01),
+		AlertRatio:  decimal.NewFromFloat(0.8),
+		CacheTTL:    30 * time.Second,
+	}
+}
+
+// Bankroll is a snapshot of what the vault holds against what players are owed.
+// Amounts are in SOL.
+type Bankroll struct {
+	// Liquidity is the vault's lamport balance above its rent-exempt minimum.
+	Liquidity decimal.Decimal
+	// Liabilities are playable balances plus signed, unexecuted withdrawals.
+	Liabilities decimal.Decimal
+	FetchedAt   time.Time
+}
+
+// Free is the liquidity not already owed to players.
+func (b *Bankroll) Free() decimal.Decimal {
+	free := b.Liquidity.Sub(b.Liabilities)
+	if free.IsNegative() {
+		return decimal.Zero
+	}
+	return free
+}
+
+// Utilization is liabilities as a share of liquidity.
+func (b *Bankroll) Utilization() decimal.Decimal {
+	if !b.Liquidity.IsPositive() {
+		if b.Liabilities.IsPositive() {
+			return decimal.NewFromInt(1)
+		}
+		return decimal.Zero
+	}
+	return b.Liabilities.Div(b.Liquidity)
+}
+
+// Manager limits bets to what the vault can pay out. The biggest possible win
+// of a spin (bet times the game's worst-case multiplier) may use at most
+// MaxExposure of the free liquidity.
+type Manager struct {
+	repo      repository.Repository
+	rpcClient solana_parser.ChainClient
+	vault     solana.PublicKey
+	cfg       Config
+
+	mu        sync.Mutex
+	snapshot  *Bankroll
+	lastAlert time.Time
+}
+
+func NewManager(repo repository.Repository, rpcClient solana_parser.ChainClient, vaultAddressStr string, cfg Config) (*Manager, error) {
+	vault, err := solana.PublicKeyFromBase58(vaultAddressStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address: %w", err)
+	}
+	return &Manager{
+		repo:      repo,
+		rpcClient: rpcClient,
+		vault:     vault,
+		cfg:       cfg,
+	}, nil
+}
+
+// Start refreshes the bankroll in the background, so alerts are raised even
+// when nobody is spinning.
+func (m *Manager) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	log.Println("🏦 Bankroll Monitor Started")
+
+	for {
+		if _, err := m.refresh(ctx); err != nil {
+			log.Printf("❌ Bankroll Error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("🏦 Bankroll Monitor stopping...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Bankroll returns the cached snapshot, refreshing it once it is older than
+// CacheTTL. A failed refresh falls back to a snapshot younger than maxStaleness.
+func (m *Manager) Bankroll(ctx context.Context) (*Bankroll, error) {
+	m.mu.Lock()
+	snapshot := m.snapshot
+	m.mu.Unlock()
+
+	if snapshot != nil && time.Since(snapshot.FetchedAt) < m.cfg.CacheTTL {
+		return snapshot, nil
+	}
+
+	fresh, err := m.refresh(ctx)
+	if err == nil {
+		return fresh, nil
+	}
+	if snapshot != nil && time.Since(snapshot.FetchedAt) < maxStaleness {
+		log.Printf("⚠️  Warning: Using bankroll snapshot from %s: %v", snapshot.FetchedAt.Format(time.RFC3339), err)
+		return snapshot, nil
+	}
+	return nil, fmt.Errorf("%w: %v", domain.ErrBankrollUnavailable, err)
+}
+
+// MaxBet is the largest bet on def whose worst-case win the bankroll covers,
+// rounded down to whole lamports.
+func (m *Manager) MaxBet(ctx context.Context, def *game.Definition) (decimal.Decimal, error) {
+	bankroll, err := m.Bankroll(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return maxBet(bankroll, def.MaxMultiplier(), m.cfg.MaxExposure), nil
+}
+
+// CheckBet rejects bets that are not a positive number of whole lamports with
+// domain.ErrInvalidBet and bets above MaxBet with domain.ErrBetAboveMax.
+func (m *Manager) CheckBet(ctx context.Context, def *game.Definition, bet decimal.Decimal) error {
+	if !bet.IsPositive() || !bet.Equal(bet.Truncate(9)) {
+		return domain.ErrInvalidBet
+	}
+	limit, err := m.MaxBet(ctx, def)
+	if err != nil {
+		return err
+	}
+	if bet.GreaterThan(limit) {
+		return fmt.Errorf("%w: max bet on %s is %s SOL", domain.ErrBetAboveMax, def.ID, limit)
+	}
+	return nil
+}
+
+func maxBet(bankroll *Bankroll, maxMultiplier float64, maxExposure decimal.Decimal) decimal.Decimal {
+	if maxMultiplier <= 0 {
+		return bankroll.Free()
+	}
+	return bankroll.Free().Mul(maxExposure).Div(decimal.NewFromFloat(maxMultiplier)).RoundDown(9)
+}
+
+func (m *Manager) refresh(ctx context.Context) (*Bankroll, error) {
+	liquidity, err := m.vaultLiquidity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	liabilities, err := m.repo.GetLiabilities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("summing liabilities: %w", err)
+	}
+
+	snapshot := &Bankroll{Liquidity: liquidity, Liabilities: liabilities, FetchedAt: time.Now()}
+
+	m.mu.Lock()
+	m.snapshot = snapshot
+	m.mu.Unlock()
+
+	m.checkAlert(snapshot)
+	return snapshot, nil
+}
+
+func (m *Manager) vaultLiquidity(ctx context.Context) (decimal.Decimal, error) {
+	info, err := m.rpcClient.GetAccountInfoWithOpts(ctx, m.vault, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("fetching vault account: %w", err)
+	}
+	if info == nil || info.Value == nil {
+		return decimal.Zero, fmt.Errorf("vault account %s not found", m.vault)
+	}
+
+	rent, err := m.rpcClient.GetMinimumBalanceForRentExemption(ctx, uint64(len(info.Value.Data.GetBinary())), rpc.CommitmentConfirmed)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("fetching vault rent exemption: %w", err)
+	}
+
+	lamports := info.Value.Lamports
+	if lamports < rent {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromUint64(lamports - rent).Div(lamportsPerSOL), nil
+}
+
+// checkAlert logs when liabilities come close to (or exceed) vault liquidity.
+func (m *Manager) checkAlert(b *Bankroll) {
+	utilization := b.Utilization()
+	if utilization.LessThan(m.cfg.AlertRatio) {
+		return
+	}
+
+	m.mu.Lock()
+	if time.Since(m.lastAlert) < alertInterval {
+		m.mu.Unlock()
+		return
+	}
+	m.lastAlert = time.Now()
+	m.mu.Unlock()
+
+	log.Printf("🚨 Bankroll Alert: liabilities %s SOL are %s%% of vault liquidity %s SOL (free: %s SOL)",
+		b.Liabilities, utilization.Mul(decimal.NewFromInt(100)).StringFixed(1), b.Liquidity, b.Free())
+}
@@ -0,0 +1,40 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestMaxBet(t *testing.T) {
+	bankroll := &Bankroll{
+		Liquidity:   decimal.NewFromInt(1000),
+		Liabilities: decimal.NewFromInt(750),
+	}
+
+	// 1% of the 250 SOL not owed to players, spread over a 2500x worst case.
+	got := maxBet(bankroll, 2500, decimal.NewFromFloat(0.01))
+	if !got.Equal(decimal.NewFromFloat(0.001)) {
+		t.Errorf("Expected max bet 0.001 SOL, got %s", got)
+	}
+	if !bankroll.Utilization().Equal(decimal.NewFromFloat(0.75)) {
+		t.Errorf("Expected utilization 0.75, got %s", bankroll.Utilization())
+	}
+
+	bankroll.Liabilities = decimal.NewFromInt(1200)
+	if got := maxBet(bankroll, 2500, decimal.NewFromFloat(0.01)); !got.IsZero() {
+		t.Errorf("Expected no bets when liabilities exceed liquidity, got %s", got)
+	}
+}
+
+func TestCheckBetRejectsInvalidBets(t *testing.T) {
+	m := &Manager{}
+	for _, bet := range []string{"0", "-0.5", "0.0000000004"} {
+		if err := m.CheckBet(context.Background(), nil, decimal.RequireFromString(bet)); !errors.Is(err, domain.ErrInvalidBet) {
+			t.Errorf("Expected bet %s to be rejected with ErrInvalidBet, got %v", bet, err)
+		}
+	}
+}
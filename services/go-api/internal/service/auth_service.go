@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+)
+
+const (
+	challengeTTL = 5 * time.Minute
+	authTokenTTL = 12 * time.Hour
+)
+
+// AuthService implements Sign-In-With-Solana: the server issues a nonce, the
+// wallet signs a message embedding it with its ed25519 key, and a verified
+// signature is exchanged for an opaque bearer token. Only token hashes are stored.
+type AuthService struct {
+	repo   repository.Repository
+	domain string
+}
+
+func NewAuthService(repo repository.Repository, domain string) *AuthService {
+	return &AuthService{repo: repo, domain: domain}
+}
+
+// Challenge issues a single-use nonce and the message the wallet has to sign.
+func (s *AuthService) Challenge(ctx context.Context, walletAddress string) (*domain.AuthChallenge, error) {
+	if _, err := solana.PublicKeyFromBase58(walletAddress); err != nil {
+		return nil, fmt.Errorf("invalid wallet address")
+	}
+
+	nonce, err := crypto.GenerateSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now().UTC()
+	challenge := &domain.AuthChallenge{
+		Nonce:         nonce,
+		WalletAddress: walletAddress,
+		ExpiresAt:     issuedAt.Add(challengeTTL),
+	}
+	challenge.Message = s.message(walletAddress, nonce, issuedAt, challenge.ExpiresAt)
+
+	if err := s.repo.CreateAuthChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// message renders a SIWS (CAIP-122 style) sign-in message.
+func (s *AuthService) message(walletAddress string, nonce string, issuedAt time.Time, expiresAt time.Time) string {
+	return fmt.Sprintf(
+		"%s wants you to sign in with your Solana account:\n%s\n\nSign in to play and manage your casino balance.\n\nVersion: 1\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		s.domain, walletAddress, nonce, issuedAt.Format(time.RFC3339), expiresAt.Format(time.RFC3339),
+	)
+}
+
+// Login verifies the wallet's signature over a challenge message and returns a
+// bearer token. signatureHex is the raw 64-byte ed25519 signature, hex encoded.
+func (s *AuthService) Login(ctx context.Context, walletAddress string, nonce string, signatureHex string) (string, time.Time, error) {
+	pubkey, err := solana.PublicKeyFromBase58(walletAddress)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid wallet address")
+	}
+	sig, err := hex.DecodeString(signatureHex)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return "", time.Time{}, fmt.Errorf("invalid signature format")
+	}
+
+	challenge, err := s.repo.ConsumeAuthChallenge(ctx, nonce, walletAddress)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if challenge == nil {
+		return "", time.Time{}, domain.ErrUnauthorized
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pubkey[:]), []byte(challenge.Message), sig) {
+		return "", time.Time{}, domain.ErrUnauthorized
+	}
+
+	token, err := crypto.GenerateSeed()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(authTokenTTL)
+
+	if err := s.repo.CreateAuthSession(ctx, crypto.HashStringSHA256(token), walletAddress, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Authenticate resolves a bearer token to the wallet it was issued to.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", domain.ErrUnauthorized
+	}
+	wallet, err := s.repo.GetAuthSessionWallet(ctx, crypto.HashStringSHA256(token))
+	if err != nil {
+		return "", err
+	}
+	if wallet == "" {
+		return "", domain.ErrUnauthorized
+	}
+	return wallet, nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
+)
+
+// AuthorityStatus compares the key the service signs with to the one the
+// CasinoVault accepts.
+type AuthorityStatus struct {
+	Authority            string
+	OnChainAuthority     string
+	OperationalAuthority string
+	Paused               bool
+	RotationPending      bool
+}
+
+// Rotation is the outcome of a completed signing-authority rotation.
+type Rotation struct {
+	OldAuthority string
+	NewAuthority string
+	TxSignature  string
+	Resigned     int
+	Failed       int
+}
+
+// AuthorityService rotates the withdrawal signing key. A rotation pauses
+// signing, generates a key and stores it next to the keystore (<path>.next),
+// points the CasinoVault at it with update_signing_authority, installs it as
+// the keystore and re-signs every withdrawal that is still waiting to be
+// executed. A rotation that was interrupted is finished by the next call with
+// the same key.
+type AuthorityService struct {
+	repo         repository.Repository
+	signer       *signer.Rotating
+	rpcClient    solana_parser.ChainClient
+	tracker      *txconfirm.Tracker
+	operator     solana.PrivateKey
+	programID    solana.PublicKey
+	vaultAddress solana.PublicKey
+	keystorePath string
+	passphrase   string
+
+	mu sync.Mutex
+}
+
+// NewAuthorityService needs the operational wallet, which signs the update.
+// Rotation is only available with a keystore: keystorePath is where the new key
+// is written, encrypted with passphrase. An empty path leaves only Status.
+func NewAuthorityService(repo repository.Repository, withdrawalSigner *signer.Rotating, rpcClient solana_parser.ChainClient, tracker *txconfirm.Tracker, operator solana.PrivateKey, programIDStr string, vaultAddressStr string, keystorePath string, passphrase string) (*AuthorityService, error) {
+	programID, err := solana.PublicKeyFromBase58(programIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program ID: %w", err)
+	}
+	vaultPubkey, err := solana.PublicKeyFromBase58(vaultAddressStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address: %w", err)
+	}
+
+	return &AuthorityService{
+		repo:         repo,
+		signer:       withdrawalSigner,
+		rpcClient:    rpcClient,
+		tracker:      tracker,
+		operator:     operator,
+		programID:    programID,
+		vaultAddress: vaultPubkey,
+		keystorePath: keystorePath,
+		passphrase:   passphrase,
+	}, nil
+}
+
+// Status reports the local and on-chain signing authorities.
+func (s *AuthorityService) Status(ctx context.Context) (*AuthorityStatus, error) {
+	vault, err := solana_parser.FetchCasinoVault(ctx, s.rpcClient, s.vaultAddress, s.tracker.Commitment())
+	if err != nil {
+		return nil, err
+	}
+
+	current, paused := s.signer.Current()
+	status := &AuthorityStatus{
+		Authority:            hex.EncodeToString(current.Authority()),
+		OnChainAuthority:     hex.EncodeToString(vault.SigningAuthority[:]),
+		OperationalAuthority: vault.OperationalAuthority.String(),
+		Paused:               paused,
+	}
+	if s.keystorePath != "" {
+		_, err := os.Stat(s.nextKeystorePath())
+		status.RotationPending = err == nil
+	}
+	return status, nil
+}
+
+// Rotate switches withdrawals to a new signing key. Signing stays paused until
+// the vault is known to hold either the old or the new authority; if the
+// update's fate is unknown, Rotate returns an error and the next call retries.
+func (s *AuthorityService) Rotate(ctx context.Context) (*Rotation, error) {
+	if s.keystorePath == "" {
+		return nil, domain.ErrRotationUnsupported
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, _ := s.signer.Current()
+	next, err := s.nextKey()
+	if err != nil {
+		return nil, err
+	}
+	rotation := &Rotation{
+		OldAuthority: hex.EncodeToString(current.Authority()),
+		NewAuthority: hex.EncodeToString(next.Authority()),
+	}
+
+	log.Printf("🔁 Rotating signing authority %s -> %s", rotation.OldAuthority, rotation.NewAuthority)
+	s.signer.Pause()
+
+	vault, err := solana_parser.FetchCasinoVault(ctx, s.rpcClient, s.vaultAddress, s.tracker.Commitment())
+	if err != nil {
+		s.signer.Resume(current)
+		return nil, err
+	}
+
+	if !bytes.Equal(vault.SigningAuthority[:], next.Authority()) {
+		if !vault.OperationalAuthority.Equals(s.operator.PublicKey()) {
+			s.signer.Resume(current)
+			return nil, fmt.Errorf("server wallet %s is not the vault's operational authority %s", s.operator.PublicKey(), vault.OperationalAuthority)
+		}
+
+		conf, err := s.sendUpdate(ctx, next.Authority())
+		if err != nil {
+			var failed *txconfirm.TxFailedError
+			if errors.As(err, &failed) || errors.Is(err, txconfirm.ErrBlockhashExpired) {
+				// The update cannot land any more, so the old key is still the valid one.
+				s.signer.Resume(current)
+				return nil, fmt.Errorf("updating signing authority: %w", err)
+			}
+			log.Printf("⏸️  Signing stays paused: signing authority update has an unknown outcome: %v", err)
+			return nil, fmt.Errorf("updating signing authority (signing stays paused until the rotation is retried): %w", err)
+		}
+		rotation.TxSignature = conf.Signature.String()
+	}
+
+	if err := s.installKeystore(current.Authority()); err != nil {
+		// The new key is safe in <path>.next; only the restart path is affected.
+		log.Printf("⚠️  Warning: new signing key is active but not installed as %s: %v", s.keystorePath, err)
+	}
+	s.signer.Resume(signer.WithAudit(next, "keystore"))
+
+	rotation.Resigned, rotation.Failed = s.resignPending(ctx)
+	log.Printf("✅ Signing authority rotated to %s. Re-signed %d pending withdrawals (%d failed)",
+		rotation.NewAuthority, rotation.Resigned, rotation.Failed)
+	return rotation, nil
+}
+
+// nextKey returns the key of an interrupted rotation, or generates and stores
+// a new one before it is used anywhere.
+func (s *AuthorityService) nextKey() (*signer.LocalSigner, error) {
+	path := s.nextKeystorePath()
+	if _, err := os.Stat(path); err == nil {
+		return signer.LoadKeystore(path, s.passphrase)
+	}
+
+	next, err := signer.GenerateLocalSigner()
+	if err != nil {
+		return nil, err
+	}
+	data, err := next.Keystore(s.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("writing new keystore: %w", err)
+	}
+	return next, nil
+}
+
+func (s *AuthorityService) nextKeystorePath() string {
+	return s.keystorePath + ".next"
+}
+
+// installKeystore retires the old keystore and moves the new one in its place.
+func (s *AuthorityService) installKeystore(oldAuthority []byte) error {
+	retired := fmt.Sprintf("%s.%s.retired", s.keystorePath, hex.EncodeToString(oldAuthority[:8]))
+	if err := os.Rename(s.keystorePath, retired); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Rename(s.nextKeystorePath(), s.keystorePath)
+}
+
+func (s *AuthorityService) sendUpdate(ctx context.Context, newAuthority []byte) (*txconfirm.Confirmation, error) {
+	instruction, err := solana_parser.NewUpdateSigningAuthorityInstruction(s.programID, s.vaultAddress, s.operator.PublicKey(), newAuthority)
+	if err != nil {
+		return nil, err
+	}
+
+	build := func(blockhash solana.Hash) (*solana.Transaction, error) {
+		tx, err := solana.NewTransaction(
+			[]solana.Instruction{instruction},
+			blockhash,
+			solana.TransactionPayer(s.operator.PublicKey()),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(s.operator.PublicKey()) {
+				return &s.operator
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+
+	return s.tracker.SendAndConfirm(ctx, build, func(sig solana.Signature) error {
+		log.Printf("📤 Signing authority update sent. Tx: %s", sig)
+		return nil
+	})
+}
+
+// resignPending authorizes every unexpired signed withdrawal again under the
+// current key. The old signatures no longer verify on chain.
+func (s *AuthorityService) resignPending(ctx context.Context) (resigned int, failed int) {
+	pending, err := s.repo.GetPendingWithdrawals(ctx)
+	if err != nil {
+		log.Printf("❌ Re-signing pending withdrawals failed: %v", err)
+		return 0, 0
+	}
+
+	now := time.Now()
+	for i := range pending {
+		w := &pending[i]
+		if !w.ExpiresAt.After(now) {
+			continue
+		}
+		if err := resignWithdrawal(ctx, s.repo, s.signer, w); err != nil {
+			log.Printf("❌ Re-signing withdrawal #%d for %s failed: %v", w.Nonce, w.WalletAddress, err)
+			failed++
+			continue
+		}
+		resigned++
+	}
+	return resigned, failed
+}
+
+// resignWithdrawal authorizes w again with s, keeping its nonce, amount and
+// expiry, and stores the new signature if w is still waiting to be executed.
+func resignWithdrawal(ctx context.Context, repo repository.Repository, s signer.Signer, w *domain.Withdrawal) error {
+	signature, recoveryID, err := s.SignWithdrawal(ctx, withdrawalRequest(w))
+	if err != nil {
+		return fmt.Errorf("signing failed: %w", err)
+	}
+
+	updated, err := repo.UpdateWithdrawalSignature(ctx, w.WalletAddress, w.Nonce, hex.EncodeToString(signature), recoveryID)
+	if err != nil {
+		return err
+	}
+	if updated {
+		w.Signature = hex.EncodeToString(signature)
+		w.RecoveryID = &recoveryID
+	}
+	return nil
+}
+
+// signedBy reports whether w's signature recovers to authority.
+func signedBy(w *domain.Withdrawal, authority []byte) bool {
+	req := withdrawalRequest(w)
+	messageHash, err := crypto.WithdrawalHash(req.WalletAddress, req.Amount, req.Nonce, req.ExpiresAt)
+	if err != nil {
+		return false
+	}
+	sig, err := hex.DecodeString(w.Signature)
+	if err != nil || w.RecoveryID == nil {
+		return false
+	}
+	recovered, err := crypto.RecoverSigningAuthority(messageHash, sig, *w.RecoveryID)
+	return err == nil && bytes.Equal(recovered, authority)
+}
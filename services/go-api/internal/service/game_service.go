@@ -2,8 +2,6 @@ package service
 
 import (
 	"context"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -11,37 +9,63 @@ import (
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/risk"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/verify"
 	"github.com/shopspring/decimal"
 )
 
 type GameService struct {
-	repo repository.Repository
+	repo     repository.Repository
+	games    *game.Registry
+	bankroll *risk.Manager
 }
 
-func NewGameService(repo repository.Repository) *GameService {
+func NewGameService(repo repository.Repository, games *game.Registry, bankroll *risk.Manager) *GameService {
 	return &GameService{
-		repo: repo,
+		repo:     repo,
+		games:    games,
+		bankroll: bankroll,
 	}
 }
 
-// InitiateSession creates a new session or rotates seeds if needed
+// GameLimit is the current max bet on the latest version of a game.
+type GameLimit struct {
+	GameID        string
+	Version       int
+	MaxMultiplier float64
+	MaxBet        decimal.Decimal
+}
+
+// Limits returns the max bet of every game, derived from the bankroll.
+func (s *GameService) Limits(ctx context.Context) ([]GameLimit, error) {
+	var limits []GameLimit
+	for _, id := range s.games.IDs() {
+		def, err := s.games.Latest(id)
+		if err != nil {
+			return nil, err
+		}
+		maxBet, err := s.bankroll.MaxBet(ctx, def)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, GameLimit{
+			GameID:        def.ID,
+			Version:       def.Version,
+			MaxMultiplier: def.MaxMultiplier(),
+			MaxBet:        maxBet,
+		})
+	}
+	return limits, nil
+}
+
+// InitiateSession creates a new session or rotates seeds if needed.
+// The balance lives on the wallet's account, so nothing is carried over.
 func (s *GameService) InitiateSession(ctx context.Context, walletAddress string) (*domain.Session, error) {
 	err := s.repo.CreateUser(ctx, walletAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register user: %w", err)
 	}
 
-	startingBalance := decimal.Zero
-
-	lastSession, err := s.repo.GetLatestSession(ctx, walletAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch last session: %w", err)
-	}
-
-	if lastSession != nil {
-		startingBalance = lastSession.PlayableBalance
-	}
-
 	seed, err := crypto.GenerateSeed()
 	if err != nil {
 		return nil, err
@@ -51,7 +75,6 @@ func (s *GameService) InitiateSession(ctx context.Context, walletAddress string)
 	session := &domain.Session{
 		SessionID:          uuid.New(),
 		WalletAddress:      walletAddress,
-		PlayableBalance:    startingBalance,
 		NextServerSeed:     seed,
 		NextServerSeedHash: hash,
 		IsActive:           true,
@@ -64,78 +87,70 @@ func (s *GameService) InitiateSession(ctx context.Context, walletAddress string)
 	return session, nil
 }
 
-// ExecuteSpin performs the game logic and returns the Spin result and the Next Server Seed Hash
-func (s *GameService) ExecuteSpin(ctx context.Context, walletAddress string, betAmount decimal.Decimal, clientSeed string) (*domain.Spin, string, error) {
-	session, err := s.repo.GetActiveSession(ctx, walletAddress)
+// ExecuteSpin performs the game logic and returns the Spin result and the Next Server Seed Hash.
+// An empty gameID plays the default game; spins always run on the latest version of a game.
+// The session is locked while the spin is played, so parallel spins are applied one after another.
+// Bets whose worst-case win the bankroll cannot cover are rejected before anything is locked.
+func (s *GameService) ExecuteSpin(ctx context.Context, walletAddress string, gameID string, betAmount decimal.Decimal, clientSeed string) (*domain.Spin, string, error) {
+	if gameID == "" {
+		gameID = game.DefaultGameID
+	}
+	def, err := s.games.Latest(gameID)
 	if err != nil {
 		return nil, "", err
 	}
-	if session == nil {
-		return nil, "", domain.ErrSessionInactive
-	}
-	if session.PlayableBalance.LessThan(betAmount) {
-		return nil, "", domain.ErrInsufficientFunds
+	if err := s.bankroll.CheckBet(ctx, def, betAmount); err != nil {
+		return nil, "", err
 	}
 
-	currentSeed := session.NextServerSeed
-	currentHash := session.NextServerSeedHash
+	spin, session, _, err := s.repo.PlaySpin(ctx, walletAddress, func(session *domain.Session, account *domain.Account, spinNonce int64) (*domain.Spin, string, string, error) {
+		if account.PlayableBalance.LessThan(betAmount) {
+			return nil, "", "", domain.ErrInsufficientFunds
+		}
 
-	prevCount, err := s.repo.GetSpinCount(ctx, session.SessionID)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to calculate nonce: %w", err)
-	}
-	spinNonce := prevCount + 1
+		currentSeed := session.NextServerSeed
 
-	result, err := game.CalculateSpin(currentSeed, clientSeed, spinNonce, betAmount)
-	if err != nil {
-		return nil, "", err
-	}
+		result, err := game.CalculateSpin(def, currentSeed, clientSeed, spinNonce, betAmount)
+		if err != nil {
+			return nil, "", "", err
+		}
 
-	nextSeed, err := crypto.GenerateSeed()
-	if err != nil {
-		return nil, "", err
-	}
-	nextHash := crypto.HashStringSHA256(nextSeed)
-
-	outcomeBytes, _ := json.Marshal(result.Matrix)
-	canonicalString := fmt.Sprintf("%s:%d:%s:%s:%s:%s:%s",
-		walletAddress,
-		spinNonce,
-		currentSeed,
-		clientSeed,
-		betAmount.String(),
-		string(outcomeBytes),
-		result.TotalPayout.String(),
-	)
-	leafHash := hex.EncodeToString(crypto.HashDataSHA256([]byte(canonicalString)))
-
-	newBalance := session.PlayableBalance.Sub(betAmount).Add(result.TotalPayout)
-
-	spin := &domain.Spin{
-		SpinID:         uuid.New(),
-		SessionID:      session.SessionID,
-		WalletAddress:  walletAddress,
-		SpinNonce:      spinNonce,
-		ServerSeed:     currentSeed,
-		ClientSeed:     clientSeed,
-		ServerSeedHash: currentHash,
-		BetAmount:      betAmount,
-		PayoutAmount:   result.TotalPayout,
-		Outcome:        domain.SpinOutcome{Reels: convertMatrixToFlat(result.Matrix), IsWin: result.TotalPayout.GreaterThan(decimal.Zero)},
-		LeafHash:       leafHash,
-	}
-
-	err = s.repo.CreateSpin(ctx, spin)
-	if err != nil {
-		return nil, "", err
-	}
+		nextSeed, err := crypto.GenerateSeed()
+		if err != nil {
+			return nil, "", "", err
+		}
 
-	err = s.repo.UpdateSessionState(ctx, session.SessionID.String(), newBalance.String(), nextSeed, nextHash)
+		payout := game.RoundPayout(result.TotalPayout)
+
+		spin := &domain.Spin{
+			SpinID:         uuid.New(),
+			SessionID:      session.SessionID,
+			WalletAddress:  walletAddress,
+			SpinNonce:      spinNonce,
+			GameID:         def.ID,
+			GameVersion:    def.Version,
+			ServerSeed:     currentSeed,
+			ClientSeed:     clientSeed,
+			ServerSeedHash: session.NextServerSeedHash,
+			BetAmount:      betAmount,
+			PayoutAmount:   payout,
+			Outcome: domain.SpinOutcome{
+				Reels: convertMatrixToFlat(result.Matrix),
+				Rows:  def.Rows,
+				IsWin: payout.GreaterThan(decimal.Zero),
+				Wins:  convertWins(result.Wins),
+			},
+			LeafFormat: int(crypto.CurrentMerkleFormat),
+		}
+		spin.LeafHash = verify.SpinLeafHash(crypto.CurrentMerkleFormat, spin, result.Matrix, payout)
+
+		return spin, nextSeed, crypto.HashStringSHA256(nextSeed), nil
+	})
 	if err != nil {
 		return nil, "", err
 	}
 
-	return spin, nextHash, nil
+	return spin, session.NextServerSeedHash, nil
 }
 
 func convertMatrixToFlat(matrix [][]game.Symbol) []int {
@@ -148,6 +163,20 @@ func convertMatrixToFlat(matrix [][]game.Symbol) []int {
 	return flat
 }
 
+func convertWins(wins []game.Win) []domain.SpinWin {
+	var out []domain.SpinWin
+	for _, w := range wins {
+		out = append(out, domain.SpinWin{
+			Kind:       string(w.Kind),
+			Payline:    w.Payline,
+			Symbol:     int(w.Symbol),
+			Count:      w.Count,
+			Multiplier: w.Multiplier,
+		})
+	}
+	return out
+}
+
 func (s *GameService) GetUserHistory(ctx context.Context, walletAddress string) ([]domain.Spin, error) {
 	return s.repo.GetSpinsByWallet(ctx, walletAddress, 50, 0)
 }
@@ -166,6 +195,23 @@ func (s *GameService) GetSpinProof(ctx context.Context, spinIDStr string) (inter
 		return nil, err
 	}
 
+	stored, err := s.repo.GetMerkleProof(ctx, spinIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		return map[string]interface{}{
+			"spin":        spin,
+			"batch_id":    batch.BatchID,
+			"tree_format": batch.TreeFormat,
+			"merkle_root": batch.MerkleRoot,
+			"solana_tx":   batch.SolanaTxSig,
+			"proof":       stored.Proof,
+			"leaf_index":  stored.LeafIndex,
+		}, nil
+	}
+
+	// Batches committed before proofs were persisted: rebuild the tree.
 	batchSpins, err := s.repo.GetBatchSpins(ctx, *spin.BatchID)
 	if err != nil {
 		return nil, err
@@ -185,13 +231,24 @@ func (s *GameService) GetSpinProof(ctx context.Context, spinIDStr string) (inter
 		return nil, fmt.Errorf("spin not found in batch")
 	}
 
-	proof, _ := crypto.GenerateMerkleProof(leafHashes, targetIndex)
+	var proof interface{}
+	switch crypto.MerkleFormat(batch.TreeFormat) {
+	case crypto.MerkleFormatLegacy:
+		proof, err = crypto.GenerateMerkleProof(leafHashes, targetIndex)
+	default:
+		proof, err = crypto.GenerateMerkleProofRFC6962(leafHashes, targetIndex)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	return map[string]interface{}{
 		"spin":        spin,
 		"batch_id":    batch.BatchID,
+		"tree_format": batch.TreeFormat,
 		"merkle_root": batch.MerkleRoot,
 		"solana_tx":   batch.SolanaTxSig,
 		"proof":       proof,
+		"leaf_index":  targetIndex,
 	}, nil
 }
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+)
+
+// IdempotencyService deduplicates retried requests. Keys are scoped per wallet;
+// each key is bound to the hash of the request it was first used with.
+type IdempotencyService struct {
+	repo repository.Repository
+}
+
+func NewIdempotencyService(repo repository.Repository) *IdempotencyService {
+	return &IdempotencyService{repo: repo}
+}
+
+// Begin claims key for request. It returns nil when the request should run, or
+// the stored record whose response must be replayed instead.
+func (s *IdempotencyService) Begin(ctx context.Context, walletAddress string, key string, request []byte) (*domain.IdempotencyRecord, error) {
+	requestHash := crypto.HashStringSHA256(string(request))
+
+	rec, reserved, err := s.repo.ReserveIdempotencyKey(ctx, walletAddress, key, requestHash)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	if rec.RequestHash != requestHash {
+		return nil, domain.ErrIdempotencyReuse
+	}
+	if rec.ResponseStatus == nil {
+		return nil, domain.ErrRequestInFlight
+	}
+	return rec, nil
+}
+
+// Finish stores the response for replay, whatever its status: a failure may
+// come after the request already changed state, so running it again is unsafe.
+func (s *IdempotencyService) Finish(ctx context.Context, walletAddress string, key string, status int, body []byte) error {
+	return s.repo.CompleteIdempotencyKey(ctx, walletAddress, key, status, body)
+}
+
+// Release forgets the reservation of a request that failed without writing
+// anything, so the client can retry it under the same key.
+func (s *IdempotencyService) Release(ctx context.Context, walletAddress string, key string) error {
+	return s.repo.ReleaseIdempotencyKey(ctx, walletAddress, key)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/risk"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/shopspring/decimal"
+)
+
+// ReconciliationService compares every UserBalance PDA with Postgres. The
+// on-chain amount only moves with deposits and withdrawals, so it should equal
+// deposited minus withdrawn; the playable balance drifts from it by the
+// player's game result, which the report shows per wallet.
+type ReconciliationService struct {
+	repo      repository.Repository
+	rpcClient solana_parser.ChainClient
+	bankroll  *risk.Manager
+	programID solana.PublicKey
+}
+
+func NewReconciliationService(repo repository.Repository, rpcClient solana_parser.ChainClient, bankroll *risk.Manager, programIDStr string) (*ReconciliationService, error) {
+	programID, err := solana.PublicKeyFromBase58(programIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program ID: %w", err)
+	}
+	return &ReconciliationService{
+		repo:      repo,
+		rpcClient: rpcClient,
+		bankroll:  bankroll,
+		programID: programID,
+	}, nil
+}
+
+// Run builds and stores a report. Chain state is read at finalized, so
+// activity of the last few seconds can show up as a discrepancy that is gone
+// in the next run.
+func (s *ReconciliationService) Run(ctx context.Context) (*domain.ReconciliationRun, error) {
+	positions, err := s.repo.GetWalletPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading wallet positions: %w", err)
+	}
+	balances, err := solana_parser.FetchUserBalances(ctx, s.rpcClient, s.programID, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, err
+	}
+	bankroll, err := s.bankroll.Bankroll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	onChain := make(map[string]*solana_parser.UserBalanceAccount, len(balances))
+	for i := range balances {
+		onChain[balances[i].User.String()] = &balances[i]
+	}
+
+	run := &domain.ReconciliationRun{VaultLiquidity: bankroll.Liquidity}
+	add := func(item domain.ReconciliationItem) {
+		run.Items = append(run.Items, item)
+		run.Liabilities = run.Liabilities.Add(item.PlayableBalance).Add(item.PendingWithdrawals)
+		if item.OnChainAmount != nil {
+			run.OnChainTotal = run.OnChainTotal.Add(*item.OnChainAmount)
+		}
+		if len(item.Issues) > 0 {
+			run.Discrepancies++
+		}
+	}
+
+	for i := range positions {
+		pos := &positions[i]
+		add(reconcileWallet(pos, onChain[pos.WalletAddress]))
+		delete(onChain, pos.WalletAddress)
+	}
+	for _, acc := range onChain {
+		add(reconcileWallet(nil, acc))
+	}
+
+	run.Wallets = len(run.Items)
+	run.LiabilitiesCovered = run.VaultLiquidity.GreaterThanOrEqual(run.Liabilities)
+
+	if err := s.repo.SaveReconciliation(ctx, run); err != nil {
+		return nil, fmt.Errorf("saving reconciliation: %w", err)
+	}
+	return run, nil
+}
+
+// Latest returns the newest report, nil if none has run yet.
+func (s *ReconciliationService) Latest(ctx context.Context, discrepanciesOnly bool) (*domain.ReconciliationRun, error) {
+	return s.repo.GetLatestReconciliation(ctx, discrepanciesOnly)
+}
+
+// reconcileWallet compares a wallet's Postgres position with its UserBalance
+// account. Either side may be nil, but not both.
+func reconcileWallet(pos *domain.WalletPosition, acc *solana_parser.UserBalanceAccount) domain.ReconciliationItem {
+	item := domain.ReconciliationItem{Issues: []string{}}
+
+	if pos == nil {
+		item.WalletAddress = acc.User.String()
+		item.Issues = append(item.Issues, domain.DiscrepancyMissingOffChain)
+	} else {
+		nextNonce := pos.NextWithdrawalNonce
+		item.WalletAddress = pos.WalletAddress
+		item.Deposited = pos.Deposited
+		item.Withdrawn = pos.Withdrawn
+		item.PlayableBalance = pos.PlayableBalance
+		item.PendingWithdrawals = pos.Pending
+		item.NextWithdrawalNonce = &nextNonce
+	}
+
+	offChain := item.PlayableBalance.Add(item.PendingWithdrawals)
+	if acc == nil {
+		item.Drift = offChain
+		if item.Deposited.IsPositive() {
+			item.Issues = append(item.Issues, domain.DiscrepancyMissingOnChain)
+		}
+		return item
+	}
+
+	amount := decimal.NewFromUint64(acc.Amount).Div(decimal.NewFromInt(1_000_000_000))
+	lastNonce := int64(acc.LastNonce)
+	item.OnChainAmount = &amount
+	item.OnChainLastNonce = &lastNonce
+	item.Drift = offChain.Sub(amount)
+
+	if pos == nil {
+		return item
+	}
+	if !amount.Equal(pos.Deposited.Sub(pos.Withdrawn)) {
+		item.Issues = append(item.Issues, domain.DiscrepancyAmountMismatch)
+	}
+	if pos.FirstSignedNonce != nil && *pos.FirstSignedNonce <= lastNonce {
+		item.Issues = append(item.Issues, domain.DiscrepancyUnconfirmedExecution)
+	}
+	if pos.LastConfirmedNonce > lastNonce {
+		item.Issues = append(item.Issues, domain.DiscrepancyConfirmedNotExecuted)
+	}
+	if pos.NextWithdrawalNonce <= lastNonce {
+		item.Issues = append(item.Issues, domain.DiscrepancyStaleNonce)
+	}
+	return item
+}
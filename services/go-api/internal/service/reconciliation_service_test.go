@@ -0,0 +1,47 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/shopspring/decimal"
+)
+
+func TestReconcileWallet(t *testing.T) {
+	user := solana.MustPublicKeyFromBase58("AMyC4nrskq9PERnZfFZv3KRhEm23VUpRV4VrggAjYiiU")
+	firstSigned := int64(3)
+	pos := &domain.WalletPosition{
+		WalletAddress:       user.String(),
+		NextWithdrawalNonce: 4,
+		Deposited:           decimal.NewFromInt(5),
+		Withdrawn:           decimal.NewFromInt(1),
+		Pending:             decimal.NewFromFloat(0.5),
+		PlayableBalance:     decimal.NewFromInt(6),
+		LastConfirmedNonce:  2,
+		FirstSignedNonce:    &firstSigned,
+	}
+
+	// The program already executed nonce 3, so it also moved 0.5 SOL more out.
+	acc := &solana_parser.UserBalanceAccount{User: user, Amount: 3_500_000_000, LastNonce: 3}
+	item := reconcileWallet(pos, acc)
+
+	want := []string{domain.DiscrepancyAmountMismatch, domain.DiscrepancyUnconfirmedExecution}
+	if !reflect.DeepEqual(item.Issues, want) {
+		t.Errorf("Expected issues %v, got %v", want, item.Issues)
+	}
+	if !item.Drift.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected drift 6.5 - 3.5 = 3, got %s", item.Drift)
+	}
+
+	acc.Amount, acc.LastNonce = 4_000_000_000, 2
+	if item := reconcileWallet(pos, acc); len(item.Issues) != 0 {
+		t.Errorf("Expected a matching wallet, got %v", item.Issues)
+	}
+
+	if item := reconcileWallet(nil, acc); !reflect.DeepEqual(item.Issues, []string{domain.DiscrepancyMissingOffChain}) {
+		t.Errorf("Expected an unknown wallet to be reported, got %v", item.Issues)
+	}
+}
@@ -3,121 +3,157 @@ package service
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/google/uuid"
-	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
 	"github.com/shopspring/decimal"
 )
 
 type WalletService struct {
-	repo          repository.Repository
-	serverPrivKey string
-	rpcClient     *rpc.Client
-	vaultAddress  solana.PublicKey
+	repo         repository.Repository
+	signer       signer.Signer
+	rpcClient    solana_parser.ChainClient
+	tracker      *txconfirm.Tracker
+	programID    solana.PublicKey
+	vaultAddress solana.PublicKey
 }
 
-func NewWalletService(repo repository.Repository, serverPrivKey string, rpcClient *rpc.Client, vaultAddressStr string) (*WalletService, error) {
+func NewWalletService(repo repository.Repository, withdrawalSigner signer.Signer, rpcClient solana_parser.ChainClient, tracker *txconfirm.Tracker, programIDStr string, vaultAddressStr string) (*WalletService, error) {
+	programID, err := solana.PublicKeyFromBase58(programIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program ID: %w", err)
+	}
 	vaultPubkey, err := solana.PublicKeyFromBase58(vaultAddressStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid vault address: %w", err)
 	}
 
 	return &WalletService{
-		repo:          repo,
-		serverPrivKey: serverPrivKey,
-		rpcClient:     rpcClient,
-		vaultAddress:  vaultPubkey,
+		repo:         repo,
+		signer:       withdrawalSigner,
+		rpcClient:    rpcClient,
+		tracker:      tracker,
+		programID:    programID,
+		vaultAddress: vaultPubkey,
 	}, nil
 }
 
-// GetBalance returns the current playable balance from the active session
+// GetBalance returns the current playable balance from the wallet's account
 func (s *WalletService) GetBalance(ctx context.Context, walletAddress string) (decimal.Decimal, error) {
-	session, err := s.repo.GetActiveSession(ctx, walletAddress)
+	account, err := s.repo.GetAccount(ctx, walletAddress)
 	if err != nil {
 		return decimal.Zero, err
 	}
-	if session == nil {
+	if account == nil {
 		return decimal.Zero, nil
 	}
-	return session.PlayableBalance, nil
+	return account.PlayableBalance, nil
 }
 
-// AuthorizeWithdrawal checks funds, increments nonce, signs the message
-func (s *WalletService) AuthorizeWithdrawal(ctx context.Context, walletAddress string, amount decimal.Decimal) ([]byte, int, int64, error) {
+// withdrawalAuthorizationTTL is how long a signed withdrawal can be executed.
+// Once it has passed on chain, unused withdrawals are refunded automatically.
+const withdrawalAuthorizationTTL = 10 * time.Minute
+
+// AuthorizeWithdrawal checks funds, signs the message for the wallet's next
+// nonce and queues the withdrawal. Several withdrawals can be outstanding; the
+// program executes them in nonce order, each only until it expires. Amounts
+// that are not a positive number of whole lamports are rejected with
+// domain.ErrInvalidAmount before anything is signed. An error that comes after
+// the withdrawal was queued is returned together with it.
+func (s *WalletService) AuthorizeWithdrawal(ctx context.Context, walletAddress string, amount decimal.Decimal) (*domain.Withdrawal, error) {
+	if !amount.IsPositive() || !amount.Equal(amount.Truncate(9)) {
+		return nil, domain.ErrInvalidAmount
+	}
+
 	user, err := s.repo.GetUser(ctx, walletAddress)
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, err
 	}
 	if user == nil {
-		return nil, 0, 0, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user not found")
 	}
 
-	session, err := s.repo.GetActiveSession(ctx, walletAddress)
+	account, err := s.repo.GetAccount(ctx, walletAddress)
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, err
 	}
-	if session == nil {
-		return nil, 0, 0, domain.ErrSessionInactive
+	if account == nil || account.PlayableBalance.LessThan(amount) {
+		return nil, domain.ErrInsufficientFunds
 	}
 
-	if user.PendingWithdrawalAmount.GreaterThan(decimal.Zero) && user.PendingWithdrawalSignature != "" {
-		if !user.PendingWithdrawalAmount.Equal(amount) {
-			return nil, 0, 0, fmt.Errorf("pending withdrawal exists for %s SOL. Complete it first.", user.PendingWithdrawalAmount.String())
-		}
-
-		lamports := amount.Mul(decimal.NewFromInt(1_000_000_000)).BigInt().Uint64()
-		sig, recid, err := crypto.SignWithdrawal(s.serverPrivKey, walletAddress, lamports, uint64(user.NextWithdrawalNonce))
-		if err != nil {
-			return nil, 0, 0, err
-		}
-
-		return sig, recid, user.NextWithdrawalNonce, nil
+	withdrawal := &domain.Withdrawal{
+		WalletAddress: walletAddress,
+		Nonce:         user.NextWithdrawalNonce,
+		Amount:        amount,
+		ExpiresAt:     time.Now().Add(withdrawalAuthorizationTTL).Truncate(time.Second),
 	}
 
-	if session.PlayableBalance.LessThan(amount) {
-		return nil, 0, 0, domain.ErrInsufficientFunds
+	signature, recoveryID, err := s.signer.SignWithdrawal(ctx, withdrawalRequest(withdrawal))
+	if err != nil {
+		return nil, fmt.Errorf("signing failed: %w", err)
 	}
+	withdrawal.Signature = hex.EncodeToString(signature)
+	withdrawal.RecoveryID = &recoveryID
 
-	nonce := user.NextWithdrawalNonce
-	lamports := amount.Mul(decimal.NewFromInt(1_000_000_000)).BigInt().Uint64()
+	if err := s.repo.CreateWithdrawal(ctx, withdrawal); err != nil {
+		return nil, err
+	}
 
-	signature, recoveryID, err := crypto.SignWithdrawal(s.serverPrivKey, walletAddress, lamports, uint64(nonce))
-	if err != nil {
-		return nil, 0, 0, fmt.Errorf("signing failed: %w", err)
+	// A key rotation re-signs the withdrawals it can see; one that was signed
+	// under the old key but queued after that pass is re-signed here.
+	if !signedBy(withdrawal, s.signer.Authority()) {
+		if err := resignWithdrawal(ctx, s.repo, s.signer, withdrawal); err != nil {
+			return withdrawal, err
+		}
 	}
+	return withdrawal, nil
+}
 
-	sigHex := hex.EncodeToString(signature)
-	err = s.repo.SetPendingWithdrawal(ctx, walletAddress, amount, sigHex)
-	if err != nil {
-		return nil, 0, 0, err
+// withdrawalRequest is what the signer commits to for w.
+func withdrawalRequest(w *domain.Withdrawal) signer.WithdrawalRequest {
+	return signer.WithdrawalRequest{
+		WalletAddress: w.WalletAddress,
+		Amount:        toLamports(w.Amount),
+		Nonce:         uint64(w.Nonce),
+		ExpiresAt:     w.ExpiresAt.Unix(),
 	}
+}
 
-	return signature, recoveryID, nonce, nil
+func toLamports(amount decimal.Decimal) uint64 {
+	return amount.Mul(decimal.NewFromInt(1_000_000_000)).BigInt().Uint64()
 }
 
-func (s *WalletService) SyncDeposit(ctx context.Context, walletAddress string, txSigStr string) error {
-	exists, err := s.repo.CheckDepositProcessed(ctx, txSigStr)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("transaction already processed")
-	}
+// ListWithdrawals returns the wallet's withdrawal history, newest first,
+// optionally filtered by status.
+func (s *WalletService) ListWithdrawals(ctx context.Context, walletAddress string, status string) ([]domain.Withdrawal, error) {
+	return s.repo.GetWithdrawals(ctx, walletAddress, status, 50, 0)
+}
 
+// SyncDeposit credits the casino deposit instructions in txSigStr that were
+// signed by walletAddress. Deposits by other wallets sharing the tx are left for
+// their owners; plain transfers to the vault are rejected.
+func (s *WalletService) SyncDeposit(ctx context.Context, walletAddress string, txSigStr string) error {
 	sig, err := solana.SignatureFromBase58(txSigStr)
 	if err != nil {
 		return fmt.Errorf("invalid signature format")
 	}
+	wallet, err := solana.PublicKeyFromBase58(walletAddress)
+	if err != nil {
+		return fmt.Errorf("invalid wallet address")
+	}
 
+	maxVersion := uint64(0)
 	tx, err := s.rpcClient.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
-		Commitment: rpc.CommitmentConfirmed,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to fetch tx from solana: %w", err)
@@ -131,110 +167,187 @@ func (s *WalletService) SyncDeposit(ctx context.Context, walletAddress string, t
 		return fmt.Errorf("failed to decode transaction: %w", err)
 	}
 
-	vaultIndex := -1
-	accountKeys := parsedTx.Message.AccountKeys
+	deposits, err := solana_parser.ParseVaultDeposits(parsedTx, s.programID, s.vaultAddress)
+	if err != nil {
+		return fmt.Errorf("invalid deposit: %w", err)
+	}
+	if len(deposits) == 0 {
+		return fmt.Errorf("invalid deposit: no casino deposit instruction into the vault")
+	}
 
-	for i, key := range accountKeys {
-		if key.Equals(s.vaultAddress) {
-			vaultIndex = i
-			break
+	credited, owned := 0, 0
+	for _, dep := range deposits {
+		if !dep.User.Equals(wallet) {
+			continue
 		}
-	}
+		owned++
 
-	if vaultIndex == -1 {
-		return fmt.Errorf("invalid deposit: casino vault not involved in this transaction")
+		err := s.repo.RecordDeposit(ctx, txSigStr, dep.Index, walletAddress, dep.Amount)
+		if errors.Is(err, domain.ErrDepositProcessed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		credited++
 	}
 
-	if vaultIndex >= len(tx.Meta.PreBalances) || vaultIndex >= len(tx.Meta.PostBalances) {
-		return fmt.Errorf("transaction metadata mismatch")
+	if owned == 0 {
+		return fmt.Errorf("invalid deposit: transaction was not signed by this wallet")
 	}
-
-	preBal := int64(tx.Meta.PreBalances[vaultIndex])
-	postBal := int64(tx.Meta.PostBalances[vaultIndex])
-
-	amountReceived := postBal - preBal
-
-	if amountReceived <= 0 {
-		return fmt.Errorf("invalid deposit: vault balance did not increase")
+	if credited == 0 {
+		return fmt.Errorf("transaction already processed")
 	}
+	return nil
+}
 
-	seed, err := crypto.GenerateSeed()
+// AttemptRefund returns the funds of withdrawals the program has not executed
+// and can no longer execute because their authorization expired. Withdrawals
+// whose nonce was already consumed are confirmed instead.
+func (s *WalletService) AttemptRefund(ctx context.Context, walletAddress string) error {
+	pending, err := s.repo.GetWithdrawals(ctx, walletAddress, domain.WithdrawalStatusSigned, 1, 0)
 	if err != nil {
 		return err
 	}
-	hash := crypto.HashStringSHA256(seed)
-
-	fallbackSession := &domain.Session{
-		SessionID:          uuid.New(),
-		WalletAddress:      walletAddress,
-		PlayableBalance:    decimal.Zero,
-		NextServerSeed:     seed,
-		NextServerSeedHash: hash,
-		IsActive:           true,
+	if len(pending) == 0 {
+		return fmt.Errorf("no pending withdrawal to refund")
 	}
 
-	err = s.repo.RecordDeposit(ctx, txSigStr, walletAddress, uint64(amountReceived), fallbackSession)
+	refunded, err := s.refundExpired(ctx, walletAddress, domain.WithdrawalStatusRefunded)
 	if err != nil {
 		return err
 	}
-
+	if len(refunded) == 0 {
+		return fmt.Errorf("cannot refund: transaction appears to have succeeded on-chain")
+	}
 	return nil
 }
 
-func (s *WalletService) AttemptRefund(ctx context.Context, walletAddress string) error {
-	user, err := s.repo.GetUser(ctx, walletAddress)
+// refundExpired settles the wallet's signed withdrawals against finalized chain
+// state: consumed nonces are confirmed, the rest are refunded with status if
+// they all expired before the latest finalized block. The chain time is read
+// before the account, so no withdraw that could still land is missed.
+func (s *WalletService) refundExpired(ctx context.Context, walletAddress string, status string) ([]domain.Withdrawal, error) {
+	chainNow, err := s.tracker.FinalizedTime(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	if user.PendingWithdrawalAmount.IsZero() {
-		return fmt.Errorf("no pending withdrawal to refund")
+	lastOnChainNonce, err := s.lastWithdrawalNonce(ctx, walletAddress, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, err
 	}
 
-	userPubkey, _ := solana.PublicKeyFromBase58(walletAddress)
-	programID, _ := solana.PublicKeyFromBase58(os.Getenv("PROGRAM_ID"))
-
-	userBalancePDA, _, _ := solana.FindProgramAddress(
-		[][]byte{[]byte("user_balance"), userPubkey.Bytes()},
-		programID,
-	)
-
-	accountInfo, err := s.rpcClient.GetAccountInfo(ctx, userBalancePDA)
+	if _, err := s.repo.ConfirmWithdrawals(ctx, walletAddress, lastOnChainNonce); err != nil {
+		return nil, err
+	}
+	return s.repo.RefundWithdrawals(ctx, walletAddress, lastOnChainNonce, status, chainNow)
+}
 
-	var lastOnChainNonce uint64 = 0
+// lastWithdrawalNonce reads the last withdrawal nonce the program consumed for
+// the wallet; 0 if its UserBalance account does not exist yet.
+func (s *WalletService) lastWithdrawalNonce(ctx context.Context, walletAddress string, commitment rpc.CommitmentType) (int64, error) {
+	userPubkey, err := solana.PublicKeyFromBase58(walletAddress)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wallet address")
+	}
+	onChain, err := solana_parser.FetchUserBalance(ctx, s.rpcClient, s.programID, userPubkey, commitment)
+	if err != nil {
+		return 0, err
+	}
+	if onChain == nil {
+		return 0, nil
+	}
+	return int64(onChain.LastNonce), nil
+}
 
-	if err == nil && accountInfo != nil && accountInfo.Value != nil {
-		onChainData, err := solana_parser.ParseUserBalance(accountInfo.Value.Data.GetBinary())
+// withdrawalConfirmTimeout bounds how long CompleteWithdrawal waits for the
+// user's withdraw transaction to reach the configured commitment.
+const withdrawalConfirmTimeout = 60 * time.Second
+
+// CompleteWithdrawal confirms the wallet's withdrawals whose nonce the program
+// has consumed. When the client reports the withdraw transaction signature,
+// that transaction is awaited first and recorded on the withdrawals it
+// executed; the signature alone proves nothing.
+func (s *WalletService) CompleteWithdrawal(ctx context.Context, walletAddress string, txSigStr string) error {
+	var executed []solana_parser.WithdrawInstruction
+	if txSigStr != "" {
+		sig, err := solana.SignatureFromBase58(txSigStr)
+		if err != nil {
+			return fmt.Errorf("invalid signature format")
+		}
+		if _, err := s.tracker.Await(ctx, sig, withdrawalConfirmTimeout); err != nil {
+			return fmt.Errorf("withdrawal not confirmed: %w", err)
+		}
+		executed, err = s.withdrawInstructions(ctx, sig, walletAddress)
 		if err != nil {
-			return fmt.Errorf("failed to parse on-chain data: %w", err)
+			return err
 		}
-		lastOnChainNonce = onChainData.LastNonce
 	}
 
-	dbNextNonce := uint64(user.NextWithdrawalNonce)
-	correctNextNonce := lastOnChainNonce + 1
+	lastOnChainNonce, err := s.lastWithdrawalNonce(ctx, walletAddress, s.tracker.Commitment())
+	if err != nil {
+		return err
+	}
+	confirmed, err := s.repo.ConfirmWithdrawals(ctx, walletAddress, lastOnChainNonce)
+	if err != nil {
+		return err
+	}
 
-	if dbNextNonce > correctNextNonce {
-		seed, err := crypto.GenerateSeed()
-		if err != nil {
-			return err
+	for _, ix := range executed {
+		if int64(ix.Nonce) > lastOnChainNonce {
+			continue
 		}
-		hash := crypto.HashStringSHA256(seed)
-
-		fallbackSession := &domain.Session{
-			SessionID:          uuid.New(),
-			WalletAddress:      walletAddress,
-			NextServerSeed:     seed,
-			NextServerSeedHash: hash,
-			IsActive:           true,
+		if err := s.repo.SetWithdrawalTxSig(ctx, walletAddress, int64(ix.Nonce), txSigStr); err != nil {
+			return err
 		}
-
-		return s.repo.RefundWithdrawal(ctx, walletAddress, user.PendingWithdrawalAmount, int64(correctNextNonce), fallbackSession)
 	}
 
-	return fmt.Errorf("cannot refund: transaction appears to have succeeded on-chain")
+	if len(confirmed) > 0 || len(executed) > 0 {
+		return nil
+	}
+	// Nothing was executed since the last check: only an error if the wallet
+	// still has withdrawals waiting for the program.
+	pending, err := s.repo.GetWithdrawals(ctx, walletAddress, domain.WithdrawalStatusSigned, 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return domain.ErrWithdrawalNotExecuted
+	}
+	return nil
 }
 
-func (s *WalletService) CompleteWithdrawal(ctx context.Context, walletAddress string) error {
-	return s.repo.CompleteWithdrawal(ctx, walletAddress)
+// withdrawInstructions returns the withdraw instructions in a confirmed
+// transaction that were made by walletAddress.
+func (s *WalletService) withdrawInstructions(ctx context.Context, sig solana.Signature, walletAddress string) ([]solana_parser.WithdrawInstruction, error) {
+	maxVersion := uint64(0)
+	tx, err := s.rpcClient.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Commitment:                     s.tracker.Commitment(),
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching withdraw transaction: %w", err)
+	}
+	if tx == nil || tx.Meta == nil || tx.Meta.Err != nil {
+		return nil, fmt.Errorf("withdraw transaction failed or not found")
+	}
+	parsedTx, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("decoding withdraw transaction: %w", err)
+	}
+
+	all, err := solana_parser.ParseWithdrawInstructions(parsedTx, s.programID)
+	if err != nil {
+		return nil, err
+	}
+	var own []solana_parser.WithdrawInstruction
+	for _, ix := range all {
+		if ix.User.String() == walletAddress {
+			own = append(own, ix)
+		}
+	}
+	if len(own) == 0 {
+		return nil, fmt.Errorf("transaction contains no withdrawal for this wallet")
+	}
+	return own, nil
 }
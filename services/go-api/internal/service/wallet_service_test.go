@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/signer"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana/solanatest"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
+	"github.com/shopspring/decimal"
+)
+
+// walletRepo keeps deposits and withdrawals in memory, with the semantics of
+// the Postgres queries SyncDeposit and AttemptRefund rely on.
+type walletRepo struct {
+	repository.Repository
+
+	mu          sync.Mutex
+	deposits    map[string]uint64 // "sig/index" -> lamports
+	credited    map[string]uint64 // wallet -> lamports
+	nextNonce   map[string]int64
+	balances    map[string]decimal.Decimal
+	withdrawals []domain.Withdrawal
+}
+
+func newWalletRepo() *walletRepo {
+	return &walletRepo{
+		deposits:  map[string]uint64{},
+		credited:  map[string]uint64{},
+		nextNonce: map[string]int64{},
+		balances:  map[string]decimal.Decimal{},
+	}
+}
+
+func (r *walletRepo) GetUser(_ context.Context, walletAddress string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nonce, ok := r.nextNonce[walletAddress]
+	if !ok {
+		return nil, nil
+	}
+	return &domain.User{WalletAddress: walletAddress, NextWithdrawalNonce: nonce}, nil
+}
+
+func (r *walletRepo) GetAccount(_ context.Context, walletAddress string) (*domain.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &domain.Account{WalletAddress: walletAddress, PlayableBalance: r.balances[walletAddress]}, nil
+}
+
+// CreateWithdrawal enforces the nonce check and the unique index on the nonces
+// of signed and confirmed withdrawals.
+func (r *walletRepo) CreateWithdrawal(_ context.Context, w *domain.Withdrawal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nextNonce[w.WalletAddress] != w.Nonce {
+		return domain.ErrWithdrawalNonceTaken
+	}
+	if r.balances[w.WalletAddress].LessThan(w.Amount) {
+		return domain.ErrInsufficientFunds
+	}
+	for _, other := range r.withdrawals {
+		if other.WalletAddress == w.WalletAddress && other.Nonce == w.Nonce &&
+			(other.Status == domain.WithdrawalStatusSigned || other.Status == domain.WithdrawalStatusConfirmed) {
+			return fmt.Errorf("duplicate key value violates unique constraint \"uq_withdrawals_live_nonce\"")
+		}
+	}
+	r.nextNonce[w.WalletAddress]++
+	r.balances[w.WalletAddress] = r.balances[w.WalletAddress].Sub(w.Amount)
+	w.Status = domain.WithdrawalStatusSigned
+	r.withdrawals = append(r.withdrawals, *w)
+	return nil
+}
+
+func (r *walletRepo) RecordDeposit(_ context.Context, txSig string, ixIndex int, walletAddress string, amount uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := fmt.Sprintf("%s/%d", txSig, ixIndex)
+	if _, ok := r.deposits[key]; ok {
+		return domain.ErrDepositProcessed
+	}
+	r.deposits[key] = amount
+	r.credited[walletAddress] += amount
+	return nil
+}
+
+func (r *walletRepo) GetWithdrawals(_ context.Context, walletAddress string, status string, limit int, offset int) ([]domain.Withdrawal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Withdrawal
+	for _, w := range r.withdrawals {
+		if w.WalletAddress == walletAddress && (status == "" || w.Status == status) {
+			out = append(out, w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Nonce > out[j].Nonce })
+	if offset >= len(out) {
+		return nil, nil
+	}
+	out = out[offset:]
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *walletRepo) ConfirmWithdrawals(_ context.Context, walletAddress string, lastOnChainNonce int64) ([]domain.Withdrawal, error) {
+	return r.settle(walletAddress, func(w *domain.Withdrawal) bool { return w.Nonce <= lastOnChainNonce }, domain.WithdrawalStatusConfirmed), nil
+}
+
+func (r *walletRepo) RefundWithdrawals(_ context.Context, walletAddress string, lastOnChainNonce int64, status string, chainNow time.Time) ([]domain.Withdrawal, error) {
+	r.mu.Lock()
+	for _, w := range r.withdrawals {
+		if w.WalletAddress == walletAddress && w.Status == domain.WithdrawalStatusSigned &&
+			w.Nonce > lastOnChainNonce && !w.ExpiresAt.Before(chainNow) {
+			r.mu.Unlock()
+			return nil, domain.ErrWithdrawalNotExpired
+		}
+	}
+	r.mu.Unlock()
+	refunded := r.settle(walletAddress, func(w *domain.Withdrawal) bool { return w.Nonce > lastOnChainNonce }, status)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(refunded) > 0 {
+		r.nextNonce[walletAddress] = lastOnChainNonce + 1
+	}
+	for _, w := range refunded {
+		r.balances[walletAddress] = r.balances[walletAddress].Add(w.Amount)
+	}
+	return refunded, nil
+}
+
+func (r *walletRepo) settle(walletAddress string, match func(*domain.Withdrawal) bool, status string) []domain.Withdrawal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var settled []domain.Withdrawal
+	for i := range r.withdrawals {
+		w := &r.withdrawals[i]
+		if w.WalletAddress == walletAddress && w.Status == domain.WithdrawalStatusSigned && match(w) {
+			w.Status = status
+			settled = append(settled, *w)
+		}
+	}
+	return settled
+}
+
+func (r *walletRepo) status(nonce int64) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, w := range r.withdrawals {
+		if w.Nonce == nonce {
+			return w.Status
+		}
+	}
+	return ""
+}
+
+type walletFixture struct {
+	chain  *solanatest.Chain
+	repo   *walletRepo
+	signer *signer.LocalSigner
+	svc    *WalletService
+}
+
+func newWalletFixture(t *testing.T) *walletFixture {
+	t.Helper()
+	withdrawalSigner, err := signer.GenerateLocalSigner()
+	if err != nil {
+		t.Fatalf("Generating signer failed: %v", err)
+	}
+	programID := solana.NewWallet().PublicKey()
+	chain := solanatest.NewChain(programID, solana.NewWallet().PublicKey(), withdrawalSigner.Authority(), 100_000_000_000)
+	repo := newWalletRepo()
+
+	svc, err := NewWalletService(repo, withdrawalSigner, chain, txconfirm.NewTracker(chain, ""), programID.String(), chain.Vault().String())
+	if err != nil {
+		t.Fatalf("Creating wallet service failed: %v", err)
+	}
+	return &walletFixture{chain: chain, repo: repo, signer: withdrawalSigner, svc: svc}
+}
+
+// authorize queues a signed withdrawal. Its signature is made when execute
+// sends it.
+func (f *walletFixture) authorize(user solana.PublicKey, nonce int64, lamports int64, expiresAt time.Time) domain.Withdrawal {
+	w := domain.Withdrawal{
+		WalletAddress: user.String(),
+		Nonce:         nonce,
+		Amount:        decimal.New(lamports, -9),
+		Status:        domain.WithdrawalStatusSigned,
+		ExpiresAt:     expiresAt,
+	}
+	f.repo.mu.Lock()
+	f.repo.withdrawals = append(f.repo.withdrawals, w)
+	f.repo.mu.Unlock()
+	return w
+}
+
+// execute sends the program's withdraw for w, signed by user.
+func (f *walletFixture) execute(t *testing.T, user solana.PrivateKey, w domain.Withdrawal) error {
+	t.Helper()
+	req := withdrawalRequest(&w)
+	sig, recoveryID, err := f.signer.SignWithdrawal(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Signing failed: %v", err)
+	}
+	userBalance, _ := solana_parser.FindUserBalancePDA(f.svc.programID, user.PublicKey())
+	ix, err := anchor.Casino().MustInstruction("withdraw").Build(f.svc.programID,
+		map[string]solana.PublicKey{
+			"casino_vault": f.chain.Vault(),
+			"user_balance": userBalance,
+			"user":         user.PublicKey(),
+		},
+		map[string]interface{}{
+			"amount":      req.Amount,
+			"nonce":       req.Nonce,
+			"expires_at":  req.ExpiresAt,
+			"signature":   sig,
+			"recovery_id": recoveryID,
+		},
+	)
+	if err != nil {
+		t.Fatalf("Building withdraw failed: %v", err)
+	}
+	_, err = f.chain.SendInstructions(context.Background(), user, ix)
+	return err
+}
+
+func TestSyncDeposit(t *testing.T) {
+	ctx := context.Background()
+	f := newWalletFixture(t)
+
+	alice, bob := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+	f.chain.Airdrop(alice.PublicKey(), 10_000_000_000)
+
+	sig, err := f.chain.Deposit(ctx, alice, 2_000_000_000)
+	if err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+
+	if err := f.svc.SyncDeposit(ctx, bob.PublicKey().String(), sig.String()); err == nil || !strings.Contains(err.Error(), "not signed by this wallet") {
+		t.Errorf("Expected another wallet's deposit to be rejected, got %v", err)
+	}
+	if err := f.svc.SyncDeposit(ctx, alice.PublicKey().String(), sig.String()); err != nil {
+		t.Fatalf("SyncDeposit failed: %v", err)
+	}
+	if got := f.repo.credited[alice.PublicKey().String()]; got != 2_000_000_000 {
+		t.Errorf("Expected 2 SOL credited, got %d lamports", got)
+	}
+	if err := f.svc.SyncDeposit(ctx, alice.PublicKey().String(), sig.String()); err == nil || !strings.Contains(err.Error(), "already processed") {
+		t.Errorf("Expected a second sync to be rejected, got %v", err)
+	}
+
+	transfer, err := f.chain.SendInstructions(ctx, alice,
+		system.NewTransferInstruction(1_000_000_000, alice.PublicKey(), f.chain.Vault()).Build())
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	if err := f.svc.SyncDeposit(ctx, alice.PublicKey().String(), transfer.String()); err == nil || !strings.Contains(err.Error(), "no casino deposit") {
+		t.Errorf("Expected a plain transfer to be rejected, got %v", err)
+	}
+	if got := f.repo.credited[alice.PublicKey().String()]; got != 2_000_000_000 {
+		t.Errorf("Expected the transfer not to be credited, got %d lamports", got)
+	}
+
+	if err := f.svc.SyncDeposit(ctx, alice.PublicKey().String(), solana.Signature{1}.String()); err == nil {
+		t.Errorf("Expected an unknown transaction to be rejected")
+	}
+}
+
+func TestAttemptRefund(t *testing.T) {
+	ctx := context.Background()
+	f := newWalletFixture(t)
+
+	alice := solana.NewWallet().PrivateKey
+	wallet := alice.PublicKey().String()
+	f.chain.Airdrop(alice.PublicKey(), 10_000_000_000)
+	if _, err := f.chain.Deposit(ctx, alice, 3_000_000_000); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+
+	if err := f.svc.AttemptRefund(ctx, wallet); err == nil {
+		t.Errorf("Expected an error without pending withdrawals")
+	}
+
+	expiresAt := f.chain.Now().Add(withdrawalAuthorizationTTL)
+	first := f.authorize(alice.PublicKey(), 1, 1_000_000_000, expiresAt)
+	f.authorize(alice.PublicKey(), 2, 500_000_000, expiresAt)
+
+	if err := f.execute(t, alice, first); err != nil {
+		t.Fatalf("Withdraw failed: %v", err)
+	}
+
+	// Nonce 2 can still be executed, so nothing is refunded yet.
+	if err := f.svc.AttemptRefund(ctx, wallet); !errors.Is(err, domain.ErrWithdrawalNotExpired) {
+		t.Fatalf("Expected ErrWithdrawalNotExpired, got %v", err)
+	}
+	if got := f.repo.status(1); got != domain.WithdrawalStatusConfirmed {
+		t.Errorf("Expected the executed withdrawal to be confirmed, got %q", got)
+	}
+
+	f.chain.Advance(withdrawalAuthorizationTTL + time.Second)
+	if err := f.svc.AttemptRefund(ctx, wallet); err != nil {
+		t.Fatalf("AttemptRefund failed: %v", err)
+	}
+	if got := f.repo.status(2); got != domain.WithdrawalStatusRefunded {
+		t.Errorf("Expected the expired withdrawal to be refunded, got %q", got)
+	}
+
+	// A withdrawal the program executed is never refunded.
+	third := f.authorize(alice.PublicKey(), 2, 200_000_000, f.chain.Now().Add(time.Minute))
+	if err := f.execute(t, alice, third); err != nil {
+		t.Fatalf("Withdraw failed: %v", err)
+	}
+	f.chain.Advance(2 * time.Minute)
+	if err := f.svc.AttemptRefund(ctx, wallet); err == nil || !strings.Contains(err.Error(), "succeeded on-chain") {
+		t.Errorf("Expected an executed withdrawal not to be refunded, got %v", err)
+	}
+}
+
+func TestAuthorizeWithdrawalRejectsInvalidAmounts(t *testing.T) {
+	f := newWalletFixture(t)
+	wallet := solana.NewWallet().PublicKey().String()
+
+	// The repository has no users, so getting past validation would panic.
+	for _, amount := range []string{"0", "-1", "0.0000000001"} {
+		if _, err := f.svc.AuthorizeWithdrawal(context.Background(), wallet, decimal.RequireFromString(amount)); !errors.Is(err, domain.ErrInvalidAmount) {
+			t.Errorf("Expected amount %s to be rejected with ErrInvalidAmount, got %v", amount, err)
+		}
+	}
+}
+
+func TestAuthorizeAfterRefund(t *testing.T) {
+	ctx := context.Background()
+	f := newWalletFixture(t)
+
+	alice := solana.NewWallet().PrivateKey
+	wallet := alice.PublicKey().String()
+	f.chain.Airdrop(alice.PublicKey(), 10_000_000_000)
+	if _, err := f.chain.Deposit(ctx, alice, 3_000_000_000); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	f.repo.nextNonce[wallet] = 1
+	f.repo.balances[wallet] = decimal.NewFromInt(3)
+
+	first, err := f.svc.AuthorizeWithdrawal(ctx, wallet, decimal.NewFromInt(1))
+	if err != nil {
+		t.Fatalf("AuthorizeWithdrawal failed: %v", err)
+	}
+
+	// The authorization lapses unused, so its nonce is handed out again.
+	f.chain.Advance(time.Until(first.ExpiresAt) + time.Minute)
+	if err := f.svc.AttemptRefund(ctx, wallet); err != nil {
+		t.Fatalf("AttemptRefund failed: %v", err)
+	}
+
+	second, err := f.svc.AuthorizeWithdrawal(ctx, wallet, decimal.NewFromInt(2))
+	if err != nil {
+		t.Fatalf("Re-authorizing after a refund failed: %v", err)
+	}
+	if second.Nonce != first.Nonce {
+		t.Errorf("Expected nonce %d to be reused, got %d", first.Nonce, second.Nonce)
+	}
+	if got := f.repo.balances[wallet]; !got.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected 1 SOL left after the refund and the new hold, got %s", got)
+	}
+}
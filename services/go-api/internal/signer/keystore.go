@@ -0,0 +1,174 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keystoreVersion = 1
+	scryptN         = 1 << 15
+	scryptR         = 8
+	scryptP         = 1
+	scryptKeyLen    = 32
+)
+
+// keystoreFile is the on-disk form of an encrypted signing key: the key is
+// sealed with AES-256-GCM under a key derived from a passphrase with scrypt.
+// The authority is stored in the clear, so operators can tell which key a file
+// holds, and is bound to the ciphertext as additional data.
+type keystoreFile struct {
+	Version   int    `json:"version"`
+	Authority string `json:"authority"`
+	KDF       string `json:"kdf"`
+	KDFParams struct {
+		N    int    `json:"n"`
+		R    int    `json:"r"`
+		P    int    `json:"p"`
+		Salt string `json:"salt"`
+	} `json:"kdfparams"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// EncryptKeystore seals a hex-encoded secp256k1 private key with passphrase and
+// returns the keystore JSON.
+func EncryptKeystore(privateKeyHex string, passphrase string) ([]byte, error) {
+	local, err := NewLocalSigner(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return local.Keystore(passphrase)
+}
+
+// Keystore seals the signer's key with passphrase and returns the keystore JSON.
+func (s *LocalSigner) Keystore(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+
+	ks := keystoreFile{
+		Version:   keystoreVersion,
+		Authority: hex.EncodeToString(s.Authority()),
+		KDF:       "scrypt",
+		Cipher:    "aes-256-gcm",
+	}
+	ks.KDFParams.N, ks.KDFParams.R, ks.KDFParams.P = scryptN, scryptR, scryptP
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	ks.KDFParams.Salt = hex.EncodeToString(salt)
+
+	gcm, err := keystoreAEAD(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ks.Nonce = hex.EncodeToString(nonce)
+	ks.Ciphertext = hex.EncodeToString(gcm.Seal(nil, nonce, ethcrypto.FromECDSA(s.key), s.Authority()))
+
+	return json.MarshalIndent(ks, "", "  ")
+}
+
+// DecryptKeystore opens keystore JSON produced by EncryptKeystore.
+func DecryptKeystore(data []byte, passphrase string) (*LocalSigner, error) {
+	var ks keystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore: %w", err)
+	}
+	if ks.Version != keystoreVersion || ks.KDF != "scrypt" || ks.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported keystore (version %d, kdf %q, cipher %q)", ks.Version, ks.KDF, ks.Cipher)
+	}
+
+	authority, err := hex.DecodeString(ks.Authority)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore authority: %w", err)
+	}
+	salt, err := hex.DecodeString(ks.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(ks.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(ks.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+
+	gcm, err := keystoreAEAD(passphrase, salt, ks.KDFParams.N, ks.KDFParams.R, ks.KDFParams.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce length")
+	}
+	keyBytes, err := gcm.Open(nil, nonce, ciphertext, authority)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted keystore")
+	}
+
+	key, err := ethcrypto.ToECDSA(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("keystore holds an invalid key: %w", err)
+	}
+	local := newLocalSigner(key)
+	if !bytes.Equal(local.Authority(), authority) {
+		return nil, fmt.Errorf("keystore key does not match its authority")
+	}
+	return local, nil
+}
+
+// LoadKeystore reads and decrypts a keystore file.
+func LoadKeystore(path string, passphrase string) (*LocalSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	return DecryptKeystore(data, passphrase)
+}
+
+// ReadPassphrase returns the keystore passphrase from the file named by
+// SIGNER_KEYSTORE_PASSPHRASE_FILE, or else from SIGNER_KEYSTORE_PASSPHRASE.
+func ReadPassphrase() (string, error) {
+	if path := os.Getenv("SIGNER_KEYSTORE_PASSPHRASE_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if passphrase := os.Getenv("SIGNER_KEYSTORE_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	return "", fmt.Errorf("SIGNER_KEYSTORE_PASSPHRASE_FILE or SIGNER_KEYSTORE_PASSPHRASE is required")
+}
+
+func keystoreAEAD(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving keystore key: %w", err)
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
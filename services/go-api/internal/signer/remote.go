@@ -0,0 +1,164 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+)
+
+// Remote signer protocol, served by NewHandler and spoken by RemoteSigner:
+//
+//	GET  /authority -> {"authority": "<hex>"}
+//	POST /sign      WithdrawalRequest -> {"signature": "<hex>", "recovery_id": n}
+type authorityResponse struct {
+	Authority string `json:"authority"`
+}
+
+type signResponse struct {
+	Signature  string `json:"signature"`
+	RecoveryID int    `json:"recovery_id"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// RemoteSigner asks a separate signer process for authorizations, over a Unix
+// socket ("unix:///path/to.sock") or plain HTTP on a local address
+// ("http://127.0.0.1:7070"). Every signature is checked against the authority
+// the signer announced before it is handed out.
+type RemoteSigner struct {
+	client    *http.Client
+	baseURL   string
+	authority []byte
+}
+
+// NewRemoteSigner connects to the signer at addr and fetches its authority.
+func NewRemoteSigner(ctx context.Context, addr string) (*RemoteSigner, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	baseURL := strings.TrimRight(addr, "/")
+
+	if socket, ok := strings.CutPrefix(addr, "unix://"); ok {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		baseURL = "http://signer"
+	}
+
+	s := &RemoteSigner{client: client, baseURL: baseURL}
+
+	var resp authorityResponse
+	if err := s.call(ctx, http.MethodGet, "/authority", nil, &resp); err != nil {
+		return nil, fmt.Errorf("fetching signer authority: %w", err)
+	}
+	authority, err := hex.DecodeString(resp.Authority)
+	if err != nil || len(authority) != 32 {
+		return nil, fmt.Errorf("signer returned an invalid authority %q", resp.Authority)
+	}
+	s.authority = authority
+	return s, nil
+}
+
+func (s *RemoteSigner) SignWithdrawal(ctx context.Context, req WithdrawalRequest) ([]byte, int, error) {
+	messageHash, err := crypto.WithdrawalHash(req.WalletAddress, req.Amount, req.Nonce, req.ExpiresAt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var resp signResponse
+	if err := s.call(ctx, http.MethodPost, "/sign", req, &resp); err != nil {
+		return nil, 0, err
+	}
+	sig, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, 0, fmt.Errorf("signer returned invalid signature hex: %w", err)
+	}
+
+	recovered, err := crypto.RecoverSigningAuthority(messageHash, sig, resp.RecoveryID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("signer returned an unusable signature: %w", err)
+	}
+	if !bytes.Equal(recovered, s.authority) {
+		return nil, 0, fmt.Errorf("signer signature does not recover to its authority")
+	}
+	return sig, resp.RecoveryID, nil
+}
+
+func (s *RemoteSigner) Authority() []byte {
+	return s.authority
+}
+
+func (s *RemoteSigner) call(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		var e errorResponse
+		_ = json.NewDecoder(httpResp.Body).Decode(&e)
+		return fmt.Errorf("signer responded %d: %s", httpResp.StatusCode, e.Error)
+	}
+	return json.NewDecoder(httpResp.Body).Decode(out)
+}
+
+// NewHandler serves s over the remote signer protocol.
+func NewHandler(s Signer) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /authority", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, authorityResponse{Authority: hex.EncodeToString(s.Authority())})
+	})
+
+	mux.HandleFunc("POST /sign", func(w http.ResponseWriter, r *http.Request) {
+		var req WithdrawalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+			return
+		}
+		sig, recid, err := s.SignWithdrawal(r.Context(), req)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, signResponse{Signature: hex.EncodeToString(sig), RecoveryID: recid})
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
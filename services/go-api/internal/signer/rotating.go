@@ -0,0 +1,61 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPaused is returned while a signing-authority rotation is in progress.
+var ErrPaused = errors.New("withdrawal signing is paused for a signing-authority rotation")
+
+// Rotating forwards to the current signer and lets a key rotation pause
+// signing and swap the key underneath the services that hold it.
+type Rotating struct {
+	mu      sync.RWMutex
+	current Signer
+	paused  bool
+}
+
+func NewRotating(s Signer) *Rotating {
+	return &Rotating{current: s}
+}
+
+func (r *Rotating) SignWithdrawal(ctx context.Context, req WithdrawalRequest) ([]byte, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.paused {
+		return nil, 0, ErrPaused
+	}
+	return r.current.SignWithdrawal(ctx, req)
+}
+
+func (r *Rotating) Authority() []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Authority()
+}
+
+// Pause refuses new signatures. It returns once the signatures already being
+// produced are done, so nothing is signed under the current key afterwards.
+func (r *Rotating) Pause() {
+	r.mu.Lock()
+	r.paused = true
+	r.mu.Unlock()
+}
+
+// Resume signs with s from now on.
+func (r *Rotating) Resume(s Signer) {
+	r.mu.Lock()
+	r.current = s
+	r.paused = false
+	r.mu.Unlock()
+}
+
+// Current returns the signer in use and whether signing is paused.
+func (r *Rotating) Current() (Signer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, r.paused
+}
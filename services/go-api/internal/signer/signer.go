@@ -0,0 +1,101 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+)
+
+// WithdrawalRequest is everything a withdrawal authorization commits to.
+type WithdrawalRequest struct {
+	WalletAddress string `json:"wallet_address"`
+	Amount        uint64 `json:"amount_lamports"`
+	Nonce         uint64 `json:"nonce"`
+	ExpiresAt     int64  `json:"expires_at"`
+}
+
+// Signer produces withdrawal authorizations for the casino program. Where the
+// key lives (process memory, an encrypted file, another process) is up to the
+// implementation.
+type Signer interface {
+	// SignWithdrawal returns the 64-byte signature and recovery id the program
+	// expects for req.
+	SignWithdrawal(ctx context.Context, req WithdrawalRequest) ([]byte, int, error)
+	// Authority is the keccak hash of the signing public key, the value the
+	// CasinoVault stores as signing_authority.
+	Authority() []byte
+}
+
+// LocalSigner holds the secp256k1 key in memory. The key is parsed once.
+type LocalSigner struct {
+	key       *ecdsa.PrivateKey
+	authority []byte
+}
+
+// NewLocalSigner parses a hex-encoded secp256k1 private key.
+func NewLocalSigner(privateKeyHex string) (*LocalSigner, error) {
+	keyBytes, err := hex.DecodeString(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key hex: %w", err)
+	}
+	key, err := ethcrypto.ToECDSA(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ECDSA key: %w", err)
+	}
+	return newLocalSigner(key), nil
+}
+
+// GenerateLocalSigner creates a signer with a fresh random key.
+func GenerateLocalSigner() (*LocalSigner, error) {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating secp256k1 key: %w", err)
+	}
+	return newLocalSigner(key), nil
+}
+
+func newLocalSigner(key *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{key: key, authority: crypto.SigningAuthority(&key.PublicKey)}
+}
+
+func (s *LocalSigner) SignWithdrawal(_ context.Context, req WithdrawalRequest) ([]byte, int, error) {
+	messageHash, err := crypto.WithdrawalHash(req.WalletAddress, req.Amount, req.Nonce, req.ExpiresAt)
+	if err != nil {
+		return nil, 0, err
+	}
+	return crypto.SignHash(s.key, messageHash)
+}
+
+func (s *LocalSigner) Authority() []byte {
+	return s.authority
+}
+
+// auditedSigner logs every authorization it hands out, and every refusal.
+type auditedSigner struct {
+	Signer
+	backend string
+}
+
+// WithAudit wraps s so that each signing request leaves a log line naming the
+// backend, wallet, amount, nonce and expiry.
+func WithAudit(s Signer, backend string) Signer {
+	return &auditedSigner{Signer: s, backend: backend}
+}
+
+func (a *auditedSigner) SignWithdrawal(ctx context.Context, req WithdrawalRequest) ([]byte, int, error) {
+	sig, recid, err := a.Signer.SignWithdrawal(ctx, req)
+	if err != nil {
+		log.Printf("✍️  [%s] refused withdrawal #%d of %d lamports for %s: %v",
+			a.backend, req.Nonce, req.Amount, req.WalletAddress, err)
+		return nil, 0, err
+	}
+	log.Printf("✍️  [%s] signed withdrawal #%d of %d lamports for %s, expires %s",
+		a.backend, req.Nonce, req.Amount, req.WalletAddress, time.Unix(req.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	return sig, recid, nil
+}
@@ -0,0 +1,103 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+)
+
+const testKeyHex = "3ca2e85e7c3a731d241a50ee4968a672c9bcdf53ecfd335dea4133e829788772"
+
+var testRequest = WithdrawalRequest{
+	WalletAddress: "AMyC4nrskq9PERnZfFZv3KRhEm23VUpRV4VrggAjYiiU",
+	Amount:        1_000_000_000,
+	Nonce:         1,
+	ExpiresAt:     1767225600,
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	local, err := NewLocalSigner(testKeyHex)
+	if err != nil {
+		t.Fatalf("Local signer failed: %v", err)
+	}
+
+	data, err := EncryptKeystore(testKeyHex, "correct horse")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Contains(data, []byte(testKeyHex)) {
+		t.Fatalf("Keystore contains the plaintext key")
+	}
+
+	if _, err := DecryptKeystore(data, "wrong horse"); err == nil {
+		t.Fatalf("Wrong passphrase was accepted")
+	}
+
+	loaded, err := DecryptKeystore(data, "correct horse")
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(loaded.Authority(), local.Authority()) {
+		t.Errorf("Keystore authority %x differs from %x", loaded.Authority(), local.Authority())
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	local, err := NewLocalSigner(testKeyHex)
+	if err != nil {
+		t.Fatalf("Local signer failed: %v", err)
+	}
+	server := httptest.NewServer(NewHandler(local))
+	defer server.Close()
+
+	remote, err := NewRemoteSigner(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Connecting to signer failed: %v", err)
+	}
+	if !bytes.Equal(remote.Authority(), local.Authority()) {
+		t.Fatalf("Remote authority %x differs from %x", remote.Authority(), local.Authority())
+	}
+
+	sig, recid, err := remote.SignWithdrawal(context.Background(), testRequest)
+	if err != nil {
+		t.Fatalf("Remote signing failed: %v", err)
+	}
+
+	hash, _ := crypto.WithdrawalHash(testRequest.WalletAddress, testRequest.Amount, testRequest.Nonce, testRequest.ExpiresAt)
+	recovered, err := crypto.RecoverSigningAuthority(hash, sig, recid)
+	if err != nil || !bytes.Equal(recovered, local.Authority()) {
+		t.Errorf("Remote signature does not recover to the signer authority (%v)", err)
+	}
+}
+
+func TestRotatingPause(t *testing.T) {
+	old, err := NewLocalSigner(testKeyHex)
+	if err != nil {
+		t.Fatalf("Local signer failed: %v", err)
+	}
+	next, err := GenerateLocalSigner()
+	if err != nil {
+		t.Fatalf("Generating key failed: %v", err)
+	}
+
+	rotating := NewRotating(old)
+	rotating.Pause()
+	if _, _, err := rotating.SignWithdrawal(context.Background(), testRequest); !errors.Is(err, ErrPaused) {
+		t.Fatalf("Paused signer returned %v, want ErrPaused", err)
+	}
+
+	rotating.Resume(next)
+	sig, recid, err := rotating.SignWithdrawal(context.Background(), testRequest)
+	if err != nil {
+		t.Fatalf("Signing after resume failed: %v", err)
+	}
+	hash, _ := crypto.WithdrawalHash(testRequest.WalletAddress, testRequest.Amount, testRequest.Nonce, testRequest.ExpiresAt)
+	recovered, err := crypto.RecoverSigningAuthority(hash, sig, recid)
+	if err != nil || !bytes.Equal(recovered, next.Authority()) || !bytes.Equal(rotating.Authority(), next.Authority()) {
+		t.Errorf("Signature after resume does not come from the new key (%v)", err)
+	}
+}
@@ -0,0 +1,36 @@
+package solana_parser
+
+import (
+	"encoding/binary"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+)
+
+type BatchCommitAccount struct {
+	Discriminator [8]byte
+	Authority     solana.PublicKey `anchor:"authority"`
+	BatchID       uint64           `anchor:"batch_id"`
+	MerkleRoot    [32]byte         `anchor:"merkle_root"`
+}
+
+func ParseBatchCommit(data []byte) (*BatchCommitAccount, error) {
+	var acc BatchCommitAccount
+	if err := anchor.Casino().DecodeAccountInto("BatchCommit", data, &acc); err != nil {
+		return nil, err
+	}
+	copy(acc.Discriminator[:], data)
+	return &acc, nil
+}
+
+// FindBatchCommitPDA derives the address of the BatchCommit account for a batch.
+func FindBatchCommitPDA(programID solana.PublicKey, batchID int64) (solana.PublicKey, error) {
+	batchIdLe := make([]byte, 8)
+	binary.LittleEndian.PutUint64(batchIdLe, uint64(batchID))
+
+	pda, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte("batch_commit"), batchIdLe},
+		programID,
+	)
+	return pda, err
+}
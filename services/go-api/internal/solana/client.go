@@ -0,0 +1,29 @@
+package solana_parser
+
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ChainClient is the part of the Solana JSON-RPC API the server uses.
+// *rpc.Client implements it; solanatest.Chain is an in-memory stand-in for tests.
+type ChainClient interface {
+	GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error)
+	GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error)
+	GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error)
+
+	GetTransaction(ctx context.Context, sig solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error)
+
+	GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetBlockTime(ctx context.Context, slot uint64) (*solana.UnixTimeSeconds, error)
+}
+
+var _ ChainClient = (*rpc.Client)(nil)
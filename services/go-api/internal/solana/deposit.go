@@ -0,0 +1,94 @@
+package solana_parser
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+)
+
+var depositDef = anchor.Casino().MustInstruction("deposit")
+
+// DepositDiscriminator is the Anchor sighash of the casino program's deposit instruction.
+var DepositDiscriminator = [8]byte(depositDef.Discriminator)
+
+// DepositInstruction is one decoded deposit call. Accounts are looked up by
+// their name in the IDL's Deposit context.
+type DepositInstruction struct {
+	Index  int
+	Vault  solana.PublicKey
+	User   solana.PublicKey
+	Amount uint64
+}
+
+// ParseDepositInstructions returns every top-level deposit instruction of the
+// casino program in tx, in instruction order.
+func ParseDepositInstructions(tx *solana.Transaction, programID solana.PublicKey) ([]DepositInstruction, error) {
+	keys := tx.Message.AccountKeys
+
+	var deposits []DepositInstruction
+	for i, ix := range tx.Message.Instructions {
+		if int(ix.ProgramIDIndex) >= len(keys) || !keys[ix.ProgramIDIndex].Equals(programID) {
+			continue
+		}
+		if !depositDef.Matches(ix.Data) {
+			continue
+		}
+
+		var args struct {
+			Amount uint64 `anchor:"amount"`
+		}
+		if err := depositDef.DecodeArgs(ix.Data, &args); err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		vault, err := instructionAccount(keys, ix, depositDef, "casino_vault")
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		user, err := instructionAccount(keys, ix, depositDef, "user")
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+
+		deposits = append(deposits, DepositInstruction{
+			Index:  i,
+			Vault:  vault,
+			User:   user,
+			Amount: args.Amount,
+		})
+	}
+	return deposits, nil
+}
+
+// ParseVaultDeposits returns the deposit instructions that pay into vault and
+// were signed by the depositing user. A plain SOL transfer to the vault has no
+// deposit instruction and yields nothing.
+func ParseVaultDeposits(tx *solana.Transaction, programID solana.PublicKey, vault solana.PublicKey) ([]DepositInstruction, error) {
+	deposits, err := ParseDepositInstructions(tx, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := deposits[:0]
+	for _, dep := range deposits {
+		if !dep.Vault.Equals(vault) || !tx.Message.IsSigner(dep.User) {
+			continue
+		}
+		valid = append(valid, dep)
+	}
+	return valid, nil
+}
+
+// instructionAccount resolves the account that def names name in a compiled
+// instruction.
+func instructionAccount(keys solana.PublicKeySlice, ix solana.CompiledInstruction, def *anchor.Instruction, name string) (solana.PublicKey, error) {
+	pos := def.AccountIndex(name)
+	if pos < 0 || pos >= len(ix.Accounts) {
+		return solana.PublicKey{}, fmt.Errorf("%s is missing the %s account", def.Name, name)
+	}
+	idx := ix.Accounts[pos]
+	if int(idx) >= len(keys) {
+		return solana.PublicKey{}, fmt.Errorf("account index %d out of range", idx)
+	}
+	return keys[idx], nil
+}
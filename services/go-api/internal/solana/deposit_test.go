@@ -0,0 +1,57 @@
+package solana_parser
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+func depositIx(programID, vault, user solana.PublicKey, amount uint64) solana.Instruction {
+	data := make([]byte, 16)
+	copy(data, DepositDiscriminator[:])
+	binary.LittleEndian.PutUint64(data[8:], amount)
+
+	userBalance, _, _ := solana.FindProgramAddress([][]byte{[]byte("user_balance"), user.Bytes()}, programID)
+
+	return solana.NewInstruction(programID, solana.AccountMetaSlice{
+		{PublicKey: vault, IsWritable: true},
+		{PublicKey: userBalance, IsWritable: true},
+		{PublicKey: user, IsWritable: true, IsSigner: true},
+		{PublicKey: solana.SystemProgramID},
+	}, data)
+}
+
+func TestParseVaultDeposits(t *testing.T) {
+	programID := solana.NewWallet().PublicKey()
+	vault := solana.NewWallet().PublicKey()
+	alice := solana.NewWallet().PublicKey()
+	bob := solana.NewWallet().PublicKey()
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(5, alice, vault).Build(),
+		depositIx(programID, vault, alice, 1_000),
+		depositIx(programID, vault, bob, 2_000),
+		depositIx(programID, solana.NewWallet().PublicKey(), alice, 3_000),
+	}, solana.Hash{}, solana.TransactionPayer(alice))
+	if err != nil {
+		t.Fatalf("Building tx failed: %v", err)
+	}
+
+	deposits, err := ParseVaultDeposits(tx, programID, vault)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	t.Logf("Deposits: %+v", deposits)
+
+	if len(deposits) != 2 {
+		t.Fatalf("Expected 2 vault deposits, got %d", len(deposits))
+	}
+	if deposits[0].Index != 1 || !deposits[0].User.Equals(alice) || deposits[0].Amount != 1_000 {
+		t.Errorf("Unexpected first deposit: %+v", deposits[0])
+	}
+	if deposits[1].Index != 2 || !deposits[1].User.Equals(bob) || deposits[1].Amount != 2_000 {
+		t.Errorf("Unexpected second deposit: %+v", deposits[1])
+	}
+}
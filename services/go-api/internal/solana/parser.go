@@ -1,44 +1,102 @@
 package solana_parser
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
 )
 
+// UserBalanceAccount mirrors the program's UserBalance PDA. LastNonce is the
+// last_withdrawal_nonce consumed by a successful withdraw.
 type UserBalanceAccount struct {
 	Discriminator [8]byte
-	User          solana.PublicKey
-	Amount        uint64
-	LastNonce     uint64
-	Bump          uint8
+	User          solana.PublicKey `anchor:"user"`
+	Amount        uint64           `anchor:"amount"`
+	LastNonce     uint64           `anchor:"last_withdrawal_nonce"`
+	Bump          uint8            `anchor:"bump"`
 }
 
-func ParseUserBalance(data []byte) (*UserBalanceAccount, error) {
-	if len(data) < 57 {
-		return nil, fmt.Errorf("data too short")
+// userBalanceSize is the UserBalance account length, discriminator included.
+var userBalanceSize = func() uint64 {
+	size, ok := anchor.Casino().AccountSize("UserBalance")
+	if !ok {
+		panic("anchor: UserBalance has no fixed size")
 	}
+	return uint64(size)
+}()
 
-	var acc UserBalanceAccount
-	buf := bytes.NewReader(data)
+// UserBalanceDiscriminator is the Anchor account discriminator of UserBalance.
+var UserBalanceDiscriminator = [8]byte(anchor.Casino().MustAccount("UserBalance").Discriminator)
 
-	if err := binary.Read(buf, binary.LittleEndian, &acc.Discriminator); err != nil {
+func ParseUserBalance(data []byte) (*UserBalanceAccount, error) {
+	var acc UserBalanceAccount
+	if err := anchor.Casino().DecodeAccountInto("UserBalance", data, &acc); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(buf, binary.LittleEndian, &acc.User); err != nil {
+	copy(acc.Discriminator[:], data)
+	return &acc, nil
+}
+
+// FindUserBalancePDA derives the address of a user's UserBalance account.
+func FindUserBalancePDA(programID solana.PublicKey, user solana.PublicKey) (solana.PublicKey, error) {
+	pda, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte("user_balance"), user.Bytes()},
+		programID,
+	)
+	return pda, err
+}
+
+// FetchUserBalance reads and parses a user's UserBalance PDA. It returns nil if
+// the account does not exist yet (the user never deposited).
+func FetchUserBalance(ctx context.Context, rpcClient ChainClient, programID solana.PublicKey, user solana.PublicKey, commitment rpc.CommitmentType) (*UserBalanceAccount, error) {
+	pda, err := FindUserBalancePDA(programID, user)
+	if err != nil {
 		return nil, err
 	}
-	if err := binary.Read(buf, binary.LittleEndian, &acc.Amount); err != nil {
-		return nil, err
+
+	info, err := rpcClient.GetAccountInfoWithOpts(ctx, pda, &rpc.GetAccountInfoOpts{Commitment: commitment})
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching user balance account: %w", err)
 	}
-	if err := binary.Read(buf, binary.LittleEndian, &acc.LastNonce); err != nil {
-		return nil, err
+	if info == nil || info.Value == nil {
+		return nil, nil
 	}
-	if err := binary.Read(buf, binary.LittleEndian, &acc.Bump); err != nil {
-		return nil, err
+
+	return ParseUserBalance(info.Value.Data.GetBinary())
+}
+
+// FetchUserBalances enumerates every UserBalance PDA of the program with
+// getProgramAccounts.
+func FetchUserBalances(ctx context.Context, rpcClient ChainClient, programID solana.PublicKey, commitment rpc.CommitmentType) ([]UserBalanceAccount, error) {
+	result, err := rpcClient.GetProgramAccountsWithOpts(ctx, programID, &rpc.GetProgramAccountsOpts{
+		Commitment: commitment,
+		Encoding:   solana.EncodingBase64,
+		Filters: []rpc.RPCFilter{
+			{DataSize: userBalanceSize},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: solana.Base58(UserBalanceDiscriminator[:])}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching user balance accounts: %w", err)
 	}
 
-	return &acc, nil
+	balances := make([]UserBalanceAccount, 0, len(result))
+	for _, keyed := range result {
+		if keyed == nil || keyed.Account == nil {
+			continue
+		}
+		acc, err := ParseUserBalance(keyed.Account.Data.GetBinary())
+		if err != nil {
+			return nil, fmt.Errorf("parsing user balance %s: %w", keyed.Pubkey, err)
+		}
+		balances = append(balances, *acc)
+	}
+	return balances, nil
 }
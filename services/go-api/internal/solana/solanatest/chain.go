@@ -0,0 +1,733 @@
+// Package solanatest provides an in-memory Solana cluster running the casino
+// program, for tests of code that talks to the chain.
+package solanatest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+)
+
+// blockhashValidity is how many blocks a blockhash can be used for.
+const blockhashValidity = 150
+
+type account struct {
+	lamports uint64
+	owner    solana.PublicKey
+	data     []byte
+}
+
+type txRecord struct {
+	sig  solana.Signature
+	tx   *solana.Transaction
+	slot uint64
+	err  interface{}
+	logs []string
+}
+
+// Chain is a single-node cluster that executes the casino program's
+// instructions and system transfers as soon as they are sent. Every sent
+// transaction gets its own slot and is finalized at once, so all commitment
+// levels see the same state. Program errors fail preflight, unless the
+// transaction is sent with SkipPreflight, in which case it lands failed.
+//
+// Chain implements solana_parser.ChainClient and is safe for concurrent use.
+type Chain struct {
+	mu sync.Mutex
+
+	programID solana.PublicKey
+	vault     solana.PublicKey
+
+	accounts   map[solana.PublicKey]*account
+	txs        map[solana.Signature]*txRecord
+	history    []*txRecord // oldest first
+	blockhashs map[solana.Hash]uint64
+	slotTimes  map[uint64]time.Time
+	slot       uint64
+	now        time.Time
+	failSend   error
+}
+
+var _ solana_parser.ChainClient = (*Chain)(nil)
+
+// NewChain creates a cluster with an initialized CasinoVault PDA: operator is
+// its operational authority and signingAuthority the hash of the key that
+// signs withdrawals. The vault holds its rent-exempt minimum plus liquidity.
+func NewChain(programID solana.PublicKey, operator solana.PublicKey, signingAuthority []byte, liquidity uint64) *Chain {
+	vault, _, err := solana.FindProgramAddress([][]byte{[]byte("casino_vault")}, programID)
+	if err != nil {
+		panic(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	c := &Chain{
+		programID:  programID,
+		vault:      vault,
+		accounts:   make(map[solana.PublicKey]*account),
+		txs:        make(map[solana.Signature]*txRecord),
+		blockhashs: make(map[solana.Hash]uint64),
+		slotTimes:  map[uint64]time.Time{0: now},
+		now:        now,
+	}
+
+	data, err := anchor.Casino().EncodeAccount("CasinoVault", map[string]interface{}{
+		"operational_authority": operator,
+		"signing_authority":     signingAuthority,
+		"batch_id_counter":      uint64(0),
+	})
+	if err != nil {
+		panic(err)
+	}
+	c.accounts[vault] = &account{lamports: rentExempt(len(data)) + liquidity, owner: programID, data: data}
+	return c
+}
+
+// Vault is the address of the CasinoVault PDA.
+func (c *Chain) Vault() solana.PublicKey {
+	return c.vault
+}
+
+// Now is the cluster clock, the block time of the latest slot.
+func (c *Chain) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance produces an empty slot d after the latest one.
+func (c *Chain) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.nextSlot()
+}
+
+// Airdrop credits lamports to a system account, creating it if needed.
+func (c *Chain) Airdrop(to solana.PublicKey, lamports uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	acc, ok := c.accounts[to]
+	if !ok {
+		acc = &account{owner: solana.SystemProgramID}
+		c.accounts[to] = acc
+	}
+	acc.lamports += lamports
+}
+
+// Lamports returns the balance of an account, 0 if it does not exist.
+func (c *Chain) Lamports(addr solana.PublicKey) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if acc, ok := c.accounts[addr]; ok {
+		return acc.lamports
+	}
+	return 0
+}
+
+// SetUserBalance writes a user's UserBalance PDA directly, as if earlier
+// deposits and withdrawals had left it in that state.
+func (c *Chain) SetUserBalance(user solana.PublicKey, amount uint64, lastNonce uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pda, err := solana_parser.FindUserBalancePDA(c.programID, user)
+	if err != nil {
+		panic(err)
+	}
+	if err := c.writeUserBalance(pda, user, amount, lastNonce); err != nil {
+		panic(err)
+	}
+}
+
+// SetAccount writes a program-owned account directly, e.g. one holding data
+// the program would never have produced there.
+func (c *Chain) SetAccount(addr solana.PublicKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accounts[addr] = &account{lamports: rentExempt(len(data)), owner: c.programID, data: data}
+}
+
+// FailNextSend makes the next SendTransactionWithOpts return err without
+// executing anything, as an unreachable or rejecting node would.
+func (c *Chain) FailNextSend(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failSend = err
+}
+
+// Sent returns the signatures of every transaction that landed, oldest first.
+func (c *Chain) Sent() []solana.Signature {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sigs := make([]solana.Signature, 0, len(c.history))
+	for _, rec := range c.history {
+		sigs = append(sigs, rec.sig)
+	}
+	return sigs
+}
+
+// Deposit sends a deposit of lamports signed by user, the way the frontend
+// does. The user needs the lamports; see Airdrop.
+func (c *Chain) Deposit(ctx context.Context, user solana.PrivateKey, lamports uint64) (solana.Signature, error) {
+	userBalance, err := solana_parser.FindUserBalancePDA(c.programID, user.PublicKey())
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	ix, err := anchor.Casino().MustInstruction("deposit").Build(c.programID,
+		map[string]solana.PublicKey{
+			"casino_vault": c.vault,
+			"user_balance": userBalance,
+			"user":         user.PublicKey(),
+		},
+		map[string]interface{}{"amount": lamports},
+	)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	return c.SendInstructions(ctx, user, ix)
+}
+
+// SendInstructions signs instructions with payer, the only signer, against
+// the latest blockhash and sends them.
+func (c *Chain) SendInstructions(ctx context.Context, payer solana.PrivateKey, instructions ...solana.Instruction) (solana.Signature, error) {
+	recent, err := c.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(payer.PublicKey()))
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(payer.PublicKey()) {
+			return &payer
+		}
+		return nil
+	}); err != nil {
+		return solana.Signature{}, err
+	}
+	return c.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{})
+}
+
+func (c *Chain) GetAccountInfoWithOpts(_ context.Context, addr solana.PublicKey, _ *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	acc, ok := c.accounts[addr]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{
+		RPCContext: c.context(),
+		Value:      acc.rpcAccount(),
+	}, nil
+}
+
+func (c *Chain) GetProgramAccountsWithOpts(_ context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out rpc.GetProgramAccountsResult
+	for addr, acc := range c.accounts {
+		if !acc.owner.Equals(program) || (opts != nil && !matches(acc.data, opts.Filters)) {
+			continue
+		}
+		out = append(out, &rpc.KeyedAccount{Pubkey: addr, Account: acc.rpcAccount()})
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].Pubkey[:], out[j].Pubkey[:]) < 0 })
+	return out, nil
+}
+
+func matches(data []byte, filters []rpc.RPCFilter) bool {
+	for _, f := range filters {
+		if f.DataSize != 0 && uint64(len(data)) != f.DataSize {
+			return false
+		}
+		if f.Memcmp != nil {
+			end := f.Memcmp.Offset + uint64(len(f.Memcmp.Bytes))
+			if end > uint64(len(data)) || !bytes.Equal(data[f.Memcmp.Offset:end], f.Memcmp.Bytes) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *Chain) GetMinimumBalanceForRentExemption(_ context.Context, dataSize uint64, _ rpc.CommitmentType) (uint64, error) {
+	return rentExempt(int(dataSize)), nil
+}
+
+// rentExempt is the cluster default: two years of 3480 lamports per byte,
+// counting 128 bytes of account overhead.
+func rentExempt(dataSize int) uint64 {
+	return uint64(128+dataSize) * 3480 * 2
+}
+
+func (c *Chain) GetTransaction(_ context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, ok := c.txs[sig]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+
+	raw, err := rec.tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	// The envelope only has unexported fields; fill it the way the RPC
+	// response does.
+	encoded, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	if err != nil {
+		return nil, err
+	}
+	envelope := new(rpc.TransactionResultEnvelope)
+	if err := envelope.UnmarshalJSON(encoded); err != nil {
+		return nil, err
+	}
+
+	blockTime := solana.UnixTimeSeconds(c.slotTimes[rec.slot].Unix())
+	return &rpc.GetTransactionResult{
+		Slot:        rec.slot,
+		BlockTime:   &blockTime,
+		Transaction: envelope,
+		Meta:        &rpc.TransactionMeta{Err: rec.err, Fee: 5000, LogMessages: rec.logs},
+	}, nil
+}
+
+func (c *Chain) GetSignaturesForAddressWithOpts(_ context.Context, addr solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limit := 1000
+	var before, until solana.Signature
+	if opts != nil {
+		if opts.Limit != nil {
+			limit = *opts.Limit
+		}
+		before, until = opts.Before, opts.Until
+	}
+
+	var out []*rpc.TransactionSignature
+	started := before.IsZero()
+	for i := len(c.history) - 1; i >= 0 && len(out) < limit; i-- {
+		rec := c.history[i]
+		if !started {
+			started = rec.sig == before
+			continue
+		}
+		if !until.IsZero() && rec.sig == until {
+			break
+		}
+		if has, _ := rec.tx.Message.HasAccount(addr); !has {
+			continue
+		}
+		blockTime := solana.UnixTimeSeconds(c.slotTimes[rec.slot].Unix())
+		out = append(out, &rpc.TransactionSignature{
+			Err:                rec.err,
+			Signature:          rec.sig,
+			Slot:               rec.slot,
+			BlockTime:          &blockTime,
+			ConfirmationStatus: rpc.ConfirmationStatusFinalized,
+		})
+	}
+	return out, nil
+}
+
+func (c *Chain) GetSignatureStatuses(_ context.Context, _ bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := &rpc.GetSignatureStatusesResult{RPCContext: c.context()}
+	for _, sig := range sigs {
+		rec, ok := c.txs[sig]
+		if !ok {
+			out.Value = append(out.Value, nil)
+			continue
+		}
+		out.Value = append(out.Value, &rpc.SignatureStatusesResult{
+			Slot:               rec.slot,
+			Err:                rec.err,
+			ConfirmationStatus: rpc.ConfirmationStatusFinalized,
+		})
+	}
+	return out, nil
+}
+
+func (c *Chain) GetLatestBlockhash(_ context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], c.slot)
+	hash := solana.Hash(sha256.Sum256(seed[:]))
+	c.blockhashs[hash] = c.slot + blockhashValidity
+
+	return &rpc.GetLatestBlockhashResult{
+		RPCContext: c.context(),
+		Value:      &rpc.LatestBlockhashResult{Blockhash: hash, LastValidBlockHeight: c.slot + blockhashValidity},
+	}, nil
+}
+
+func (c *Chain) SendTransactionWithOpts(_ context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failSend; err != nil {
+		c.failSend = nil
+		return solana.Signature{}, err
+	}
+	if len(tx.Signatures) == 0 {
+		return solana.Signature{}, errors.New("transaction is not signed")
+	}
+	if err := tx.VerifySignatures(); err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid transaction signatures: %w", err)
+	}
+	lastValid, ok := c.blockhashs[tx.Message.RecentBlockhash]
+	if !ok || c.slot > lastValid {
+		return solana.Signature{}, errors.New("Blockhash not found")
+	}
+	sig := tx.Signatures[0]
+	if _, ok := c.txs[sig]; ok {
+		return solana.Signature{}, errors.New("This transaction has already been processed")
+	}
+
+	snapshot := c.cloneAccounts()
+	logs, execErr := c.execute(tx)
+	if execErr != nil {
+		c.accounts = snapshot
+		if !opts.SkipPreflight {
+			return solana.Signature{}, fmt.Errorf("Transaction simulation failed: %w", execErr)
+		}
+	}
+
+	c.nextSlot()
+	rec := &txRecord{sig: sig, tx: tx, slot: c.slot, logs: logs}
+	if execErr != nil {
+		rec.err = map[string]interface{}{"InstructionError": execErr.Error()}
+	}
+	c.txs[sig] = rec
+	c.history = append(c.history, rec)
+	return sig, nil
+}
+
+func (c *Chain) GetBlockHeight(_ context.Context, _ rpc.CommitmentType) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slot, nil
+}
+
+func (c *Chain) GetSlot(_ context.Context, _ rpc.CommitmentType) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slot, nil
+}
+
+func (c *Chain) GetBlockTime(_ context.Context, slot uint64) (*solana.UnixTimeSeconds, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.slotTimes[slot]
+	if !ok {
+		return nil, fmt.Errorf("slot %d was skipped or is not available", slot)
+	}
+	blockTime := solana.UnixTimeSeconds(t.Unix())
+	return &blockTime, nil
+}
+
+func (c *Chain) context() rpc.RPCContext {
+	return rpc.RPCContext{Context: rpc.Context{Slot: c.slot}}
+}
+
+func (c *Chain) nextSlot() {
+	c.slot++
+	c.slotTimes[c.slot] = c.now
+}
+
+func (c *Chain) cloneAccounts() map[solana.PublicKey]*account {
+	clone := make(map[solana.PublicKey]*account, len(c.accounts))
+	for addr, acc := range c.accounts {
+		copied := *acc
+		copied.data = append([]byte(nil), acc.data...)
+		clone[addr] = &copied
+	}
+	return clone
+}
+
+func (a *account) rpcAccount() *rpc.Account {
+	return &rpc.Account{
+		Lamports: a.lamports,
+		Owner:    a.owner,
+		Data:     rpc.DataBytesOrJSONFromBytes(append([]byte(nil), a.data...)),
+		Space:    uint64(len(a.data)),
+	}
+}
+
+// execute runs every instruction of tx in order and returns the program logs.
+// On error the caller rolls the accounts back.
+func (c *Chain) execute(tx *solana.Transaction) ([]string, error) {
+	var logs []string
+	for i, compiled := range tx.Message.Instructions {
+		programID, err := tx.Message.Program(compiled.ProgramIDIndex)
+		if err != nil {
+			return logs, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		metas, err := compiled.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			return logs, fmt.Errorf("instruction %d: %w", i, err)
+		}
+
+		logs = append(logs, fmt.Sprintf("Program %s invoke [1]", programID))
+		switch {
+		case programID.Equals(c.programID):
+			err = c.executeCasino(metas, compiled.Data)
+		case programID.Equals(solana.SystemProgramID):
+			err = c.executeSystem(metas, compiled.Data)
+		default:
+			err = fmt.Errorf("program %s is not deployed", programID)
+		}
+		if err != nil {
+			logs = append(logs, fmt.Sprintf("Program %s failed: %v", programID, err))
+			return logs, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		logs = append(logs, fmt.Sprintf("Program %s success", programID))
+	}
+	return logs, nil
+}
+
+// executeSystem supports the system program's transfer only.
+func (c *Chain) executeSystem(metas []*solana.AccountMeta, data []byte) error {
+	if len(data) != 12 || binary.LittleEndian.Uint32(data) != 2 || len(metas) < 2 {
+		return errors.New("unsupported system instruction")
+	}
+	if !metas[0].IsSigner {
+		return errors.New("missing required signature for instruction")
+	}
+	return c.transfer(metas[0].PublicKey, metas[1].PublicKey, binary.LittleEndian.Uint64(data[4:]))
+}
+
+func (c *Chain) transfer(from, to solana.PublicKey, lamports uint64) error {
+	src, ok := c.accounts[from]
+	if !ok || src.lamports < lamports {
+		return errors.New("insufficient lamports")
+	}
+	dst, ok := c.accounts[to]
+	if !ok {
+		dst = &account{owner: solana.SystemProgramID}
+		c.accounts[to] = dst
+	}
+	src.lamports -= lamports
+	dst.lamports += lamports
+	return nil
+}
+
+// programError is an Anchor custom error of the casino program.
+func programError(name string) error {
+	for _, e := range anchor.Casino().Errors {
+		if e.Name == name {
+			return fmt.Errorf("custom program error: %#x (%s: %s)", e.Code, e.Name, e.Msg)
+		}
+	}
+	return fmt.Errorf("custom program error: %s", name)
+}
+
+func (c *Chain) executeCasino(metas []*solana.AccountMeta, data []byte) error {
+	ix, args, err := anchor.Casino().DecodeInstruction(data)
+	if err != nil {
+		return fmt.Errorf("invalid instruction data: %w", err)
+	}
+	if len(metas) < len(ix.Accounts) {
+		return errors.New("not enough account keys given to the instruction")
+	}
+	accounts := make(map[string]*solana.AccountMeta, len(ix.Accounts))
+	for i, def := range ix.Accounts {
+		meta := metas[i]
+		if def.Signer && !meta.IsSigner {
+			return fmt.Errorf("%s: missing required signature", def.Name)
+		}
+		if def.Writable && !meta.IsWritable {
+			return fmt.Errorf("%s: account is not writable", def.Name)
+		}
+		if def.Address != "" && meta.PublicKey.String() != def.Address {
+			return fmt.Errorf("%s: wrong address", def.Name)
+		}
+		accounts[def.Name] = meta
+	}
+
+	if !accounts["casino_vault"].PublicKey.Equals(c.vault) {
+		return errors.New("casino_vault: seeds constraint was violated")
+	}
+	vault, err := solana_parser.ParseCasinoVault(c.accounts[c.vault].data)
+	if err != nil {
+		return err
+	}
+
+	switch ix.Name {
+	case "deposit":
+		return c.deposit(accounts, vault, args["amount"].(uint64))
+	case "withdraw":
+		return c.withdraw(accounts, vault, args)
+	case "commit_batch_root":
+		return c.commitBatchRoot(accounts, vault, args["batch_id"].(uint64), args["merkle_root"].([]byte))
+	case "update_signing_authority":
+		if !accounts["authority"].PublicKey.Equals(vault.OperationalAuthority) {
+			return programError("Unauthorized")
+		}
+		copy(vault.SigningAuthority[:], args["new_signing_authority"].([]byte))
+		return c.writeVault(vault)
+	default:
+		return fmt.Errorf("instruction %s is not simulated", ix.Name)
+	}
+}
+
+func (c *Chain) deposit(accounts map[string]*solana.AccountMeta, vault *solana_parser.CasinoVaultAccount, amount uint64) error {
+	user := accounts["user"].PublicKey
+	pda, err := solana_parser.FindUserBalancePDA(c.programID, user)
+	if err != nil {
+		return err
+	}
+	if !accounts["user_balance"].PublicKey.Equals(pda) {
+		return errors.New("user_balance: seeds constraint was violated")
+	}
+	if err := c.transfer(user, c.vault, amount); err != nil {
+		return err
+	}
+
+	var balance, lastNonce uint64
+	if acc, ok := c.accounts[pda]; ok {
+		existing, err := solana_parser.ParseUserBalance(acc.data)
+		if err != nil {
+			return err
+		}
+		balance, lastNonce = existing.Amount, existing.LastNonce
+	}
+	if balance+amount < balance {
+		return programError("DepositOverflow")
+	}
+	return c.writeUserBalance(pda, user, balance+amount, lastNonce)
+}
+
+func (c *Chain) withdraw(accounts map[string]*solana.AccountMeta, vault *solana_parser.CasinoVaultAccount, args map[string]interface{}) error {
+	user := accounts["user"].PublicKey
+	pda, err := solana_parser.FindUserBalancePDA(c.programID, user)
+	if err != nil {
+		return err
+	}
+	acc, ok := c.accounts[pda]
+	if !accounts["user_balance"].PublicKey.Equals(pda) || !ok {
+		return errors.New("user_balance: account not initialized")
+	}
+	balance, err := solana_parser.ParseUserBalance(acc.data)
+	if err != nil {
+		return err
+	}
+
+	amount, nonce, expiresAt := args["amount"].(uint64), args["nonce"].(uint64), args["expires_at"].(int64)
+	if nonce != balance.LastNonce+1 {
+		return programError("InvalidNonce")
+	}
+	if c.now.Unix() > expiresAt {
+		return programError("AuthorizationExpired")
+	}
+	hash, err := crypto.WithdrawalHash(user.String(), amount, nonce, expiresAt)
+	if err != nil {
+		return err
+	}
+	authority, err := crypto.RecoverSigningAuthority(hash, args["signature"].([]byte), int(args["recovery_id"].(uint8)))
+	if err != nil || !bytes.Equal(authority, vault.SigningAuthority[:]) {
+		return programError("SignatureVerificationFailed")
+	}
+	if balance.Amount < amount {
+		return programError("InsufficientUserBalance")
+	}
+	if c.accounts[c.vault].lamports < amount {
+		return programError("InsufficientVaultBalance")
+	}
+
+	if err := c.writeUserBalance(pda, user, balance.Amount-amount, nonce); err != nil {
+		return err
+	}
+	c.accounts[c.vault].lamports -= amount
+	c.accounts[user].lamports += amount
+	return nil
+}
+
+func (c *Chain) commitBatchRoot(accounts map[string]*solana.AccountMeta, vault *solana_parser.CasinoVaultAccount, batchID uint64, root []byte) error {
+	authority := accounts["authority"].PublicKey
+	if !authority.Equals(vault.OperationalAuthority) {
+		return programError("Unauthorized")
+	}
+	pda, err := solana_parser.FindBatchCommitPDA(c.programID, int64(batchID))
+	if err != nil {
+		return err
+	}
+	if !accounts["batch_commit"].PublicKey.Equals(pda) {
+		return errors.New("batch_commit: seeds constraint was violated")
+	}
+	if _, exists := c.accounts[pda]; exists {
+		return fmt.Errorf("allocate: account %s already in use", pda)
+	}
+
+	data, err := anchor.Casino().EncodeAccount("BatchCommit", map[string]interface{}{
+		"authority":   authority,
+		"batch_id":    batchID,
+		"merkle_root": root,
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.transfer(authority, pda, rentExempt(len(data))); err != nil {
+		return err
+	}
+	c.accounts[pda].owner = c.programID
+	c.accounts[pda].data = data
+
+	vault.BatchIDCounter++
+	return c.writeVault(vault)
+}
+
+func (c *Chain) writeVault(vault *solana_parser.CasinoVaultAccount) error {
+	data, err := anchor.Casino().EncodeAccount("CasinoVault", map[string]interface{}{
+		"operational_authority": vault.OperationalAuthority,
+		"signing_authority":     vault.SigningAuthority,
+		"batch_id_counter":      vault.BatchIDCounter,
+	})
+	if err != nil {
+		return err
+	}
+	c.accounts[c.vault].data = data
+	return nil
+}
+
+func (c *Chain) writeUserBalance(pda, user solana.PublicKey, amount, lastNonce uint64) error {
+	_, bump, err := solana.FindProgramAddress([][]byte{[]byte("user_balance"), user.Bytes()}, c.programID)
+	if err != nil {
+		return err
+	}
+	data, err := anchor.Casino().EncodeAccount("UserBalance", map[string]interface{}{
+		"user":                  user,
+		"amount":                amount,
+		"last_withdrawal_nonce": lastNonce,
+		"bump":                  bump,
+	})
+	if err != nil {
+		return err
+	}
+	acc, ok := c.accounts[pda]
+	if !ok {
+		acc = &account{lamports: rentExempt(len(data)), owner: c.programID}
+		c.accounts[pda] = acc
+	}
+	acc.data = data
+	return nil
+}
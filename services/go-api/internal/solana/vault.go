@@ -0,0 +1,75 @@
+package solana_parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+)
+
+// CasinoVaultAccount mirrors the program's CasinoVault PDA.
+type CasinoVaultAccount struct {
+	Discriminator        [8]byte
+	OperationalAuthority solana.PublicKey `anchor:"operational_authority"`
+	SigningAuthority     [32]byte         `anchor:"signing_authority"`
+	BatchIDCounter       uint64           `anchor:"batch_id_counter"`
+}
+
+func ParseCasinoVault(data []byte) (*CasinoVaultAccount, error) {
+	var acc CasinoVaultAccount
+	if err := anchor.Casino().DecodeAccountInto("CasinoVault", data, &acc); err != nil {
+		return nil, err
+	}
+	copy(acc.Discriminator[:], data)
+	return &acc, nil
+}
+
+// FetchCasinoVault reads and parses the CasinoVault account at vault.
+func FetchCasinoVault(ctx context.Context, rpcClient ChainClient, vault solana.PublicKey, commitment rpc.CommitmentType) (*CasinoVaultAccount, error) {
+	info, err := rpcClient.GetAccountInfoWithOpts(ctx, vault, &rpc.GetAccountInfoOpts{Commitment: commitment})
+	if err != nil {
+		return nil, fmt.Errorf("fetching casino vault account: %w", err)
+	}
+	if info == nil || info.Value == nil {
+		return nil, fmt.Errorf("casino vault account %s not found", vault)
+	}
+	return ParseCasinoVault(info.Value.Data.GetBinary())
+}
+
+// NewUpdateSigningAuthorityInstruction builds update_signing_authority, which
+// the vault's operational authority signs.
+func NewUpdateSigningAuthorityInstruction(programID solana.PublicKey, vault solana.PublicKey, operationalAuthority solana.PublicKey, newAuthority []byte) (solana.Instruction, error) {
+	return anchor.Casino().MustInstruction("update_signing_authority").Build(programID,
+		map[string]solana.PublicKey{
+			"casino_vault": vault,
+			"authority":    operationalAuthority,
+		},
+		map[string]interface{}{"new_signing_authority": newAuthority},
+	)
+}
+
+// LoadKeypair reads the server's operational wallet, a Solana CLI keypair
+// file. SERVER_WALLET_JSON, if set, holds the file contents instead.
+func LoadKeypair(path string) (solana.PrivateKey, error) {
+	var walletBytes []byte
+	var err error
+
+	if rawJSON := os.Getenv("SERVER_WALLET_JSON"); rawJSON != "" {
+		walletBytes = []byte(rawJSON)
+	} else {
+		walletBytes, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read server wallet: %w", err)
+		}
+	}
+
+	var keyInts []uint8
+	if err := json.Unmarshal(walletBytes, &keyInts); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet json: %w", err)
+	}
+	return solana.PrivateKey(keyInts), nil
+}
@@ -0,0 +1,52 @@
+package solana_parser
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+)
+
+var withdrawDef = anchor.Casino().MustInstruction("withdraw")
+
+// WithdrawDiscriminator is the Anchor sighash of the casino program's withdraw instruction.
+var WithdrawDiscriminator = [8]byte(withdrawDef.Discriminator)
+
+// WithdrawInstruction is one decoded withdraw call. The signature and recovery
+// id arguments are not kept.
+type WithdrawInstruction struct {
+	Index     int
+	User      solana.PublicKey
+	Amount    uint64 `anchor:"amount"`
+	Nonce     uint64 `anchor:"nonce"`
+	ExpiresAt int64  `anchor:"expires_at"`
+}
+
+// ParseWithdrawInstructions returns every top-level withdraw instruction of the
+// casino program in tx, in instruction order.
+func ParseWithdrawInstructions(tx *solana.Transaction, programID solana.PublicKey) ([]WithdrawInstruction, error) {
+	keys := tx.Message.AccountKeys
+
+	var withdrawals []WithdrawInstruction
+	for i, ix := range tx.Message.Instructions {
+		if int(ix.ProgramIDIndex) >= len(keys) || !keys[ix.ProgramIDIndex].Equals(programID) {
+			continue
+		}
+		if !withdrawDef.Matches(ix.Data) {
+			continue
+		}
+
+		w := WithdrawInstruction{Index: i}
+		if err := withdrawDef.DecodeArgs(ix.Data, &w); err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		user, err := instructionAccount(keys, ix, withdrawDef, "user")
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		w.User = user
+
+		withdrawals = append(withdrawals, w)
+	}
+	return withdrawals, nil
+}
@@ -0,0 +1,228 @@
+package txconfirm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+)
+
+var (
+	// ErrBlockhashExpired means the transaction can no longer land: the chain
+	// has moved past the last block height its blockhash is valid for.
+	ErrBlockhashExpired = errors.New("blockhash expired before the transaction was confirmed")
+	// ErrNotConfirmed means the signature did not reach the commitment level in time.
+	ErrNotConfirmed = errors.New("transaction was not confirmed in time")
+)
+
+// TxFailedError is returned when the transaction landed but its execution failed.
+// Resending the same transaction will not help.
+type TxFailedError struct {
+	Signature solana.Signature
+	Err       interface{}
+}
+
+func (e *TxFailedError) Error() string {
+	return fmt.Sprintf("transaction %s failed: %v", e.Signature, e.Err)
+}
+
+// BuildFunc builds and signs a transaction against the given blockhash. It is
+// called again with a fresh blockhash every time the previous one expires.
+type BuildFunc func(blockhash solana.Hash) (*solana.Transaction, error)
+
+// Confirmation describes a transaction that reached the tracker's commitment level.
+type Confirmation struct {
+	Signature solana.Signature
+	Slot      uint64
+	Status    rpc.ConfirmationStatusType
+}
+
+// Tracker sends transactions and waits until they reach a commitment level,
+// resending with a fresh blockhash whenever the previous one expires.
+type Tracker struct {
+	rpcClient    solana_parser.ChainClient
+	commitment   rpc.CommitmentType
+	pollInterval time.Duration
+	maxSends     int
+}
+
+func NewTracker(rpcClient solana_parser.ChainClient, commitment rpc.CommitmentType) *Tracker {
+	if commitment == "" {
+		commitment = rpc.CommitmentConfirmed
+	}
+	return &Tracker{
+		rpcClient:    rpcClient,
+		commitment:   commitment,
+		pollInterval: 2 * time.Second,
+		maxSends:     3,
+	}
+}
+
+// Commitment returns the level the tracker waits for.
+func (t *Tracker) Commitment() rpc.CommitmentType {
+	return t.commitment
+}
+
+// SendAndConfirm builds, sends and confirms a transaction. onSent (optional) is
+// called with every signature right after it is sent, so callers can persist it
+// before waiting; an error from onSent aborts without waiting.
+func (t *Tracker) SendAndConfirm(ctx context.Context, build BuildFunc, onSent func(solana.Signature) error) (*Confirmation, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < t.maxSends; attempt++ {
+		recent, err := t.rpcClient.GetLatestBlockhash(ctx, t.commitment)
+		if err != nil {
+			return nil, fmt.Errorf("fetching blockhash: %w", err)
+		}
+
+		tx, err := build(recent.Value.Blockhash)
+		if err != nil {
+			return nil, fmt.Errorf("building transaction: %w", err)
+		}
+
+		sig, err := t.rpcClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+			PreflightCommitment: t.commitment,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sending transaction: %w", err)
+		}
+
+		if onSent != nil {
+			if err := onSent(sig); err != nil {
+				return nil, err
+			}
+		}
+
+		conf, err := t.Confirm(ctx, sig, recent.Value.LastValidBlockHeight)
+		if errors.Is(err, ErrBlockhashExpired) {
+			lastErr = err
+			continue
+		}
+		return conf, err
+	}
+
+	return nil, fmt.Errorf("gave up after %d sends: %w", t.maxSends, lastErr)
+}
+
+// Confirm polls the signature until it reaches the tracker's commitment level,
+// fails, or the block height passes lastValidBlockHeight.
+func (t *Tracker) Confirm(ctx context.Context, sig solana.Signature, lastValidBlockHeight uint64) (*Confirmation, error) {
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Read the height before the status: if the signature is still unknown
+		// once the height is past the limit, it can no longer land.
+		height, err := t.rpcClient.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+		if err != nil {
+			return nil, fmt.Errorf("fetching block height: %w", err)
+		}
+
+		conf, err := t.Status(ctx, sig)
+		if err != nil {
+			return nil, err
+		}
+		if conf != nil && t.reached(conf.Status) {
+			return conf, nil
+		}
+		if conf == nil && height > lastValidBlockHeight {
+			return nil, ErrBlockhashExpired
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Await waits up to timeout for a transaction sent by someone else, where the
+// blockhash it was built with is not known.
+func (t *Tracker) Await(ctx context.Context, sig solana.Signature, timeout time.Duration) (*Confirmation, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		conf, err := t.Status(ctx, sig)
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+		if conf != nil && t.reached(conf.Status) {
+			return conf, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrNotConfirmed
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the current status of a signature, or nil if the cluster does
+// not know it. A transaction that landed with an error is a *TxFailedError.
+func (t *Tracker) Status(ctx context.Context, sig solana.Signature) (*Confirmation, error) {
+	res, err := t.rpcClient.GetSignatureStatuses(ctx, true, sig)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching signature status: %w", err)
+	}
+	if len(res.Value) == 0 || res.Value[0] == nil {
+		return nil, nil
+	}
+
+	status := res.Value[0]
+	if status.Err != nil {
+		return nil, &TxFailedError{Signature: sig, Err: status.Err}
+	}
+
+	return &Confirmation{
+		Signature: sig,
+		Slot:      status.Slot,
+		Status:    status.ConfirmationStatus,
+	}, nil
+}
+
+// FinalizedTime returns the block time of the latest finalized slot. It is the
+// clock on-chain programs saw, and no fork can still land a transaction before it.
+func (t *Tracker) FinalizedTime(ctx context.Context) (time.Time, error) {
+	slot, err := t.rpcClient.GetSlot(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetching finalized slot: %w", err)
+	}
+	blockTime, err := t.rpcClient.GetBlockTime(ctx, slot)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetching block time: %w", err)
+	}
+	if blockTime == nil {
+		return time.Time{}, fmt.Errorf("no block time for slot %d", slot)
+	}
+	return blockTime.Time(), nil
+}
+
+func (t *Tracker) reached(status rpc.ConfirmationStatusType) bool {
+	return rank(status) >= rank(rpc.ConfirmationStatusType(t.commitment))
+}
+
+func rank(status rpc.ConfirmationStatusType) int {
+	switch status {
+	case rpc.ConfirmationStatusProcessed:
+		return 1
+	case rpc.ConfirmationStatusConfirmed:
+		return 2
+	case rpc.ConfirmationStatusFinalized:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,289 @@
+package verify
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/shopspring/decimal"
+)
+
+// Request is a spin record plus its Merkle proof, in the same shape the
+// /game/proof/:spin_id endpoint returns it.
+type Request struct {
+	Spin       domain.Spin `json:"spin"`
+	BatchID    *int64      `json:"batch_id"`
+	TreeFormat int         `json:"tree_format"`
+	MerkleRoot *string     `json:"merkle_root"`
+	// Proof is a list of sibling hashes for MerkleFormatLegacy batches and a
+	// list of crypto.MerkleProofStep for MerkleFormatRFC6962 batches.
+	Proof     json.RawMessage `json:"proof"`
+	LeafIndex *int            `json:"leaf_index"`
+
+	// CommittedSeedHash is the server seed hash the player was shown before
+	// the spin: at session start, or as the next hash of the previous spin.
+	// It is the player's own record, not part of the server's response.
+	CommittedSeedHash string `json:"committed_seed_hash,omitempty"`
+}
+
+// Report lists the outcome of every individual check. Valid is true only if
+// every check that could be performed passed.
+type Report struct {
+	Valid bool `json:"valid"`
+
+	SeedValid    bool `json:"seed_valid"`
+	OutcomeValid bool `json:"outcome_valid"`
+	PayoutValid  bool `json:"payout_valid"`
+	LeafValid    bool `json:"leaf_valid"`
+	ProofValid   bool `json:"proof_valid"`
+
+	// SeedCommitmentChecked is set when SeedValid was also checked against
+	// Request.CommittedSeedHash.
+	SeedCommitmentChecked bool `json:"seed_commitment_checked"`
+
+	OnChainChecked   bool `json:"onchain_checked"`
+	OnChainRootValid bool `json:"onchain_root_valid"`
+
+	ComputedReels    []int    `json:"computed_reels"`
+	ComputedPayout   string   `json:"computed_payout"`
+	ComputedLeafHash string   `json:"computed_leaf_hash"`
+	ComputedRoot     string   `json:"computed_root,omitempty"`
+	OnChainRoot      string   `json:"onchain_root,omitempty"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+type Verifier struct {
+	games     *game.Registry
+	rpcClient solana_parser.ChainClient
+	programID solana.PublicKey
+}
+
+// NewVerifier creates a verifier. rpcClient may be nil, in which case the
+// on-chain root comparison is skipped.
+func NewVerifier(games *game.Registry, rpcClient solana_parser.ChainClient, programIDStr string) (*Verifier, error) {
+	v := &Verifier{
+		games:     games,
+		rpcClient: rpcClient,
+	}
+	if rpcClient != nil {
+		programID, err := solana.PublicKeyFromBase58(programIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid program ID: %w", err)
+		}
+		v.programID = programID
+	}
+	return v, nil
+}
+
+// LeafHash rebuilds the Merkle leaf committed for a spin:
+// sha256("wallet:nonce:server_seed:client_seed:bet:matrix_json:payout").
+func LeafHash(walletAddress string, nonce int64, serverSeed string, clientSeed string, betAmount decimal.Decimal, matrix [][]game.Symbol, payout decimal.Decimal) string {
+	outcomeBytes, _ := json.Marshal(matrix)
+	canonicalString := fmt.Sprintf("%s:%d:%s:%s:%s:%s:%s",
+		walletAddress,
+		nonce,
+		serverSeed,
+		clientSeed,
+		betAmount.String(),
+		string(outcomeBytes),
+		payout.String(),
+	)
+	return hex.EncodeToString(crypto.HashDataSHA256([]byte(canonicalString)))
+}
+
+// SpinLeafHash rebuilds the leaf of spin for a tree of the given format.
+// MerkleFormatRFC6962V2 leaves are
+// sha256("wallet:nonce:game_id:game_version:server_seed_hash:server_seed:client_seed:bet:matrix_json:payout");
+// older formats use LeafHash.
+func SpinLeafHash(format crypto.MerkleFormat, spin *domain.Spin, matrix [][]game.Symbol, payout decimal.Decimal) string {
+	if format < crypto.MerkleFormatRFC6962V2 {
+		return LeafHash(spin.WalletAddress, spin.SpinNonce, spin.ServerSeed, spin.ClientSeed, spin.BetAmount, matrix, payout)
+	}
+	outcomeBytes, _ := json.Marshal(matrix)
+	canonicalString := fmt.Sprintf("%s:%d:%s:%d:%s:%s:%s:%s:%s:%s",
+		spin.WalletAddress,
+		spin.SpinNonce,
+		spin.GameID,
+		spin.GameVersion,
+		spin.ServerSeedHash,
+		spin.ServerSeed,
+		spin.ClientSeed,
+		spin.BetAmount.String(),
+		string(outcomeBytes),
+		payout.String(),
+	)
+	return hex.EncodeToString(crypto.HashDataSHA256([]byte(canonicalString)))
+}
+
+// Verify replays the spin and checks it against the committed seed hash, the
+// recorded leaf, the supplied proof and, when configured, the on-chain root.
+func (v *Verifier) Verify(ctx context.Context, req *Request) (*Report, error) {
+	spin := req.Spin
+	report := &Report{}
+
+	gameID := spin.GameID
+	if gameID == "" {
+		gameID = game.DefaultGameID
+	}
+	version := spin.GameVersion
+	if version == 0 {
+		version = 1
+	}
+	def, err := v.games.Get(gameID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// server_seed_hash comes from the same record as the seed, so on its own
+	// this only proves the record is consistent. The player's commitment ties
+	// the seed to what was promised before the spin; v2 leaves also bind the
+	// hash into the committed root.
+	report.SeedValid = crypto.HashStringSHA256(spin.ServerSeed) == spin.ServerSeedHash
+	if !report.SeedValid {
+		report.Errors = append(report.Errors, "server_seed does not hash to server_seed_hash")
+	}
+	if req.CommittedSeedHash != "" {
+		report.SeedCommitmentChecked = true
+		if spin.ServerSeedHash != req.CommittedSeedHash {
+			report.SeedValid = false
+			report.Errors = append(report.Errors, "server_seed_hash differs from the hash committed before the spin")
+		}
+	}
+
+	result, err := game.CalculateSpin(def, spin.ServerSeed, spin.ClientSeed, spin.SpinNonce, spin.BetAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay spin: %w", err)
+	}
+
+	for _, row := range result.Matrix {
+		for _, sym := range row {
+			report.ComputedReels = append(report.ComputedReels, int(sym))
+		}
+	}
+	report.OutcomeValid = equalInts(report.ComputedReels, spin.Outcome.Reels)
+	if !report.OutcomeValid {
+		report.Errors = append(report.Errors, "recorded reels differ from the replayed outcome")
+	}
+
+	payout := game.RoundPayout(result.TotalPayout)
+	report.ComputedPayout = payout.String()
+	report.PayoutValid = payout.Equal(spin.PayoutAmount)
+	if !report.PayoutValid {
+		report.Errors = append(report.Errors, "recorded payout differs from the replayed payout")
+	}
+
+	format := crypto.MerkleFormat(req.TreeFormat)
+	leafPayout := payout
+	if format < crypto.MerkleFormatRFC6962V2 {
+		// Older leaves hashed the payout before it was rounded.
+		leafPayout = result.TotalPayout
+	}
+	report.ComputedLeafHash = SpinLeafHash(format, &spin, result.Matrix, leafPayout)
+	report.LeafValid = report.ComputedLeafHash == spin.LeafHash
+	if !report.LeafValid {
+		report.Errors = append(report.Errors, "recorded leaf_hash differs from the rebuilt leaf")
+	}
+
+	if req.MerkleRoot == nil || len(req.Proof) == 0 {
+		report.Errors = append(report.Errors, "no merkle proof supplied")
+	} else {
+		root, err := computeRoot(req, report.ComputedLeafHash)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("invalid proof: %v", err))
+		} else {
+			report.ComputedRoot = root
+			report.ProofValid = root == *req.MerkleRoot
+			if !report.ProofValid {
+				report.Errors = append(report.Errors, "proof does not lead to merkle_root")
+			}
+		}
+	}
+
+	if v.rpcClient != nil && req.BatchID != nil {
+		onChainRoot, err := v.fetchBatchRoot(ctx, *req.BatchID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("on-chain check failed: %v", err))
+		} else {
+			report.OnChainChecked = true
+			report.OnChainRoot = onChainRoot
+			report.OnChainRootValid = report.ComputedRoot != "" && onChainRoot == report.ComputedRoot
+			if !report.OnChainRootValid {
+				report.Errors = append(report.Errors, "on-chain BatchCommit root does not match the proof")
+			}
+		}
+	}
+
+	report.Valid = report.SeedValid && report.OutcomeValid && report.PayoutValid &&
+		report.LeafValid && report.ProofValid &&
+		(!report.OnChainChecked || report.OnChainRootValid)
+
+	return report, nil
+}
+
+// computeRoot walks the proof in the tree format the batch was committed with.
+// Batches without a tree_format predate the field and use the legacy format.
+func computeRoot(req *Request, leafHash string) (string, error) {
+	switch crypto.MerkleFormat(req.TreeFormat) {
+	case 0, crypto.MerkleFormatLegacy:
+		if req.LeafIndex == nil {
+			return "", fmt.Errorf("leaf_index is required for legacy proofs")
+		}
+		var proof []string
+		if err := json.Unmarshal(req.Proof, &proof); err != nil {
+			return "", err
+		}
+		return crypto.ComputeRootFromProof(leafHash, proof, *req.LeafIndex)
+	case crypto.MerkleFormatRFC6962, crypto.MerkleFormatRFC6962V2:
+		var proof []crypto.MerkleProofStep
+		if err := json.Unmarshal(req.Proof, &proof); err != nil {
+			return "", err
+		}
+		return crypto.ComputeRootFromProofRFC6962(leafHash, proof)
+	default:
+		return "", fmt.Errorf("unknown tree format %d", req.TreeFormat)
+	}
+}
+
+func (v *Verifier) fetchBatchRoot(ctx context.Context, batchID int64) (string, error) {
+	pda, err := solana_parser.FindBatchCommitPDA(v.programID, batchID)
+	if err != nil {
+		return "", err
+	}
+
+	accountInfo, err := v.rpcClient.GetAccountInfoWithOpts(ctx, pda, &rpc.GetAccountInfoOpts{})
+	if err != nil {
+		return "", err
+	}
+	if accountInfo == nil || accountInfo.Value == nil {
+		return "", fmt.Errorf("batch %d is not committed on chain", batchID)
+	}
+
+	commit, err := solana_parser.ParseBatchCommit(accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return "", err
+	}
+	if commit.BatchID != uint64(batchID) {
+		return "", fmt.Errorf("BatchCommit account holds batch %d, expected %d", commit.BatchID, batchID)
+	}
+
+	return hex.EncodeToString(commit.MerkleRoot[:]), nil
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/game"
+	"github.com/shopspring/decimal"
+)
+
+func TestVerifySpinRoundTrip(t *testing.T) {
+	games, err := game.NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+	def, _ := games.Latest(game.DefaultGameID)
+
+	serverSeed, _ := crypto.GenerateSeed()
+	bet := decimal.RequireFromString("0.25")
+	wallet := "AMyC4nrskq9PERnZfFZv3KRhEm23VUpRV4VrggAjYiiU"
+
+	result, err := game.CalculateSpin(def, serverSeed, "client-seed", 3, bet)
+	if err != nil {
+		t.Fatalf("Spin failed: %v", err)
+	}
+
+	var reels []int
+	for _, row := range result.Matrix {
+		for _, sym := range row {
+			reels = append(reels, int(sym))
+		}
+	}
+
+	spin := domain.Spin{
+		WalletAddress:  wallet,
+		SpinNonce:      3,
+		GameID:         def.ID,
+		GameVersion:    def.Version,
+		ServerSeed:     serverSeed,
+		ClientSeed:     "client-seed",
+		ServerSeedHash: crypto.HashStringSHA256(serverSeed),
+		BetAmount:      bet,
+		PayoutAmount:   result.TotalPayout,
+		Outcome:        domain.SpinOutcome{Reels: reels, Rows: def.Rows},
+		LeafHash:       LeafHash(wallet, 3, serverSeed, "client-seed", bet, result.Matrix, result.TotalPayout),
+	}
+
+	leaves := []string{
+		crypto.HashStringSHA256("a"),
+		spin.LeafHash,
+		crypto.HashStringSHA256("b"),
+	}
+	root, _ := crypto.ComputeMerkleRootRFC6962(leaves)
+	proof, _ := crypto.GenerateMerkleProofRFC6962(leaves, 1)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"spin":        spin,
+		"tree_format": crypto.MerkleFormatRFC6962,
+		"merkle_root": root,
+		"proof":       proof,
+		"leaf_index":  1,
+	})
+	var req Request
+	if err := json.Unmarshal(payload, &req); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	verifier, _ := NewVerifier(games, nil, "")
+	report, err := verifier.Verify(context.Background(), &req)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.Valid {
+		t.Fatalf("Expected a valid report, got %+v", report)
+	}
+
+	// A record rewritten with another seed and its hash is only caught
+	// against the hash the player was shown before the spin.
+	forged := req
+	forged.Spin.ServerSeed, _ = crypto.GenerateSeed()
+	forged.Spin.ServerSeedHash = crypto.HashStringSHA256(forged.Spin.ServerSeed)
+	forged.CommittedSeedHash = spin.ServerSeedHash
+	report, err = verifier.Verify(context.Background(), &forged)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.SeedValid || !report.SeedCommitmentChecked {
+		t.Errorf("Expected a seed not matching the commitment to be rejected, got %+v", report)
+	}
+
+	req.Spin.PayoutAmount = req.Spin.PayoutAmount.Add(decimal.NewFromInt(1))
+	report, _ = verifier.Verify(context.Background(), &req)
+	if report.Valid || report.PayoutValid {
+		t.Errorf("Expected tampered payout to be rejected, got %+v", report)
+	}
+}
+
+func TestVerifyV2Leaf(t *testing.T) {
+	games, err := game.NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("Registry failed: %v", err)
+	}
+	def, _ := games.Latest(game.DefaultGameID)
+	serverSeed, _ := crypto.GenerateSeed()
+	bet := decimal.RequireFromString("0.123456789")
+
+	// Find a win whose payout is finer than a lamport.
+	var result *game.SpinResult
+	nonce := int64(0)
+	for nonce = 1; nonce < 100_000; nonce++ {
+		result, err = game.CalculateSpin(def, serverSeed, "client-seed", nonce, bet)
+		if err != nil {
+			t.Fatalf("Spin failed: %v", err)
+		}
+		if !game.RoundPayout(result.TotalPayout).Equal(result.TotalPayout) {
+			break
+		}
+	}
+	if nonce == 100_000 {
+		t.Fatalf("No payout with more than 9 decimals found")
+	}
+
+	var reels []int
+	for _, row := range result.Matrix {
+		for _, sym := range row {
+			reels = append(reels, int(sym))
+		}
+	}
+	spin := domain.Spin{
+		WalletAddress:  "AMyC4nrskq9PERnZfFZv3KRhEm23VUpRV4VrggAjYiiU",
+		SpinNonce:      nonce,
+		GameID:         def.ID,
+		GameVersion:    def.Version,
+		ServerSeed:     serverSeed,
+		ClientSeed:     "client-seed",
+		ServerSeedHash: crypto.HashStringSHA256(serverSeed),
+		BetAmount:      bet,
+		PayoutAmount:   game.RoundPayout(result.TotalPayout),
+		Outcome:        domain.SpinOutcome{Reels: reels, Rows: def.Rows},
+	}
+	spin.LeafHash = SpinLeafHash(crypto.MerkleFormatRFC6962V2, &spin, result.Matrix, spin.PayoutAmount)
+
+	leaves := []string{spin.LeafHash, crypto.HashStringSHA256("other")}
+	root, _ := crypto.ComputeMerkleRootRFC6962(leaves)
+	proof, _ := crypto.GenerateMerkleProofRFC6962(leaves, 0)
+	proofJSON, _ := json.Marshal(proof)
+	req := Request{Spin: spin, TreeFormat: int(crypto.MerkleFormatRFC6962V2), MerkleRoot: &root, Proof: proofJSON}
+
+	verifier, _ := NewVerifier(games, nil, "")
+	report, err := verifier.Verify(context.Background(), &req)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.Valid {
+		t.Fatalf("Expected a valid report, got %+v", report)
+	}
+
+	// The leaf binds the paytable version the spin was scored with.
+	other := spin
+	other.GameVersion++
+	if SpinLeafHash(crypto.MerkleFormatRFC6962V2, &other, result.Matrix, spin.PayoutAmount) == spin.LeafHash {
+		t.Errorf("Expected the game version to change the leaf")
+	}
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/service"
+)
+
+const balanceReconcileInterval = 15 * time.Minute
+
+// BalanceReconciler writes a reconciliation report between Postgres and the
+// on-chain UserBalance accounts on a schedule.
+type BalanceReconciler struct {
+	reconciliation *service.ReconciliationService
+}
+
+func NewBalanceReconciler(reconciliation *service.ReconciliationService) *BalanceReconciler {
+	return &BalanceReconciler{reconciliation: reconciliation}
+}
+
+// Start runs the background loop
+func (b *BalanceReconciler) Start(ctx context.Context) {
+	ticker := time.NewTicker(balanceReconcileInterval)
+	defer ticker.Stop()
+
+	log.Println("⚖️  Balance Reconciler Started")
+
+	for {
+		b.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("⚖️  Balance Reconciler stopping...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *BalanceReconciler) run(ctx context.Context) {
+	run, err := b.reconciliation.Run(ctx)
+	if err != nil {
+		log.Printf("❌ Balance Reconciler Error: %v\n", err)
+		return
+	}
+
+	if run.Discrepancies > 0 {
+		log.Printf("⚠️  Reconciliation #%d: %d of %d wallets disagree with their UserBalance accounts",
+			run.RunID, run.Discrepancies, run.Wallets)
+	} else {
+		log.Printf("⚖️  Reconciliation #%d: %d wallets match their UserBalance accounts", run.RunID, run.Wallets)
+	}
+	if !run.LiabilitiesCovered {
+		log.Printf("🚨 Reconciliation #%d: vault liquidity %s SOL does not cover liabilities %s SOL",
+			run.RunID, run.VaultLiquidity, run.Liabilities)
+	}
+}
@@ -2,48 +2,38 @@ package worker
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
 	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
 )
 
 type BatchCommitter struct {
 	repo         repository.Repository
-	rpcClient    *rpc.Client
+	rpcClient    solana_parser.ChainClient
+	tracker      *txconfirm.Tracker
 	serverWallet solana.PrivateKey
 	programID    solana.PublicKey
 	vaultAddress solana.PublicKey
 }
 
 // NewBatchCommitter loads the keypair and configures the Solana client
-func NewBatchCommitter(repo repository.Repository, rpcURL string, keypairPath string, programIDStr string, vaultAddrStr string) (*BatchCommitter, error) {
-	var walletBytes []byte
-	var err error
-
-	if rawJSON := os.Getenv("SERVER_WALLET_JSON"); rawJSON != "" {
-		walletBytes = []byte(rawJSON)
-	} else {
-		walletBytes, err = os.ReadFile(keypairPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read server wallet: %w", err)
-		}
-	}
-
-	var keyInts []uint8
-	if err := json.Unmarshal(walletBytes, &keyInts); err != nil {
-		return nil, fmt.Errorf("failed to parse wallet json: %w", err)
+func NewBatchCommitter(repo repository.Repository, rpcClient solana_parser.ChainClient, tracker *txconfirm.Tracker, keypairPath string, programIDStr string, vaultAddrStr string) (*BatchCommitter, error) {
+	serverWallet, err := solana_parser.LoadKeypair(keypairPath)
+	if err != nil {
+		return nil, err
 	}
-	serverWallet := solana.PrivateKey(keyInts)
 
 	progID, err := solana.PublicKeyFromBase58(programIDStr)
 	if err != nil {
@@ -56,13 +46,24 @@ func NewBatchCommitter(repo repository.Repository, rpcURL string, keypairPath st
 
 	return &BatchCommitter{
 		repo:         repo,
-		rpcClient:    rpc.New(rpcURL),
+		rpcClient:    rpcClient,
+		tracker:      tracker,
 		serverWallet: serverWallet,
 		programID:    progID,
 		vaultAddress: vaultAddr,
 	}, nil
 }
 
+const (
+	batchSize        = 1000
+	maxBatchAttempts = 10
+	baseRetryDelay   = 30 * time.Second
+	maxRetryDelay    = 30 * time.Minute
+	// A blockhash expires after ~150 slots (~60-90s); a SUBMITTED batch left
+	// behind by a crashed or failed confirmation is sent again after this long.
+	resubmitAfter = 2 * time.Minute
+)
+
 // Start runs the background loop
 func (b *BatchCommitter) Start(ctx context.Context) {
 	ticker := time.NewTicker(60 * time.Second)
@@ -70,22 +71,47 @@ func (b *BatchCommitter) Start(ctx context.Context) {
 
 	log.Println("👷 Batch Committer Worker Started")
 
+	// Pick up batches stranded by a previous run before waiting for the first tick.
+	b.tick(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("👷 Worker stopping...")
 			return
 		case <-ticker.C:
-			if err := b.processBatch(ctx); err != nil {
-				log.Printf("❌ Batch Error: %v\n", err)
-			}
+			b.tick(ctx)
+		}
+	}
+}
+
+func (b *BatchCommitter) tick(ctx context.Context) {
+	if err := b.resumePendingBatches(ctx); err != nil {
+		log.Printf("❌ Batch Error: %v\n", err)
+	}
+	if err := b.processBatch(ctx); err != nil {
+		log.Printf("❌ Batch Error: %v\n", err)
+	}
+}
+
+// resumePendingBatches retries every OPEN or SUBMITTED batch whose backoff has elapsed
+func (b *BatchCommitter) resumePendingBatches(ctx context.Context) error {
+	batches, err := b.repo.GetPendingBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching pending batches: %w", err)
+	}
+
+	for i := range batches {
+		if err := b.commitBatch(ctx, &batches[i]); err != nil {
+			log.Printf("❌ Batch #%d (attempt %d): %v\n", batches[i].BatchID, batches[i].Attempts+1, err)
 		}
 	}
+	return nil
 }
 
-// processBatch orchestrates the fetching, hashing, and blockchain submission
+// processBatch opens a new batch from unbatched spins and tries to commit it
 func (b *BatchCommitter) processBatch(ctx context.Context) error {
-	spins, err := b.repo.GetUnbatchedSpins(ctx, 1000)
+	spins, err := b.repo.GetUnbatchedSpins(ctx, batchSize)
 	if err != nil {
 		return fmt.Errorf("fetching spins: %w", err)
 	}
@@ -93,111 +119,269 @@ func (b *BatchCommitter) processBatch(ctx context.Context) error {
 		return nil
 	}
 
-	log.Printf("Processing new batch with %d spins...", len(spins))
+	// A batch only takes leaves built for its format; spins of another format
+	// go into the next batch.
+	format := spins[0].LeafFormat
+	spinIDs := make([]string, 0, len(spins))
+	for _, s := range spins {
+		if s.LeafFormat == format {
+			spinIDs = append(spinIDs, s.SpinID.String())
+		}
+	}
+
+	log.Printf("Processing new batch with %d spins...", len(spinIDs))
 
-	batch, err := b.repo.CreateBatch(ctx)
+	batch, err := b.repo.CreateBatch(ctx, format, spinIDs)
 	if err != nil {
 		return fmt.Errorf("creating batch DB record: %w", err)
 	}
 
-	for _, s := range spins {
-		if err := b.repo.AddSpinToBatch(ctx, s.SpinID.String(), batch.BatchID); err != nil {
-			return fmt.Errorf("linking spin to batch: %w", err)
+	return b.commitBatch(ctx, batch)
+}
+
+// commitBatch moves a batch along OPEN -> SUBMITTED -> COMMITTED. It is safe to
+// call repeatedly: the BatchCommit PDA is checked before anything is sent, so a
+// batch_id is never committed twice. A batch is only COMMITTED once its
+// transaction reaches the tracker's commitment level.
+func (b *BatchCommitter) commitBatch(ctx context.Context, batch *domain.Batch) error {
+	spins, err := b.repo.GetBatchSpins(ctx, batch.BatchID)
+	if err != nil {
+		return fmt.Errorf("fetching batch spins: %w", err)
+	}
+
+	rootHex, proofs, err := buildBatchProofs(crypto.MerkleFormat(batch.TreeFormat), batch.BatchID, spins)
+	if err != nil {
+		return b.fail(ctx, batch, fmt.Errorf("calculating merkle root: %w", err), true)
+	}
+	if batch.MerkleRoot != nil && *batch.MerkleRoot != rootHex {
+		return b.fail(ctx, batch, fmt.Errorf("recomputed root %s differs from submitted root %s", rootHex, *batch.MerkleRoot), true)
+	}
+
+	onChainRoot, exists, err := b.fetchCommittedRoot(ctx, batch.BatchID)
+	if err != nil {
+		return b.fail(ctx, batch, fmt.Errorf("checking batch commit account: %w", err), errors.Is(err, errBatchCommitMismatch))
+	}
+
+	if exists {
+		if onChainRoot != rootHex {
+			return b.fail(ctx, batch, fmt.Errorf("batch_id already committed on chain with root %s", onChainRoot), true)
 		}
+
+		txSig := ""
+		var slot uint64
+		if batch.SolanaTxSig != nil {
+			txSig = *batch.SolanaTxSig
+			slot = b.lookupSlot(ctx, txSig)
+		}
+		if err := b.repo.CloseBatch(ctx, batch.BatchID, rootHex, txSig, slot, proofs); err != nil {
+			return fmt.Errorf("closing batch in DB: %w", err)
+		}
+
+		log.Printf("✅ Batch #%d Committed! Root: %s, Tx: %s", batch.BatchID, rootHex, txSig)
+		return nil
 	}
 
-	var leafHashes []string
-	for _, s := range spins {
-		leafHashes = append(leafHashes, s.LeafHash)
+	if batch.Status == domain.BatchStatusSubmitted && batch.SubmittedAt != nil && time.Since(*batch.SubmittedAt) < resubmitAfter {
+		return nil
 	}
-	rootHex, err := crypto.ComputeMerkleRoot(leafHashes)
+
+	build, err := b.commitTxBuilder(batch.BatchID, rootHex)
 	if err != nil {
-		return fmt.Errorf("calculating merkle root: %w", err)
+		return b.fail(ctx, batch, fmt.Errorf("building commit instruction: %w", err), true)
 	}
 
-	txSig, err := b.submitToSolana(ctx, batch.BatchID, rootHex)
+	conf, err := b.tracker.SendAndConfirm(ctx, build, func(sig solana.Signature) error {
+		if err := b.repo.MarkBatchSubmitted(ctx, batch.BatchID, rootHex, sig.String()); err != nil {
+			return fmt.Errorf("marking batch submitted: %w", err)
+		}
+		log.Printf("📤 Batch #%d Submitted. Root: %s, Tx: %s", batch.BatchID, rootHex, sig)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("submitting to solana: %w", err)
+		return b.fail(ctx, batch, fmt.Errorf("submitting to solana: %w", err), false)
 	}
 
-	if err := b.repo.CloseBatch(ctx, batch.BatchID, rootHex, txSig); err != nil {
+	if err := b.repo.CloseBatch(ctx, batch.BatchID, rootHex, conf.Signature.String(), conf.Slot, proofs); err != nil {
 		return fmt.Errorf("closing batch in DB: %w", err)
 	}
 
-	log.Printf("✅ Batch #%d Committed! Root: %s, Tx: %s", batch.BatchID, rootHex, txSig)
+	log.Printf("✅ Batch #%d Committed! Root: %s, Tx: %s, Slot: %d", batch.BatchID, rootHex, conf.Signature, conf.Slot)
 	return nil
 }
 
-// submitToSolana constructs the raw Anchor instruction
-func (b *BatchCommitter) submitToSolana(ctx context.Context, batchID int64, rootHex string) (string, error) {
-	hash := sha256.Sum256([]byte("global:commit_batch_root"))
-	discriminator := hash[:8]
+// lookupSlot returns the slot a previously sent commit landed in, or 0 if the
+// cluster no longer knows the signature.
+func (b *BatchCommitter) lookupSlot(ctx context.Context, txSig string) uint64 {
+	sig, err := solana.SignatureFromBase58(txSig)
+	if err != nil {
+		return 0
+	}
+	conf, err := b.tracker.Status(ctx, sig)
+	if err != nil || conf == nil {
+		return 0
+	}
+	return conf.Slot
+}
 
-	data := make([]byte, 0, 8+8+32)
-	data = append(data, discriminator...)
+// fail records the error and schedules a retry with exponential backoff. After
+// maxBatchAttempts, or for errors that cannot be fixed by retrying, the batch
+// is moved to FAILED.
+func (b *BatchCommitter) fail(ctx context.Context, batch *domain.Batch, cause error, permanent bool) error {
+	final := permanent || batch.Attempts+1 >= maxBatchAttempts
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uint64(batchID))
-	data = append(data, buf...)
+	if err := b.repo.RecordBatchFailure(ctx, batch.BatchID, cause.Error(), retryDelay(batch.Attempts), final); err != nil {
+		return fmt.Errorf("%v (recording failure: %w)", cause, err)
+	}
+	batch.Attempts++
+	if final {
+		log.Printf("🛑 Batch #%d marked FAILED: %v", batch.BatchID, cause)
+	}
+	return cause
+}
 
-	rootBytes, err := hex.DecodeString(rootHex)
+func retryDelay(attempts int) time.Duration {
+	if attempts > 16 {
+		return maxRetryDelay
+	}
+	delay := baseRetryDelay << attempts
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// errBatchCommitMismatch means the BatchCommit PDA of a batch records another
+// batch_id, which retrying cannot fix.
+var errBatchCommitMismatch = errors.New("BatchCommit account belongs to another batch")
+
+// fetchCommittedRoot reads the BatchCommit PDA. exists is false if the batch
+// has never been committed on chain.
+func (b *BatchCommitter) fetchCommittedRoot(ctx context.Context, batchID int64) (string, bool, error) {
+	pda, err := solana_parser.FindBatchCommitPDA(b.programID, batchID)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	data = append(data, rootBytes...)
 
-	batchIdLe := make([]byte, 8)
-	binary.LittleEndian.PutUint64(batchIdLe, uint64(batchID))
+	accountInfo, err := b.rpcClient.GetAccountInfoWithOpts(ctx, pda, &rpc.GetAccountInfoOpts{
+		Commitment: b.tracker.Commitment(),
+	})
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if accountInfo == nil || accountInfo.Value == nil {
+		return "", false, nil
+	}
 
-	batchCommitPDA, _, err := solana.FindProgramAddress(
-		[][]byte{[]byte("batch_commit"), batchIdLe},
-		b.programID,
-	)
+	commit, err := solana_parser.ParseBatchCommit(accountInfo.Value.Data.GetBinary())
 	if err != nil {
-		return "", err
+		return "", false, err
+	}
+	if commit.BatchID != uint64(batchID) {
+		return "", false, fmt.Errorf("%w: account holds batch %d, expected %d", errBatchCommitMismatch, commit.BatchID, batchID)
 	}
+	return hex.EncodeToString(commit.MerkleRoot[:]), true, nil
+}
 
-	accounts := []*solana.AccountMeta{
-		{PublicKey: b.vaultAddress, IsWritable: true, IsSigner: false},
-		{PublicKey: batchCommitPDA, IsWritable: true, IsSigner: false},
-		{PublicKey: b.serverWallet.PublicKey(), IsWritable: true, IsSigner: true},
-		{PublicKey: solana.SystemProgramID, IsWritable: false, IsSigner: false},
+// buildBatchProofs computes the root and every spin's proof in one pass over the tree.
+func buildBatchProofs(format crypto.MerkleFormat, batchID int64, spins []domain.Spin) (string, []domain.MerkleProof, error) {
+	var leafHashes []string
+	for _, s := range spins {
+		leafHashes = append(leafHashes, s.LeafHash)
 	}
 
-	instruction := solana.NewInstruction(
-		b.programID,
-		accounts,
-		data,
-	)
+	var rootHex string
+	var proofFor func(i int) (interface{}, error)
 
-	recent, err := b.rpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		return "", err
+	switch format {
+	case crypto.MerkleFormatRFC6962, crypto.MerkleFormatRFC6962V2:
+		tree, err := crypto.BuildMerkleTreeRFC6962(leafHashes)
+		if err != nil {
+			return "", nil, err
+		}
+		rootHex = tree.Root()
+		proofFor = func(i int) (interface{}, error) { return tree.Proof(i) }
+	case crypto.MerkleFormatLegacy:
+		root, err := crypto.ComputeMerkleRoot(leafHashes)
+		if err != nil {
+			return "", nil, err
+		}
+		rootHex = root
+		proofFor = func(i int) (interface{}, error) { return crypto.GenerateMerkleProof(leafHashes, i) }
+	default:
+		return "", nil, fmt.Errorf("unknown merkle format %d", format)
 	}
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(b.serverWallet.PublicKey()),
-	)
+	proofs := make([]domain.MerkleProof, 0, len(spins))
+	for i, s := range spins {
+		proof, err := proofFor(i)
+		if err != nil {
+			return "", nil, err
+		}
+		proofJSON, err := json.Marshal(proof)
+		if err != nil {
+			return "", nil, err
+		}
+		proofs = append(proofs, domain.MerkleProof{
+			SpinID:    s.SpinID,
+			BatchID:   batchID,
+			LeafIndex: i,
+			Proof:     proofJSON,
+		})
+	}
+
+	return rootHex, proofs, nil
+}
+
+// commitTxBuilder encodes commit_batch_root from the IDL and returns a builder
+// that signs it against whichever blockhash the tracker hands in.
+func (b *BatchCommitter) commitTxBuilder(batchID int64, rootHex string) (txconfirm.BuildFunc, error) {
+	rootBytes, err := hex.DecodeString(rootHex)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(b.serverWallet.PublicKey()) {
-			return &b.serverWallet
-		}
-		return nil
-	})
+	batchCommitPDA, err := solana_parser.FindBatchCommitPDA(b.programID, batchID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	sig, err := b.rpcClient.SendTransaction(ctx, tx)
+	instruction, err := anchor.Casino().MustInstruction("commit_batch_root").Build(b.programID,
+		map[string]solana.PublicKey{
+			"casino_vault": b.vaultAddress,
+			"batch_commit": batchCommitPDA,
+			"authority":    b.serverWallet.PublicKey(),
+		},
+		map[string]interface{}{
+			"batch_id":    batchID,
+			"merkle_root": rootBytes,
+		},
+	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return sig.String(), nil
+	return func(blockhash solana.Hash) (*solana.Transaction, error) {
+		tx, err := solana.NewTransaction(
+			[]solana.Instruction{instruction},
+			blockhash,
+			solana.TransactionPayer(b.serverWallet.PublicKey()),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(b.serverWallet.PublicKey()) {
+				return &b.serverWallet
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}, nil
 }
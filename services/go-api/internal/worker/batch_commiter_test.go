@@ -0,0 +1,256 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/google/uuid"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/anchor"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/crypto"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana/solanatest"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
+)
+
+// batchRepo keeps spins and batches in memory, following the batch state
+// machine of the Postgres repository.
+type batchRepo struct {
+	repository.Repository
+
+	mu      sync.Mutex
+	spins   []domain.Spin
+	batches map[int64]*domain.Batch
+	proofs  map[int64][]domain.MerkleProof
+}
+
+func newBatchRepo(spins int) *batchRepo {
+	r := &batchRepo{batches: map[int64]*domain.Batch{}, proofs: map[int64][]domain.MerkleProof{}}
+	for i := 0; i < spins; i++ {
+		leaf := sha256.Sum256([]byte{byte(i)})
+		r.spins = append(r.spins, domain.Spin{SpinID: uuid.New(), LeafHash: hex.EncodeToString(leaf[:]), LeafFormat: int(crypto.CurrentMerkleFormat)})
+	}
+	return r
+}
+
+func (r *batchRepo) GetUnbatchedSpins(_ context.Context, limit int) ([]domain.Spin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Spin
+	for _, s := range r.spins {
+		if s.BatchID == nil && len(out) < limit {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *batchRepo) CreateBatch(_ context.Context, treeFormat int, spinIDs []string) (*domain.Batch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := int64(len(r.batches) + 1)
+	for i := range r.spins {
+		for _, spinID := range spinIDs {
+			if r.spins[i].SpinID.String() == spinID {
+				r.spins[i].BatchID = &id
+			}
+		}
+	}
+	r.batches[id] = &domain.Batch{BatchID: id, Status: domain.BatchStatusOpen, TreeFormat: treeFormat}
+	b := *r.batches[id]
+	return &b, nil
+}
+
+func (r *batchRepo) GetBatchSpins(_ context.Context, batchID int64) ([]domain.Spin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Spin
+	for _, s := range r.spins {
+		if s.BatchID != nil && *s.BatchID == batchID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *batchRepo) MarkBatchSubmitted(_ context.Context, batchID int64, merkleRoot string, txSig string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	b := r.batches[batchID]
+	b.Status, b.MerkleRoot, b.SolanaTxSig, b.SubmittedAt = domain.BatchStatusSubmitted, &merkleRoot, &txSig, &now
+	b.LastError = nil
+	return nil
+}
+
+func (r *batchRepo) RecordBatchFailure(_ context.Context, batchID int64, reason string, _ time.Duration, final bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.batches[batchID]
+	b.Attempts++
+	b.LastError = &reason
+	if final {
+		b.Status = domain.BatchStatusFailed
+	}
+	return nil
+}
+
+func (r *batchRepo) CloseBatch(_ context.Context, batchID int64, merkleRoot string, txSig string, slot uint64, proofs []domain.MerkleProof) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.batches[batchID]
+	committedSlot := int64(slot)
+	b.Status, b.MerkleRoot, b.SolanaTxSig, b.CommittedSlot = domain.BatchStatusCommitted, &merkleRoot, &txSig, &committedSlot
+	r.proofs[batchID] = proofs
+	return nil
+}
+
+func (r *batchRepo) batch(id int64) domain.Batch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.batches[id]
+}
+
+// newTestCommitter runs against a chain whose vault names operator as its
+// operational authority; the committer signs with wallet.
+func newTestCommitter(repo repository.Repository, operator solana.PublicKey, wallet solana.PrivateKey) (*BatchCommitter, *solanatest.Chain) {
+	programID := solana.NewWallet().PublicKey()
+	chain := solanatest.NewChain(programID, operator, make([]byte, 32), 0)
+	chain.Airdrop(wallet.PublicKey(), 1_000_000_000)
+
+	return &BatchCommitter{
+		repo:         repo,
+		rpcClient:    chain,
+		tracker:      txconfirm.NewTracker(chain, ""),
+		serverWallet: wallet,
+		programID:    programID,
+		vaultAddress: chain.Vault(),
+	}, chain
+}
+
+func TestProcessBatch(t *testing.T) {
+	ctx := context.Background()
+	repo := newBatchRepo(3)
+	wallet := solana.NewWallet().PrivateKey
+	committer, chain := newTestCommitter(repo, wallet.PublicKey(), wallet)
+
+	if err := committer.processBatch(ctx); err != nil {
+		t.Fatalf("processBatch failed: %v", err)
+	}
+
+	batch := repo.batch(1)
+	sent := chain.Sent()
+	if batch.Status != domain.BatchStatusCommitted || len(sent) != 1 || *batch.SolanaTxSig != sent[0].String() {
+		t.Fatalf("Expected batch 1 committed by %v, got %+v", sent, batch)
+	}
+	if len(repo.proofs[1]) != 3 {
+		t.Errorf("Expected 3 proofs, got %d", len(repo.proofs[1]))
+	}
+
+	root, exists, err := committer.fetchCommittedRoot(ctx, 1)
+	if err != nil || !exists || root != *batch.MerkleRoot {
+		t.Errorf("Expected root %s on chain, got %s (exists: %v, err: %v)", *batch.MerkleRoot, root, exists, err)
+	}
+	pda, _ := solana_parser.FindBatchCommitPDA(committer.programID, 1)
+	info, _ := chain.GetAccountInfoWithOpts(ctx, pda, nil)
+	commit, err := solana_parser.ParseBatchCommit(info.Value.Data.GetBinary())
+	if err != nil || commit.BatchID != 1 || !commit.Authority.Equals(wallet.PublicKey()) {
+		t.Errorf("Unexpected BatchCommit account: %+v (%v)", commit, err)
+	}
+
+	// Every spin is batched now, so there is nothing to send.
+	if err := committer.processBatch(ctx); err != nil {
+		t.Fatalf("processBatch failed: %v", err)
+	}
+	// Retrying a batch that already landed closes it without sending again.
+	if err := committer.commitBatch(ctx, &batch); err != nil {
+		t.Fatalf("commitBatch failed: %v", err)
+	}
+	if len(chain.Sent()) != 1 {
+		t.Errorf("Expected a single commit transaction, got %d", len(chain.Sent()))
+	}
+}
+
+func TestProcessBatchFailures(t *testing.T) {
+	ctx := context.Background()
+
+	// The node rejects the send: the batch stays open for a retry.
+	repo := newBatchRepo(2)
+	wallet := solana.NewWallet().PrivateKey
+	committer, chain := newTestCommitter(repo, wallet.PublicKey(), wallet)
+	chain.FailNextSend(errors.New("connection refused"))
+
+	if err := committer.processBatch(ctx); err == nil {
+		t.Fatalf("Expected processBatch to fail")
+	}
+	batch := repo.batch(1)
+	if batch.Status != domain.BatchStatusOpen || batch.Attempts != 1 || !strings.Contains(*batch.LastError, "connection refused") {
+		t.Errorf("Expected a recorded, retryable failure, got %+v", batch)
+	}
+
+	if err := committer.commitBatch(ctx, &batch); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if batch := repo.batch(1); batch.Status != domain.BatchStatusCommitted {
+		t.Errorf("Expected the retry to commit, got %s", batch.Status)
+	}
+
+	// Every failed attempt counts once; the last allowed one marks the batch FAILED.
+	repo = newBatchRepo(2)
+	committer, chain = newTestCommitter(repo, wallet.PublicKey(), wallet)
+	chain.FailNextSend(errors.New("connection refused"))
+	_ = committer.processBatch(ctx)
+	batch = repo.batch(1)
+	for batch.Attempts < maxBatchAttempts {
+		if batch.Status == domain.BatchStatusFailed {
+			t.Fatalf("Batch marked FAILED after %d attempts", batch.Attempts)
+		}
+		chain.FailNextSend(errors.New("connection refused"))
+		if err := committer.commitBatch(ctx, &batch); err == nil {
+			t.Fatalf("Expected commitBatch to fail")
+		}
+		if got := repo.batch(1); got.Attempts != batch.Attempts {
+			t.Fatalf("Expected %d attempts recorded, got %d", batch.Attempts, got.Attempts)
+		}
+		batch.Status = repo.batch(1).Status
+	}
+	if batch.Status != domain.BatchStatusFailed {
+		t.Errorf("Expected FAILED after %d attempts, got %s", maxBatchAttempts, batch.Status)
+	}
+
+	// A wallet that is not the vault's operational authority is refused by
+	// the program.
+	repo = newBatchRepo(2)
+	committer, chain = newTestCommitter(repo, solana.NewWallet().PublicKey(), wallet)
+	if err := committer.processBatch(ctx); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Fatalf("Expected an Unauthorized error, got %v", err)
+	}
+	if batch := repo.batch(1); batch.Status != domain.BatchStatusOpen || len(chain.Sent()) != 0 {
+		t.Errorf("Expected nothing committed, got %+v", batch)
+	}
+
+	// The PDA of batch 1 holding another batch is never taken as its commit.
+	repo = newBatchRepo(2)
+	committer, chain = newTestCommitter(repo, wallet.PublicKey(), wallet)
+	pda, _ := solana_parser.FindBatchCommitPDA(committer.programID, 1)
+	data, _ := anchor.Casino().EncodeAccount("BatchCommit", map[string]interface{}{
+		"authority":   wallet.PublicKey(),
+		"batch_id":    uint64(7),
+		"merkle_root": make([]byte, 32),
+	})
+	chain.SetAccount(pda, data)
+	if err := committer.processBatch(ctx); err == nil || !strings.Contains(err.Error(), "another batch") {
+		t.Fatalf("Expected a mismatched BatchCommit to fail, got %v", err)
+	}
+	if batch := repo.batch(1); batch.Status != domain.BatchStatusFailed {
+		t.Errorf("Expected the batch to be FAILED, got %s", batch.Status)
+	}
+}
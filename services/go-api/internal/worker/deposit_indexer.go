@@ -0,0 +1,186 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+)
+
+const (
+	depositCursorName    = "vault_deposits"
+	depositPollInterval  = 15 * time.Second
+	signaturesPageLimit  = 1000
+	depositIndexerCommit = rpc.CommitmentConfirmed
+)
+
+// DepositIndexer credits deposits into the vault without relying on the client
+// to call /wallet/sync. It walks the vault's signatures oldest-first from a
+// cursor kept in Postgres, so nothing is missed across restarts.
+type DepositIndexer struct {
+	repo         repository.Repository
+	rpcClient    solana_parser.ChainClient
+	programID    solana.PublicKey
+	vaultAddress solana.PublicKey
+}
+
+func NewDepositIndexer(repo repository.Repository, rpcClient solana_parser.ChainClient, programIDStr string, vaultAddrStr string) (*DepositIndexer, error) {
+	progID, err := solana.PublicKeyFromBase58(programIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program ID: %w", err)
+	}
+	vaultAddr, err := solana.PublicKeyFromBase58(vaultAddrStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address: %w", err)
+	}
+
+	return &DepositIndexer{
+		repo:         repo,
+		rpcClient:    rpcClient,
+		programID:    progID,
+		vaultAddress: vaultAddr,
+	}, nil
+}
+
+// Start runs the background loop
+func (d *DepositIndexer) Start(ctx context.Context) {
+	ticker := time.NewTicker(depositPollInterval)
+	defer ticker.Stop()
+
+	log.Println("🔎 Deposit Indexer Started")
+
+	d.tick(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🔎 Deposit Indexer stopping...")
+			return
+		case <-ticker.C:
+			d.tick(ctx)
+		}
+	}
+}
+
+func (d *DepositIndexer) tick(ctx context.Context) {
+	if err := d.poll(ctx); err != nil {
+		log.Printf("❌ Deposit Indexer Error: %v\n", err)
+	}
+}
+
+// poll processes every vault signature newer than the cursor. The cursor only
+// moves past a transaction once it has been credited (or found irrelevant), so a
+// failure is retried on the next tick.
+func (d *DepositIndexer) poll(ctx context.Context) error {
+	cursor, err := d.repo.GetIndexerCursor(ctx, depositCursorName)
+	if err != nil {
+		return fmt.Errorf("reading cursor: %w", err)
+	}
+
+	sigs, err := d.signaturesSince(ctx, cursor)
+	if err != nil {
+		return fmt.Errorf("listing vault signatures: %w", err)
+	}
+
+	for i := len(sigs) - 1; i >= 0; i-- {
+		sig := sigs[i]
+		if sig.Err == nil {
+			if err := d.indexTransaction(ctx, sig.Signature); err != nil {
+				return fmt.Errorf("indexing %s: %w", sig.Signature, err)
+			}
+		}
+		if err := d.repo.SetIndexerCursor(ctx, depositCursorName, sig.Signature.String(), sig.Slot); err != nil {
+			return fmt.Errorf("advancing cursor: %w", err)
+		}
+	}
+	return nil
+}
+
+// signaturesSince pages backwards from the tip until it reaches cursor. The
+// result is newest-first, as returned by the RPC.
+func (d *DepositIndexer) signaturesSince(ctx context.Context, cursor string) ([]*rpc.TransactionSignature, error) {
+	limit := signaturesPageLimit
+	opts := &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: depositIndexerCommit,
+	}
+	if cursor != "" {
+		until, err := solana.SignatureFromBase58(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q: %w", cursor, err)
+		}
+		opts.Until = until
+	}
+
+	var all []*rpc.TransactionSignature
+	for {
+		page, err := d.rpcClient.GetSignaturesForAddressWithOpts(ctx, d.vaultAddress, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < signaturesPageLimit {
+			return all, nil
+		}
+		opts.Before = page[len(page)-1].Signature
+	}
+}
+
+// indexTransaction credits every signed deposit instruction of one transaction,
+// each to the wallet that signed it.
+func (d *DepositIndexer) indexTransaction(ctx context.Context, sig solana.Signature) error {
+	txSig := sig.String()
+
+	maxVersion := uint64(0)
+	tx, err := d.rpcClient.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Commitment:                     depositIndexerCommit,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("fetching transaction: %w", err)
+	}
+	if tx == nil || tx.Meta == nil || tx.Meta.Err != nil {
+		return nil
+	}
+
+	parsedTx, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		log.Printf("⚠️  Deposit Indexer: skipping undecodable tx %s: %v", txSig, err)
+		return nil
+	}
+
+	deposits, err := solana_parser.ParseVaultDeposits(parsedTx, d.programID, d.vaultAddress)
+	if err != nil {
+		log.Printf("⚠️  Deposit Indexer: skipping malformed deposit in %s: %v", txSig, err)
+		return nil
+	}
+
+	for _, dep := range deposits {
+		processed, err := d.repo.CheckDepositProcessed(ctx, txSig, dep.Index)
+		if err != nil {
+			return err
+		}
+		if processed {
+			continue
+		}
+
+		walletAddress := dep.User.String()
+		err = d.repo.RecordDeposit(ctx, txSig, dep.Index, walletAddress, dep.Amount)
+		if errors.Is(err, domain.ErrDepositProcessed) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("recording deposit: %w", err)
+		}
+
+		log.Printf("💰 Indexed deposit %s#%d: %d lamports for %s", txSig, dep.Index, dep.Amount, walletAddress)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/domain"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/repository"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/solana"
+	"github.com/magnacartaam/chain-solutions/services/go-api/internal/txconfirm"
+)
+
+const withdrawalReconcileInterval = 30 * time.Second
+
+// WithdrawalReconciler confirms signed withdrawals whose nonce the program has
+// already consumed, so a withdrawal completes even if the client never reports
+// it, and refunds the ones whose authorization expired unused.
+type WithdrawalReconciler struct {
+	repo      repository.Repository
+	rpcClient solana_parser.ChainClient
+	tracker   *txconfirm.Tracker
+	programID solana.PublicKey
+}
+
+func NewWithdrawalReconciler(repo repository.Repository, rpcClient solana_parser.ChainClient, tracker *txconfirm.Tracker, programIDStr string) (*WithdrawalReconciler, error) {
+	progID, err := solana.PublicKeyFromBase58(programIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program ID: %w", err)
+	}
+
+	return &WithdrawalReconciler{
+		repo:      repo,
+		rpcClient: rpcClient,
+		tracker:   tracker,
+		programID: progID,
+	}, nil
+}
+
+// Start runs the background loop
+func (w *WithdrawalReconciler) Start(ctx context.Context) {
+	ticker := time.NewTicker(withdrawalReconcileInterval)
+	defer ticker.Stop()
+
+	log.Println("🧾 Withdrawal Reconciler Started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🧾 Withdrawal Reconciler stopping...")
+			return
+		case <-ticker.C:
+			if err := w.reconcile(ctx); err != nil {
+				log.Printf("❌ Withdrawal Reconciler Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// reconcile checks every wallet with signed withdrawals against its finalized
+// UserBalance PDA. The chain time is read first, so a withdrawal that expired
+// before it can no longer land after the PDA was read. A failure for one wallet
+// is logged and does not stop the others.
+func (w *WithdrawalReconciler) reconcile(ctx context.Context) error {
+	chainNow, err := w.tracker.FinalizedTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending, err := w.repo.GetPendingWithdrawals(ctx)
+	if err != nil {
+		return fmt.Errorf("listing pending withdrawals: %w", err)
+	}
+
+	for start := 0; start < len(pending); {
+		// Rows are grouped by wallet: one PDA read per wallet.
+		end := start + 1
+		for end < len(pending) && pending[end].WalletAddress == pending[start].WalletAddress {
+			end++
+		}
+		w.reconcileWallet(ctx, pending[start:end], chainNow)
+		start = end
+	}
+	return nil
+}
+
+func (w *WithdrawalReconciler) reconcileWallet(ctx context.Context, pending []domain.Withdrawal, chainNow time.Time) {
+	walletAddress := pending[0].WalletAddress
+
+	userPubkey, err := solana.PublicKeyFromBase58(walletAddress)
+	if err != nil {
+		log.Printf("⚠️  Withdrawal Reconciler: invalid wallet %s: %v", walletAddress, err)
+		return
+	}
+
+	var lastOnChainNonce int64
+	onChain, err := solana_parser.FetchUserBalance(ctx, w.rpcClient, w.programID, userPubkey, rpc.CommitmentFinalized)
+	if err != nil {
+		log.Printf("⚠️  Withdrawal Reconciler: reading %s: %v", walletAddress, err)
+		return
+	}
+	if onChain != nil {
+		lastOnChainNonce = int64(onChain.LastNonce)
+	}
+
+	confirmed, err := w.repo.ConfirmWithdrawals(ctx, walletAddress, lastOnChainNonce)
+	if err != nil {
+		log.Printf("⚠️  Withdrawal Reconciler: confirming %s: %v", walletAddress, err)
+		return
+	}
+	for _, c := range confirmed {
+		log.Printf("✅ Withdrawal #%d of %s SOL confirmed on-chain for %s", c.Nonce, c.Amount.String(), walletAddress)
+	}
+
+	// The last queued authorization expires last; until it has, nothing is refunded.
+	if !pending[len(pending)-1].ExpiresAt.Before(chainNow) {
+		return
+	}
+	refunded, err := w.repo.RefundWithdrawals(ctx, walletAddress, lastOnChainNonce, domain.WithdrawalStatusExpired, chainNow)
+	if err != nil {
+		if !errors.Is(err, domain.ErrWithdrawalNotExpired) {
+			log.Printf("⚠️  Withdrawal Reconciler: refunding %s: %v", walletAddress, err)
+		}
+		return
+	}
+	for _, r := range refunded {
+		log.Printf("↩️  Withdrawal #%d of %s SOL expired unused, refunded to %s", r.Nonce, r.Amount.String(), walletAddress)
+	}
+}
@@ -70,4 +70,259 @@ CREATE INDEX idx_batches_status ON batches (status);
 
 
 ALTER TABLE users ADD COLUMN pending_withdrawal_amount DECIMAL(20, 9) NOT NULL DEFAULT 0;
-ALTER TABLE users ADD COLUMN pending_withdrawal_signature VARCHAR(128) NOT NULL DEFAULT '';
\ No newline at end of file
+ALTER TABLE users ADD COLUMN pending_withdrawal_signature VARCHAR(128) NOT NULL DEFAULT '';
+
+ALTER TABLE spins ADD COLUMN game_id VARCHAR(32) NOT NULL DEFAULT 'classic';
+ALTER TABLE spins ADD COLUMN game_version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE batches ADD COLUMN tree_format SMALLINT NOT NULL DEFAULT 1;
+
+ALTER TABLE merkle_proofs ADD COLUMN batch_id BIGINT REFERENCES batches (batch_id);
+ALTER TABLE merkle_proofs ADD COLUMN leaf_index INT NOT NULL DEFAULT 0;
+
+ALTER TABLE batches ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE batches ADD COLUMN last_error TEXT;
+ALTER TABLE batches ADD COLUMN next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE batches ADD COLUMN submitted_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE batches ADD COLUMN committed_slot BIGINT;
+
+CREATE TABLE indexer_cursors
+(
+    name           VARCHAR(32) PRIMARY KEY,
+    last_signature VARCHAR(88) NOT NULL,
+    last_slot      BIGINT      NOT NULL,
+    updated_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Deposits credited before ix_index existed were credited once for the whole
+-- transaction; ix_index -1 keeps them covering every instruction of it.
+ALTER TABLE processed_deposits ADD COLUMN ix_index INT NOT NULL DEFAULT -1;
+ALTER TABLE processed_deposits ALTER COLUMN ix_index DROP DEFAULT;
+ALTER TABLE processed_deposits DROP CONSTRAINT processed_deposits_pkey;
+ALTER TABLE processed_deposits ADD PRIMARY KEY (tx_sig, ix_index);
+
+CREATE TABLE auth_challenges
+(
+    nonce          VARCHAR(64) PRIMARY KEY,
+    wallet_address VARCHAR(44)              NOT NULL,
+    message        TEXT                     NOT NULL,
+    expires_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at        TIMESTAMP WITH TIME ZONE,
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE auth_sessions
+(
+    token_hash     VARCHAR(64) PRIMARY KEY,
+    wallet_address VARCHAR(44)              NOT NULL,
+    expires_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE sessions ADD COLUMN spin_nonce BIGINT NOT NULL DEFAULT 0;
+UPDATE sessions s SET spin_nonce = (SELECT COALESCE(MAX(spin_nonce), 0) FROM spins WHERE spins.session_id = s.session_id);
+ALTER TABLE spins ADD CONSTRAINT uq_spins_session_nonce UNIQUE (session_id, spin_nonce);
+
+CREATE TABLE idempotency_keys
+(
+    wallet_address  VARCHAR(44)  NOT NULL,
+    idempotency_key VARCHAR(128) NOT NULL,
+    request_hash    VARCHAR(64)  NOT NULL,
+    response_status INT,
+    response_body   BYTEA,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at    TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (wallet_address, idempotency_key)
+);
+
+CREATE TABLE ledger_entries
+(
+    entry_id       BIGSERIAL PRIMARY KEY,
+    journal_id     UUID           NOT NULL,
+    account        VARCHAR(24)    NOT NULL,
+    wallet_address VARCHAR(44)    NOT NULL,
+    entry_type     VARCHAR(24)    NOT NULL,
+    amount         DECIMAL(20, 9) NOT NULL,
+
+    source_tx_sig  VARCHAR(88),
+    source_spin_id UUID REFERENCES spins (spin_id),
+    source_nonce   BIGINT,
+
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_wallet_account ON ledger_entries (wallet_address, account);
+CREATE INDEX idx_ledger_journal ON ledger_entries (journal_id);
+
+-- Balances held before the ledger existed are booked once as opening entries.
+WITH opening AS (SELECT gen_random_uuid() AS journal_id, wallet_address, playable_balance
+                 FROM sessions
+                 WHERE is_active = TRUE AND playable_balance <> 0)
+INSERT INTO ledger_entries (journal_id, account, wallet_address, entry_type, amount)
+SELECT o.journal_id, leg.account, o.wallet_address, 'opening_balance', leg.amount
+FROM opening o
+         CROSS JOIN LATERAL (VALUES ('player', o.playable_balance), ('vault', -o.playable_balance)) AS leg(account, amount);
+
+WITH opening AS (SELECT gen_random_uuid() AS journal_id, wallet_address, pending_withdrawal_amount
+                 FROM users
+                 WHERE pending_withdrawal_amount <> 0)
+INSERT INTO ledger_entries (journal_id, account, wallet_address, entry_type, amount)
+SELECT o.journal_id, leg.account, o.wallet_address, 'opening_balance', leg.amount
+FROM opening o
+         CROSS JOIN LATERAL (VALUES ('pending_withdrawal', o.pending_withdrawal_amount),
+                                    ('vault', -o.pending_withdrawal_amount)) AS leg(account, amount);
+
+-- Ledger totals next to the balances stored on sessions/users, one row per wallet.
+CREATE VIEW ledger_reconciliation AS
+WITH ledger AS (SELECT wallet_address,
+                       COALESCE(SUM(amount) FILTER (WHERE account = 'player'), 0)             AS ledger_balance,
+                       COALESCE(SUM(amount) FILTER (WHERE account = 'pending_withdrawal'), 0) AS ledger_pending
+                FROM ledger_entries
+                GROUP BY wallet_address),
+     stored AS (SELECT u.wallet_address,
+                       COALESCE(s.playable_balance, 0) AS stored_balance,
+                       u.pending_withdrawal_amount     AS stored_pending
+                FROM users u
+                         LEFT JOIN sessions s ON s.wallet_address = u.wallet_address AND s.is_active = TRUE)
+SELECT COALESCE(st.wallet_address, l.wallet_address) AS wallet_address,
+       COALESCE(l.ledger_balance, 0)                 AS ledger_balance,
+       COALESCE(st.stored_balance, 0)                AS stored_balance,
+       COALESCE(l.ledger_pending, 0)                 AS ledger_pending,
+       COALESCE(st.stored_pending, 0)                AS stored_pending
+FROM stored st
+         FULL OUTER JOIN ledger l ON l.wallet_address = st.wallet_address;
+
+CREATE TABLE accounts
+(
+    wallet_address   VARCHAR(44) PRIMARY KEY REFERENCES users (wallet_address),
+    playable_balance DECIMAL(20, 9) NOT NULL DEFAULT 0 CHECK (playable_balance >= 0),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO accounts (wallet_address, playable_balance)
+SELECT u.wallet_address, COALESCE(s.playable_balance, 0)
+FROM users u
+         LEFT JOIN sessions s ON s.wallet_address = u.wallet_address AND s.is_active = TRUE;
+
+DROP VIEW ledger_reconciliation;
+ALTER TABLE sessions DROP COLUMN playable_balance;
+
+CREATE VIEW ledger_reconciliation AS
+WITH ledger AS (SELECT wallet_address,
+                       COALESCE(SUM(amount) FILTER (WHERE account = 'player'), 0)             AS ledger_balance,
+                       COALESCE(SUM(amount) FILTER (WHERE account = 'pending_withdrawal'), 0) AS ledger_pending
+                FROM ledger_entries
+                GROUP BY wallet_address),
+     stored AS (SELECT u.wallet_address,
+                       COALESCE(a.playable_balance, 0) AS stored_balance,
+                       u.pending_withdrawal_amount     AS stored_pending
+                FROM users u
+                         LEFT JOIN accounts a ON a.wallet_address = u.wallet_address)
+SELECT COALESCE(st.wallet_address, l.wallet_address) AS wallet_address,
+       COALESCE(l.ledger_balance, 0)                 AS ledger_balance,
+       COALESCE(st.stored_balance, 0)                AS stored_balance,
+       COALESCE(l.ledger_pending, 0)                 AS ledger_pending,
+       COALESCE(st.stored_pending, 0)                AS stored_pending
+FROM stored st
+         FULL OUTER JOIN ledger l ON l.wallet_address = st.wallet_address;
+
+CREATE TABLE withdrawals
+(
+    wallet_address VARCHAR(44)    NOT NULL REFERENCES users (wallet_address),
+    nonce          BIGINT         NOT NULL,
+    amount         DECIMAL(20, 9) NOT NULL CHECK (amount > 0),
+    signature      VARCHAR(128)   NOT NULL,
+    recovery_id    SMALLINT,
+    status         VARCHAR(16)    NOT NULL DEFAULT 'signed'
+        CHECK (status IN ('signed', 'confirmed', 'refunded', 'expired')),
+    solana_tx_sig  VARCHAR(88),
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (wallet_address, nonce)
+);
+
+CREATE INDEX idx_withdrawals_signed ON withdrawals (wallet_address) WHERE status = 'signed';
+
+-- The single pending withdrawal kept on users becomes the first queued row.
+-- Its recovery id was never stored.
+INSERT INTO withdrawals (wallet_address, nonce, amount, signature)
+SELECT wallet_address, next_withdrawal_nonce - 1, pending_withdrawal_amount, pending_withdrawal_signature
+FROM users
+WHERE pending_withdrawal_amount > 0;
+
+DROP VIEW ledger_reconciliation;
+ALTER TABLE users DROP COLUMN pending_withdrawal_amount;
+ALTER TABLE users DROP COLUMN pending_withdrawal_signature;
+
+CREATE VIEW ledger_reconciliation AS
+WITH ledger AS (SELECT wallet_address,
+                       COALESCE(SUM(amount) FILTER (WHERE account = 'player'), 0)             AS ledger_balance,
+                       COALESCE(SUM(amount) FILTER (WHERE account = 'pending_withdrawal'), 0) AS ledger_pending
+                FROM ledger_entries
+                GROUP BY wallet_address),
+     pending AS (SELECT wallet_address, SUM(amount) AS amount
+                 FROM withdrawals
+                 WHERE status = 'signed'
+                 GROUP BY wallet_address),
+     stored AS (SELECT u.wallet_address,
+                       COALESCE(a.playable_balance, 0) AS stored_balance,
+                       COALESCE(p.amount, 0)           AS stored_pending
+                FROM users u
+                         LEFT JOIN accounts a ON a.wallet_address = u.wallet_address
+                         LEFT JOIN pending p ON p.wallet_address = u.wallet_address)
+SELECT COALESCE(st.wallet_address, l.wallet_address) AS wallet_address,
+       COALESCE(l.ledger_balance, 0)                 AS ledger_balance,
+       COALESCE(st.stored_balance, 0)                AS stored_balance,
+       COALESCE(l.ledger_pending, 0)                 AS ledger_pending,
+       COALESCE(st.stored_pending, 0)                AS stored_pending
+FROM stored st
+         FULL OUTER JOIN ledger l ON l.wallet_address = st.wallet_address;
+
+-- Withdrawal signatures now commit to an expiry. Signatures issued before that
+-- no longer verify against the upgraded program, so they are already expired.
+ALTER TABLE withdrawals ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+UPDATE withdrawals SET expires_at = NOW();
+ALTER TABLE withdrawals ALTER COLUMN expires_at SET NOT NULL;
+
+-- Reports comparing every wallet's on-chain UserBalance with Postgres.
+CREATE TABLE reconciliation_runs
+(
+    run_id              BIGSERIAL PRIMARY KEY,
+    wallets             INT            NOT NULL,
+    discrepancies       INT            NOT NULL,
+    onchain_total       DECIMAL(20, 9) NOT NULL,
+    liabilities         DECIMAL(20, 9) NOT NULL,
+    vault_liquidity     DECIMAL(20, 9) NOT NULL,
+    liabilities_covered BOOLEAN        NOT NULL,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE reconciliation_items
+(
+    run_id                BIGINT         NOT NULL REFERENCES reconciliation_runs (run_id) ON DELETE CASCADE,
+    wallet_address        VARCHAR(44)    NOT NULL,
+    onchain_amount        DECIMAL(20, 9),
+    onchain_last_nonce    BIGINT,
+    deposited             DECIMAL(20, 9) NOT NULL,
+    withdrawn             DECIMAL(20, 9) NOT NULL,
+    playable_balance      DECIMAL(20, 9) NOT NULL,
+    pending_withdrawals   DECIMAL(20, 9) NOT NULL,
+    next_withdrawal_nonce BIGINT,
+    drift                 DECIMAL(20, 9) NOT NULL,
+    issues                TEXT[]         NOT NULL DEFAULT '{}',
+    PRIMARY KEY (run_id, wallet_address)
+);
+
+-- A refund rewinds the wallet's next nonce, so a cancelled withdrawal shares
+-- its nonce with the one issued after it. Nonces stay unique among the
+-- withdrawals that can still be, or were, executed.
+ALTER TABLE withdrawals DROP CONSTRAINT withdrawals_pkey;
+ALTER TABLE withdrawals ADD COLUMN withdrawal_id BIGSERIAL PRIMARY KEY;
+CREATE UNIQUE INDEX uq_withdrawals_live_nonce ON withdrawals (wallet_address, nonce)
+    WHERE status IN ('signed', 'confirmed');
+
+-- Spins record which tree format their leaf was built for, so a batch only
+-- holds leaves of its own format. Every earlier spin has the RFC 6962 leaf.
+ALTER TABLE spins ADD COLUMN leaf_format INT NOT NULL DEFAULT 2;
+ALTER TABLE spins ALTER COLUMN leaf_format DROP DEFAULT;